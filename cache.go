@@ -0,0 +1,283 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheEntry 缓存中的一条分类记录
+type CacheEntry struct {
+	Name          string    `json:"name"`
+	Size          int64     `json:"size"`
+	Hash          string    `json:"hash,omitempty"`
+	Model         string    `json:"model"`
+	PromptVersion string    `json:"prompt_version"`
+	Category      string    `json:"category"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// ClassificationCache 持久化的本地分类缓存
+type ClassificationCache struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]CacheEntry
+}
+
+// defaultCachePath 返回用户缓存目录下的缓存文件路径
+func defaultCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户缓存目录失败: %v", err)
+	}
+	return filepath.Join(dir, "fileclassify", "classification_cache.json"), nil
+}
+
+// OpenClassificationCache 打开分类缓存，path为空时使用默认位置
+func OpenClassificationCache(path string) (*ClassificationCache, error) {
+	if path == "" {
+		var err error
+		if path, err = defaultCachePath(); err != nil {
+			return nil, err
+		}
+	}
+
+	cache := &ClassificationCache{
+		path:    path,
+		entries: make(map[string]CacheEntry),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cache, nil
+		}
+		return nil, fmt.Errorf("读取缓存文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &cache.entries); err != nil {
+		return nil, fmt.Errorf("解析缓存文件失败: %v", err)
+	}
+	return cache, nil
+}
+
+// normalizeCacheName 规范化文件名，忽略目录、大小写和首尾空白
+func normalizeCacheName(path string) string {
+	return strings.ToLower(strings.TrimSpace(filepath.Base(path)))
+}
+
+// cacheKey 由规范化文件名、大小、内容哈希、模型、提示词版本和提示词中的已有分类生成缓存键
+// 提示词模板、设置或已有分类变化后键不同，旧的缓存不会再被命中
+func cacheKey(file FileInfo, modelName, promptVersion string, existing []string) string {
+	name := normalizeCacheName(file.Path)
+	// 目录与同名文件分开缓存
+	if file.IsDir {
+		name += "/"
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%d\x00%s\x00%s\x00%s", name, file.Size, file.Hash, modelName, promptVersion)
+	// 没有已有分类时与之前的键相同，原有缓存仍然有效
+	if len(existing) > 0 {
+		fmt.Fprintf(h, "\x00%s", strings.Join(existing, "\x01"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Lookup 查询文件的缓存分类，existing 为提示词中列出的已有分类
+func (c *ClassificationCache) Lookup(file FileInfo, modelName, promptVersion string, existing []string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[cacheKey(file, modelName, promptVersion, existing)]
+	if !ok || entry.Category == "" {
+		return "", false
+	}
+	return entry.Category, true
+}
+
+// StoreResult 将一批分类结果写入缓存并保存到磁盘
+func (c *ClassificationCache) StoreResult(result map[string][]FileInfo, modelName, promptVersion string, existing []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for category, files := range result {
+		for _, file := range files {
+			c.entries[cacheKey(file, modelName, promptVersion, existing)] = CacheEntry{
+				Name:          normalizeCacheName(file.Path),
+				Size:          file.Size,
+				Hash:          file.Hash,
+				Model:         modelName,
+				PromptVersion: promptVersion,
+				Category:      category,
+				UpdatedAt:     now,
+			}
+		}
+	}
+	return c.saveLocked()
+}
+
+// Purge 删除满足条件的缓存记录，返回删除数量
+func (c *ClassificationCache) Purge(match func(CacheEntry) bool) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for key, entry := range c.entries {
+		if match(entry) {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, c.saveLocked()
+}
+
+// Entries 返回按更新时间倒序排列的缓存记录
+func (c *ClassificationCache) Entries() []CacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make([]CacheEntry, 0, len(c.entries))
+	for _, entry := range c.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].UpdatedAt.After(entries[j].UpdatedAt)
+	})
+	return entries
+}
+
+// saveLocked 先写临时文件再重命名，避免写到一半时缓存损坏
+func (c *ClassificationCache) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, c.path)
+}
+
+// hashFile 计算文件内容的SHA-256
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fillContentHashes 为文件列表计算内容哈希，失败的文件保留空哈希
+func fillContentHashes(root string, files []FileInfo) {
+	for i := range files {
+		if files[i].IsDir {
+			continue
+		}
+		hash, err := hashFile(filepath.Join(root, files[i].Path))
+		if err != nil {
+			slog.Warn("计算文件哈希失败", "path", files[i].Path, "error", err)
+			continue
+		}
+		files[i].Hash = hash
+	}
+}
+
+// runCacheCommand 处理 cache 子命令：list、stats、purge
+func runCacheCommand(config *Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: cache <list|stats|purge> [参数]")
+	}
+
+	fs := flag.NewFlagSet("cache "+args[0], flag.ContinueOnError)
+	model := fs.String("model", "", "只处理指定模型的记录")
+	prompt := fs.String("prompt", "", "只处理指定提示词版本的记录，如 default@v1")
+	olderThan := fs.Duration("older-than", 0, "只处理早于该时长的记录，如 720h")
+	all := fs.Bool("all", false, "purge 时清空全部缓存")
+	limit := fs.Int("limit", 50, "list 时最多显示的记录数")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cache, err := OpenClassificationCache(config.Cache.Path)
+	if err != nil {
+		return err
+	}
+
+	match := func(entry CacheEntry) bool {
+		if *model != "" && entry.Model != *model {
+			return false
+		}
+		if *prompt != "" && entry.PromptVersion != *prompt {
+			return false
+		}
+		if *olderThan > 0 && time.Since(entry.UpdatedAt) < *olderThan {
+			return false
+		}
+		return true
+	}
+
+	switch args[0] {
+	case "list":
+		shown := 0
+		for _, entry := range cache.Entries() {
+			if !match(entry) {
+				continue
+			}
+			if shown >= *limit {
+				fmt.Println("...")
+				break
+			}
+			fmt.Printf("%s  %-20s  %-12s  %s (%d 字节)  -> %s\n", entry.UpdatedAt.Format("2006-01-02 15:04"), entry.Model, entry.PromptVersion, entry.Name, entry.Size, entry.Category)
+			shown++
+		}
+	case "stats":
+		byModel := make(map[string]int)
+		total := 0
+		for _, entry := range cache.Entries() {
+			if match(entry) {
+				byModel[entry.Model]++
+				total++
+			}
+		}
+		fmt.Printf("缓存文件: %s\n", cache.path)
+		fmt.Printf("记录总数: %d\n", total)
+		for m, count := range byModel {
+			fmt.Printf("- %s: %d\n", m, count)
+		}
+	case "purge":
+		if !*all && *model == "" && *prompt == "" && *olderThan == 0 {
+			return fmt.Errorf("请指定 -all、-model、-prompt 或 -older-than")
+		}
+		removed, err := cache.Purge(match)
+		if err != nil {
+			return fmt.Errorf("清理缓存失败: %v", err)
+		}
+		fmt.Printf("已删除 %d 条缓存记录\n", removed)
+	default:
+		return fmt.Errorf("未知的 cache 子命令: %s", args[0])
+	}
+	return nil
+}
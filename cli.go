@@ -0,0 +1,1177 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// 进程退出码
+const (
+	exitOK        = 0 // 成功
+	exitError     = 1 // 运行失败
+	exitUsage     = 2 // 参数或配置错误
+	exitCancelled = 3 // 用户取消
+	exitPartial   = 4 // 部分文件处理失败
+)
+
+// 输出格式
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// cliError 带退出码的错误
+type cliError struct {
+	code int
+	err  error
+}
+
+func (e *cliError) Error() string {
+	if e.err == nil {
+		return ""
+	}
+	return e.err.Error()
+}
+
+// usageError 参数或配置错误，退出码为 exitUsage
+func usageError(format string, args ...interface{}) error {
+	return &cliError{code: exitUsage, err: fmt.Errorf(format, args...)}
+}
+
+// exitWith 以指定退出码结束，err 为nil时不打印信息
+func exitWith(code int, err error) error {
+	return &cliError{code: code, err: err}
+}
+
+// cliOptions 各子命令共用的命令行参数
+// 全局参数写在子命令之前时作为子命令参数的默认值，两种写法效果相同
+type cliOptions struct {
+	Root        string
+	Provider    string
+	Format      string
+	DryRun      bool
+	Yes         bool
+	Template    string
+	Prompt      string
+	NoCache     bool
+	Incremental bool
+	Duplicates  string
+	Symlinks    string
+	Conflict    string
+	Dirs        bool
+	PlanFile    string
+	RunID       string
+	LogLevel    string
+	LogFile     string
+	LogFormat   string
+	Verbose     bool
+	Report      string
+	Review      bool
+
+	out    io.Writer // 命令结果的输出位置
+	info   *os.File  // 提示信息和进度的输出位置，JSON 格式时为标准错误，不影响结果的解析
+	reader *bufio.Reader
+}
+
+func (o *cliOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&configFile, "config", configFile, "配置文件路径，默认依次查找 $FILECLASSIFY_CONFIG、./config.json 和用户配置目录")
+	fs.StringVar(&o.Root, "root", o.Root, "要整理的目录")
+	fs.StringVar(&o.Provider, "provider", o.Provider, "指定使用的大模型类型 (deepseek, siliconflow, aliyun, github)")
+	fs.StringVar(&o.Format, "format", o.Format, "输出格式 (text, json)")
+	fs.BoolVar(&o.DryRun, "dry-run", o.DryRun, "只显示将要执行的操作，不移动文件")
+	fs.BoolVar(&o.Yes, "yes", o.Yes, "不询问，直接确认")
+	fs.StringVar(&o.Template, "template", o.Template, "目标路径模板，如 {category}/{year}/{month}/{name}{ext}")
+	fs.StringVar(&o.Prompt, "prompt", o.Prompt, "分类提示词模板名称，可用 prompts 命令查看")
+	fs.BoolVar(&o.NoCache, "no-cache", o.NoCache, "不使用本地分类缓存")
+	fs.BoolVar(&o.Incremental, "incremental", o.Incremental, "只处理上次整理后新增或修改的文件")
+	fs.StringVar(&o.Duplicates, "duplicates", o.Duplicates, "重复文件处理策略 (off, report, move, hardlink)")
+	fs.StringVar(&o.Symlinks, "symlinks", o.Symlinks, "符号链接处理策略 (skip, move, follow)")
+	fs.StringVar(&o.Conflict, "conflict", o.Conflict, "目标文件已存在时的处理策略 (skip, overwrite_newer, overwrite, rename, dedupe, ask)")
+	fs.BoolVar(&o.Dirs, "dirs", o.Dirs, "顶层子目录作为整体分类和移动，不递归处理")
+	fs.StringVar(&o.PlanFile, "plan", o.PlanFile, "plan 命令保存计划的文件，或 apply 命令要执行的计划文件")
+	fs.StringVar(&o.RunID, "run", o.RunID, "undo 要撤销的运行编号，默认为最近一次")
+	fs.StringVar(&o.LogLevel, "log-level", o.LogLevel, "日志级别 (debug, info, warn, error)，默认 warn")
+	fs.StringVar(&o.LogFile, "log-file", o.LogFile, "日志写入的文件，默认输出到标准错误")
+	fs.StringVar(&o.LogFormat, "log-format", o.LogFormat, "日志格式 (text, json)")
+	fs.StringVar(&o.Report, "report", o.Report, "apply 结束后保存运行报告，按扩展名选择格式 (.md, .html, .csv, .json)，多个文件用逗号分隔")
+	fs.BoolVar(&o.Review, "review", o.Review, "分类后在终端中审查结果，可以重命名、合并分类，移动或排除文件")
+	fs.BoolVar(&o.Verbose, "v", o.Verbose, "输出调试日志，包括模型的原始响应，等同于 -log-level debug")
+}
+
+// cliCommand 子命令
+type cliCommand struct {
+	name    string
+	usage   string
+	summary string
+	run     func(o *cliOptions, args []string) error
+}
+
+var cliCommands = []cliCommand{
+	{"scan", "scan [目录]", "列出将要整理的文件", runScanCommand},
+	{"classify", "classify [目录]", "使用模型分类并输出结果，不移动文件", runClassifyCommand},
+	{"plan", "plan [目录] [-plan 文件]", "生成移动计划，可保存后用 apply -plan 执行", runPlanCommand},
+	{"apply", "apply [目录] [-plan 文件]", "分类并移动文件（不写子命令时的默认行为）", runApplyCommand},
+	{"undo", "undo [目录] [-run 编号]", "撤销最近一次或指定的整理", runUndoCommand},
+	{"history", "history", "列出可以撤销的运行记录", runHistoryCommand},
+	{"providers", "providers", "列出已配置的模型", runProvidersCommand},
+	{"prompts", "prompts [名称]", "列出提示词模板，或显示指定模板的内容", runPromptsCommand},
+	{"config", "config <show|path|init|set-key>", "查看配置、创建配置文件或加密保存密钥", runConfigCommand},
+	{"doctor", "doctor [目录]", "检查配置、模型连接和目录写入权限", runDoctorCommand},
+	{"cache", "cache <list|stats|purge>", "查看和清理分类缓存", runCacheCLICommand},
+	{"watch", "watch [目录...]", "持续监控收件目录（Linux）", runWatchCLICommand},
+}
+
+// runCLI 解析命令行并执行子命令，返回进程退出码
+func runCLI(args []string) int {
+	// 读取配置前先使用默认日志设置，同样会隐藏密钥
+	setupLogging(LogConfig{})
+	opts := &cliOptions{Format: formatText, info: os.Stdout}
+	global := flag.NewFlagSet("fileclassify", flag.ContinueOnError)
+	opts.register(global)
+	gui := global.Bool("gui", false, "打开图形界面，可与 -config 一起使用")
+	global.Usage = func() { printCLIUsage(global) }
+	if err := global.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if *gui {
+		if len(global.Args()) > 0 {
+			fmt.Fprintln(os.Stderr, "-gui 不能与子命令一起使用")
+			return exitUsage
+		}
+		createMainWindow()
+		return exitOK
+	}
+
+	// 不写子命令时与原来的交互方式相同：分类并移动文件
+	command := cliCommand{name: "apply", run: runApplyCommand}
+	rest := global.Args()
+	if len(rest) > 0 {
+		if rest[0] == "help" {
+			printCLIUsage(global)
+			return exitOK
+		}
+		found := false
+		for _, c := range cliCommands {
+			if c.name == rest[0] {
+				command, rest, found = c, rest[1:], true
+				break
+			}
+		}
+		if !found {
+			fmt.Fprintf(os.Stderr, "未知的子命令: %s\n\n", rest[0])
+			printCLIUsage(global)
+			return exitUsage
+		}
+	}
+
+	err := command.run(opts, rest)
+	if err == nil {
+		return exitOK
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	code := exitError
+	var ce *cliError
+	if errors.As(err, &ce) {
+		code = ce.code
+	}
+	if msg := err.Error(); msg != "" {
+		fmt.Fprintln(os.Stderr, msg)
+	}
+	return code
+}
+
+func printCLIUsage(global *flag.FlagSet) {
+	w := global.Output()
+	fmt.Fprintf(w, "用法: fileclassify [参数] [子命令] [子命令参数]\n      fileclassify -gui\n\n子命令：\n")
+	for _, c := range cliCommands {
+		fmt.Fprintf(w, "  %-28s %s\n", c.usage, c.summary)
+	}
+	fmt.Fprintf(w, "\n参数（写在子命令前后均可）：\n")
+	global.PrintDefaults()
+	fmt.Fprintf(w, "\n退出码：0 成功，1 运行失败，2 参数或配置错误，3 已取消，4 部分文件处理失败\n")
+}
+
+// parseCommandFlags 解析子命令参数，rootArg 为 true 时第一个位置参数可以代替 -root
+func (o *cliOptions) parseCommandFlags(name string, args []string, rootArg bool) ([]string, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	o.register(fs)
+	rest, err := parseInterspersed(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if rootArg && o.Root == "" && len(rest) > 0 {
+		o.Root, rest = rest[0], rest[1:]
+	}
+	if err := o.setupOutput(); err != nil {
+		return nil, err
+	}
+	return rest, nil
+}
+
+// parseInterspersed 解析参数，位置参数之后的参数同样生效，如 plan ~/Downloads -plan p.json
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, exitWith(exitUsage, nil)
+		}
+		rest := fs.Args()
+		// "--" 之后全部是位置参数
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// setupOutput 校验输出格式；JSON 格式时结果写到标准输出，其余信息写到标准错误，方便脚本解析
+// 进度和其他提示信息输出到同一位置
+func (o *cliOptions) setupOutput() error {
+	if o.out != nil {
+		return nil
+	}
+	switch o.Format {
+	case formatText:
+		o.out = os.Stdout
+	case formatJSON:
+		o.out, o.info = os.Stdout, os.Stderr
+	default:
+		return usageError("不支持的输出格式: %s（可选 text、json）", o.Format)
+	}
+	setProgressHandler(newCLIProgress(o.info).handle)
+	return nil
+}
+
+// loadOrganizer 加载配置，应用命令行覆盖项并在扫描前校验
+func (o *cliOptions) loadOrganizer() (*Organizer, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, usageError("加载配置失败: %v", err)
+	}
+	if err := o.setupLogging(config); err != nil {
+		return nil, usageError("%v", err)
+	}
+	org, err := NewOrganizer(config, OrganizerOptions{
+		Provider:    o.Provider,
+		Template:    o.Template,
+		Prompt:      o.Prompt,
+		Duplicates:  o.Duplicates,
+		Symlinks:    o.Symlinks,
+		Conflict:    o.Conflict,
+		Dirs:        o.Dirs,
+		NoCache:     o.NoCache,
+		Incremental: o.Incremental,
+	})
+	if err != nil {
+		return nil, usageError("%v", err)
+	}
+	org.Hooks.ScanWarnings = func(warnings []string) { printScanWarnings(o.info, warnings) }
+	org.Hooks.Duplicates = func(groups []DuplicateGroup) { printDuplicateGroups(o.info, groups) }
+	org.Hooks.Classified = func(classified map[string][]FileInfo) { printClassifiedSummary(o.info, classified) }
+	return org, nil
+}
+
+// scan 扫描整理目录并显示找到的文件数
+func (o *cliOptions) scan(org *Organizer, root string) (*ScanResult, error) {
+	scan, err := org.Scan(root)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(o.info, "找到 %d 个文件\n", scan.Found)
+	if org.Options.Incremental {
+		fmt.Fprintf(o.info, "增量模式：%d 个新增或修改的文件，已有 %d 个分类\n", len(scan.Files), len(scan.Existing))
+	}
+	return scan, nil
+}
+
+// printClassifiedSummary 显示每个分类的文件数
+func printClassifiedSummary(w io.Writer, classified map[string][]FileInfo) {
+	fmt.Fprintf(w, "分类完成，共 %d 个分类\n", len(classified))
+	for category, files := range classified {
+		fmt.Fprintf(w, "- %s: %d 个文件\n", category, len(files))
+	}
+}
+
+// setupLogging 按配置和命令行参数设置日志
+func (o *cliOptions) setupLogging(config *Config) error {
+	if o.LogLevel != "" {
+		config.Log.Level = o.LogLevel
+	}
+	if o.Verbose {
+		config.Log.Level = "debug"
+	}
+	if o.LogFile != "" {
+		config.Log.File = o.LogFile
+	}
+	if o.LogFormat != "" {
+		config.Log.Format = o.LogFormat
+	}
+	return setupLogging(config.Log)
+}
+
+// openProvider 创建大模型提供者并打开分类缓存，配置有误时为参数错误
+func (o *cliOptions) openProvider(org *Organizer) (LLMProvider, error) {
+	provider, err := org.OpenProvider()
+	if err != nil {
+		return nil, usageError("%v", err)
+	}
+	return provider, nil
+}
+
+// stdinIsTerminal 判断标准输入是否连接到终端
+func stdinIsTerminal() bool {
+	return isTerminal(os.Stdin)
+}
+
+// stdinReader 返回读取标准输入的缓冲，所有询问共用一个，避免输入被其他缓冲读走
+func (o *cliOptions) stdinReader() *bufio.Reader {
+	if o.reader == nil {
+		o.reader = bufio.NewReader(os.Stdin)
+	}
+	return o.reader
+}
+
+func (o *cliOptions) readLine() (string, error) {
+	line, err := o.stdinReader().ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// resolveRoot 确定整理目录；未指定时在终端中询问，非终端时从标准输入读取一行
+func (o *cliOptions) resolveRoot() (string, error) {
+	root := o.Root
+	if root == "" {
+		if stdinIsTerminal() {
+			fmt.Fprint(o.info, "请输入要整理的文件夹路径: ")
+		}
+		line, err := o.readLine()
+		if err != nil {
+			return "", usageError("未指定整理目录，请使用 -root 参数")
+		}
+		root = line
+	}
+	if root == "" {
+		return "", usageError("未指定整理目录，请使用 -root 参数")
+	}
+
+	abs, err := filepath.Abs(expandHome(root))
+	if err != nil {
+		return "", usageError("解析目录失败 %s: %v", root, err)
+	}
+	if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+		return "", usageError("目录不存在: %s", abs)
+	}
+	o.Root = abs
+	return abs, nil
+}
+
+// canPrompt 判断是否可以询问用户；非终端且未指定 -yes 时返回错误
+func (o *cliOptions) canPrompt() error {
+	if o.Yes || o.DryRun || stdinIsTerminal() {
+		return nil
+	}
+	return usageError("标准输入不是终端，无法确认操作，请使用 -yes 或 -dry-run")
+}
+
+// confirm 在终端中询问是否继续，-yes 时直接确认
+func (o *cliOptions) confirm(question string) bool {
+	if o.Yes {
+		return true
+	}
+	fmt.Fprintf(o.info, "\n%s(y/N): ", question)
+	answer, _ := o.readLine()
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes"
+}
+
+// writeJSON 将结果以 JSON 格式写到输出
+func (o *cliOptions) writeJSON(v interface{}) error {
+	encoder := json.NewEncoder(o.out)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(v)
+}
+
+// runScanCommand 列出扫描到的文件
+func runScanCommand(o *cliOptions, args []string) error {
+	if _, err := o.parseCommandFlags("scan", args, true); err != nil {
+		return err
+	}
+	org, err := o.loadOrganizer()
+	if err != nil {
+		return err
+	}
+	root, err := o.resolveRoot()
+	if err != nil {
+		return err
+	}
+	scan, err := o.scan(org, root)
+	if err != nil {
+		return err
+	}
+	files := scan.Files
+
+	if o.Format == formatJSON {
+		return o.writeJSON(map[string]interface{}{"root": root, "files": nonNilFiles(files)})
+	}
+	for _, file := range files {
+		kind := ""
+		switch {
+		case file.IsDir:
+			kind = "（整个目录）"
+		case file.IsSymlink:
+			kind = "（符号链接）"
+		}
+		fmt.Fprintf(o.out, "%s\t%d\t%s%s\n", file.ModTime.Format("2006-01-02 15:04"), file.Size, file.Path, kind)
+	}
+	return nil
+}
+
+// runClassifyCommand 分类并输出结果，不移动文件
+func runClassifyCommand(o *cliOptions, args []string) error {
+	if _, err := o.parseCommandFlags("classify", args, true); err != nil {
+		return err
+	}
+	org, err := o.loadOrganizer()
+	if err != nil {
+		return err
+	}
+	root, err := o.resolveRoot()
+	if err != nil {
+		return err
+	}
+	provider, err := o.openProvider(org)
+	if err != nil {
+		return err
+	}
+	scan, err := o.scan(org, root)
+	if err != nil {
+		return err
+	}
+	if len(scan.Files) == 0 {
+		fmt.Fprintln(o.info, "没有需要分类的文件")
+		return nil
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+	classified, err := org.Classify(ctx, root, scan.Files, scan.Existing)
+	if ctx.Err() != nil {
+		fmt.Fprintln(o.info, "已取消分类")
+		return exitWith(exitCancelled, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	categories := make([]string, 0, len(classified))
+	for category := range classified {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	if o.Format == formatJSON {
+		result := make(map[string][]string)
+		for _, category := range categories {
+			for _, file := range classified[category] {
+				result[category] = append(result[category], file.Path)
+			}
+		}
+		modelName, _, _ := provider.GetConfig()
+		return o.writeJSON(map[string]interface{}{"root": root, "model": modelName, "prompt_version": org.PromptID(), "categories": result})
+	}
+	for _, category := range categories {
+		fmt.Fprintf(o.out, "%s（%d）\n", category, len(classified[category]))
+		for _, file := range classified[category] {
+			fmt.Fprintf(o.out, "  %s\n", file.Path)
+		}
+	}
+	return nil
+}
+
+// runPlanCommand 生成移动计划并输出或保存
+func runPlanCommand(o *cliOptions, args []string) error {
+	if _, err := o.parseCommandFlags("plan", args, true); err != nil {
+		return err
+	}
+	ctx, stop := signalContext()
+	defer stop()
+	plan, _, _, err := o.buildPlan(ctx)
+	if err != nil {
+		return err
+	}
+
+	if o.PlanFile != "" {
+		if err := plan.save(o.PlanFile); err != nil {
+			return fmt.Errorf("保存计划失败: %v", err)
+		}
+		fmt.Fprintf(o.info, "计划已保存到 %s，检查后可使用 apply -plan %s 执行\n", o.PlanFile, o.PlanFile)
+	}
+	return o.printPlan(plan)
+}
+
+// buildPlan 扫描、分类并生成移动计划，ctx 取消时中止正在进行的API请求
+func (o *cliOptions) buildPlan(ctx context.Context) (*PlanFile, *Organizer, *OrganizeState, error) {
+	if o.Review && !stdinIsTerminal() {
+		return nil, nil, nil, usageError("标准输入不是终端，无法使用 -review 审查分类结果")
+	}
+	org, err := o.loadOrganizer()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	root, err := o.resolveRoot()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if _, err := o.openProvider(org); err != nil {
+		return nil, nil, nil, err
+	}
+	scan, err := o.scan(org, root)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(scan.Files) == 0 {
+		fmt.Fprintln(o.info, "没有需要整理的文件")
+	}
+
+	if o.Review {
+		org.Hooks.Review = func(classified map[string][]FileInfo) (map[string][]FileInfo, error) {
+			return reviewClassification(o.stdinReader(), o.info, classified)
+		}
+	}
+	plan, err := org.Plan(ctx, root, scan.Files, scan.Existing)
+	if err != nil {
+		if errors.Is(err, errReviewCancelled) || ctx.Err() != nil {
+			fmt.Fprintln(o.info, "已取消，未移动任何文件")
+			return nil, nil, nil, exitWith(exitCancelled, nil)
+		}
+		return nil, nil, nil, err
+	}
+	return plan, org, scan.State, nil
+}
+
+// printPlan 按输出格式打印移动计划
+func (o *cliOptions) printPlan(plan *PlanFile) error {
+	if o.Format == formatJSON {
+		return o.writeJSON(plan)
+	}
+	for _, move := range plan.Moves {
+		suffix := ""
+		if move.File.IsDir {
+			suffix = "/"
+		}
+		fmt.Fprintf(o.out, "%s%s -> %s%s\n", move.File.Path, suffix, move.Dst, suffix)
+	}
+	return nil
+}
+
+// runApplyCommand 分类并移动文件，或执行保存的计划
+func runApplyCommand(o *cliOptions, args []string) error {
+	if _, err := o.parseCommandFlags("apply", args, true); err != nil {
+		return err
+	}
+	// 报告格式写错时在移动前报错，而不是移动完才发现
+	for _, path := range o.reportPaths() {
+		if err := validateReportPath(path); err != nil {
+			return usageError("%v", err)
+		}
+	}
+	// 在调用模型之前确认能否询问用户，避免分类后才发现无法继续
+	if err := o.canPrompt(); err != nil {
+		return err
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+
+	var (
+		plan  *PlanFile
+		org   *Organizer
+		state *OrganizeState
+		err   error
+	)
+	// 报告从分类前开始统计耗时和 token 用量
+	report := NewRunReport(o.Root)
+	if o.PlanFile != "" {
+		if o.Review {
+			return usageError("-review 只能在分类时使用，不能与 -plan 一起执行保存的计划")
+		}
+		if plan, err = loadPlanFile(o.PlanFile); err != nil {
+			return usageError("%v", err)
+		}
+		if o.Root != "" {
+			root, err := o.resolveRoot()
+			if err != nil {
+				return err
+			}
+			if root != plan.Root {
+				return usageError("计划文件对应的目录是 %s，与 -root 不一致", plan.Root)
+			}
+		}
+		if org, err = o.loadOrganizer(); err != nil {
+			return err
+		}
+		if state, err = LoadOrganizeState(plan.Root); err != nil {
+			return err
+		}
+	} else if plan, org, state, err = o.buildPlan(ctx); err != nil {
+		return err
+	}
+
+	ops, err := plan.moveOps()
+	if err != nil {
+		return usageError("%v", err)
+	}
+	if len(ops) == 0 {
+		fmt.Fprintln(o.info, "没有需要移动的文件")
+		return nil
+	}
+
+	if o.DryRun {
+		fmt.Fprintln(o.info, "试运行，不会移动任何文件：")
+		return o.printPlan(plan)
+	}
+
+	// 交互式处理目标冲突，非终端时 ask 策略按 rename 处理
+	if stdinIsTerminal() {
+		org.Hooks.AskConflict = func(src, dst string) (string, bool) {
+			for {
+				fmt.Fprintf(o.info, "\n目标文件已存在: %s\n来源文件: %s\n", dst, src)
+				fmt.Fprint(o.info, "[s]跳过 [o]覆盖 [n]较新时覆盖 [r]重命名 [d]相同则去重（大写表示之后都这样处理，默认 r）: ")
+				answer, _ := o.readLine()
+				if policy, applyToAll, ok := parseConflictAnswer(answer); ok {
+					return policy, applyToAll
+				}
+			}
+		}
+	}
+	org.Hooks.Confirm = func(ops []MoveOp) bool {
+		printMovePlan(o.info, plan.Root, ops)
+		if !o.confirm("确认按以上路径移动文件吗？") {
+			fmt.Fprintln(o.info, "已取消移动")
+			return false
+		}
+		return true
+	}
+
+	// 收到中断信号时在当前文件移动完成后停止，已移动的文件记录在运行日志中
+	report, journal, err := org.Apply(ctx, plan, state, report)
+	if err != nil {
+		return usageError("%v", err)
+	}
+	report.PrintSummary(o.info)
+	if err := o.writeReports(report); err != nil {
+		return err
+	}
+
+	if o.Format == formatJSON {
+		output := map[string]interface{}{"report": report}
+		if journal != nil && report.Moved > 0 {
+			output["run_id"] = journal.ID
+		}
+		if err := o.writeJSON(output); err != nil {
+			return err
+		}
+	}
+	switch {
+	case report.Cancelled:
+		return exitWith(exitCancelled, nil)
+	case len(report.Failures) > 0:
+		return exitWith(exitPartial, fmt.Errorf("%d 个文件处理失败", len(report.Failures)))
+	}
+	if journal != nil {
+		fmt.Fprintf(o.info, "文件整理完成！如需撤销，请运行: fileclassify undo -run %s\n", journal.ID)
+	} else {
+		fmt.Fprintln(o.info, "文件整理完成！")
+	}
+	return nil
+}
+
+// reportPaths 返回 -report 参数中的报告文件
+func (o *cliOptions) reportPaths() []string {
+	var paths []string
+	for _, path := range strings.Split(o.Report, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, expandHome(path))
+		}
+	}
+	return paths
+}
+
+// writeReports 按 -report 参数保存运行报告
+func (o *cliOptions) writeReports(report *RunReport) error {
+	if o.Report == "" || report.Cancelled {
+		return nil
+	}
+	for _, path := range o.reportPaths() {
+		if err := report.WriteReport(path); err != nil {
+			return err
+		}
+		fmt.Fprintf(o.info, "运行报告已保存到 %s\n", path)
+	}
+	return nil
+}
+
+// runUndoCommand 撤销一次整理
+func runUndoCommand(o *cliOptions, args []string) error {
+	if _, err := o.parseCommandFlags("undo", args, true); err != nil {
+		return err
+	}
+	if err := o.canPrompt(); err != nil {
+		return err
+	}
+
+	root := ""
+	if o.Root != "" {
+		abs, err := filepath.Abs(expandHome(o.Root))
+		if err != nil {
+			return usageError("解析目录失败 %s: %v", o.Root, err)
+		}
+		root = abs
+	}
+	journal, err := findRunJournal(o.RunID, root)
+	if err != nil {
+		return usageError("%v", err)
+	}
+	if !journal.UndoneAt.IsZero() && o.RunID == "" {
+		return usageError("运行 %s 已经撤销过", journal.ID)
+	}
+
+	fmt.Fprintf(o.info, "将撤销 %s 在 %s 中的 %d 个操作：\n", journal.StartedAt.Format("2006-01-02 15:04:05"), journal.Root, len(journal.Entries))
+	if _, failures := journal.Undo(true, o.info); len(failures) > 0 {
+		for _, failure := range failures {
+			fmt.Fprintf(o.info, "  无法恢复：%s\n", failure)
+		}
+	}
+	if o.DryRun {
+		return nil
+	}
+	if !o.confirm("确认撤销吗？") {
+		fmt.Fprintln(o.info, "已取消撤销")
+		return exitWith(exitCancelled, nil)
+	}
+
+	restored, failures := journal.Undo(false, nil)
+	for _, failure := range failures {
+		fmt.Fprintln(o.info, failure)
+	}
+	if o.Format == formatJSON {
+		if err := o.writeJSON(map[string]interface{}{"run_id": journal.ID, "restored": restored, "failures": failures}); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(o.info, "已恢复 %d 个文件\n", restored)
+	if len(failures) > 0 {
+		return exitWith(exitPartial, nil)
+	}
+	return nil
+}
+
+// runHistoryCommand 列出运行记录
+func runHistoryCommand(o *cliOptions, args []string) error {
+	if _, err := o.parseCommandFlags("history", args, false); err != nil {
+		return err
+	}
+	journals, err := ListRunJournals()
+	if err != nil {
+		return err
+	}
+
+	if o.Format == formatJSON {
+		if journals == nil {
+			journals = []*RunJournal{}
+		}
+		return o.writeJSON(journals)
+	}
+	if len(journals) == 0 {
+		fmt.Fprintln(o.out, "没有运行记录")
+		return nil
+	}
+	for _, j := range journals {
+		status := ""
+		switch {
+		case !j.UndoneAt.IsZero():
+			status = "（已撤销）"
+		case j.FinishedAt.IsZero():
+			status = "（未完成）"
+		}
+		fmt.Fprintf(o.out, "%s  %s  %d 个操作  %s%s\n", j.ID, j.StartedAt.Format("2006-01-02 15:04:05"), len(j.Entries), j.Root, status)
+	}
+	return nil
+}
+
+// providerSummary providers 命令输出的模型信息
+type providerSummary struct {
+	Name      string `json:"name"`
+	Default   bool   `json:"default"`
+	Model     string `json:"model,omitempty"`
+	APIURL    string `json:"api_url,omitempty"`
+	APIKey    string `json:"api_key"`
+	KeyStatus string `json:"key_status"`
+}
+
+// runProvidersCommand 列出已配置的模型
+func runProvidersCommand(o *cliOptions, args []string) error {
+	if _, err := o.parseCommandFlags("providers", args, false); err != nil {
+		return err
+	}
+	config, err := LoadConfig()
+	if err != nil {
+		return usageError("加载配置失败: %v", err)
+	}
+
+	names := make([]string, 0, len(config.Providers))
+	for name := range config.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	summaries := make([]providerSummary, 0, len(names))
+	for _, name := range names {
+		pc := config.Providers[name]
+		status := "已设置"
+		switch {
+		case pc.APIKey == "" && config.secretProviders[name]:
+			status = "已加密保存"
+		case pc.APIKey == "":
+			status = "未设置"
+		case isPlaceholderKey(pc.APIKey):
+			status = "示例值，需要替换"
+		}
+		summaries = append(summaries, providerSummary{
+			Name:      name,
+			Default:   name == config.DefaultProvider,
+			Model:     pc.ModelName,
+			APIURL:    pc.APIURL,
+			APIKey:    maskSecret(pc.APIKey),
+			KeyStatus: status,
+		})
+	}
+
+	if o.Format == formatJSON {
+		return o.writeJSON(summaries)
+	}
+	for _, s := range summaries {
+		mark := " "
+		if s.Default {
+			mark = "*"
+		}
+		model := s.Model
+		if model == "" {
+			model = "（默认模型）"
+		}
+		fmt.Fprintf(o.out, "%s %-12s %-24s 密钥%s\n", mark, s.Name, model, s.KeyStatus)
+	}
+	return nil
+}
+
+// promptSummary prompts 命令输出的模板信息
+type promptSummary struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Current bool   `json:"current"`
+	Error   string `json:"error,omitempty"`
+}
+
+// runPromptsCommand 列出可用的提示词模板，指定名称时输出模板内容，可复制到模板目录后修改
+func runPromptsCommand(o *cliOptions, args []string) error {
+	rest, err := o.parseCommandFlags("prompts", args, false)
+	if err != nil {
+		return err
+	}
+	config, err := LoadConfig()
+	if err != nil {
+		return usageError("加载配置失败: %v", err)
+	}
+	if o.Prompt != "" {
+		config.Prompt.Name = o.Prompt
+	}
+
+	if len(rest) > 0 {
+		pc := config.promptConfig()
+		pc.Name = rest[0]
+		prompt, err := pc.LoadPrompt()
+		if err != nil {
+			return usageError("%v", err)
+		}
+		if o.Format == formatJSON {
+			return o.writeJSON(map[string]string{"name": prompt.Name, "version": prompt.ID(), "source": prompt.Source, "text": prompt.text})
+		}
+		fmt.Fprint(o.out, prompt.text)
+		fmt.Fprintln(o.out)
+		return nil
+	}
+
+	current := config.Prompt.Name
+	if current == "" {
+		current = defaultPromptName
+	}
+	names, _ := config.Prompt.PromptNames()
+	summaries := make([]promptSummary, 0, len(names))
+	for _, name := range names {
+		pc := config.promptConfig()
+		pc.Name = name
+		summary := promptSummary{Name: name, Current: name == current}
+		if prompt, err := pc.LoadPrompt(); err != nil {
+			summary.Error = err.Error()
+		} else {
+			summary.Version, summary.Source = prompt.ID(), prompt.Source
+		}
+		summaries = append(summaries, summary)
+	}
+
+	if o.Format == formatJSON {
+		return o.writeJSON(summaries)
+	}
+	if dir, err := config.Prompt.promptDir(); err == nil {
+		fmt.Fprintf(o.out, "自定义模板目录: %s\n", dir)
+	}
+	for _, s := range summaries {
+		mark := " "
+		if s.Current {
+			mark = "*"
+		}
+		detail := s.Source
+		if s.Error != "" {
+			detail = s.Error
+		}
+		fmt.Fprintf(o.out, "%s %-12s %-20s %s\n", mark, s.Name, s.Version, detail)
+	}
+	return nil
+}
+
+// runConfigCommand 查看配置
+func runConfigCommand(o *cliOptions, args []string) error {
+	rest, err := o.parseCommandFlags("config", args, false)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return usageError("用法: config <show|path|init|set-key>")
+	}
+
+	switch rest[0] {
+	case "path":
+		path, err := findConfigFile()
+		if err != nil {
+			return usageError("%v", err)
+		}
+		if path == "" {
+			fmt.Fprintln(o.info, "没有找到配置文件，使用内置默认配置。查找顺序：-config 参数、$"+configEnvVar+"、./config.json、用户配置目录")
+			return exitWith(exitError, nil)
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		fmt.Fprintln(o.out, path)
+		return nil
+
+	case "init":
+		path := configFile
+		if path == "" {
+			var err error
+			if path, err = userConfigPath(); err != nil {
+				return err
+			}
+		}
+		if err := writeConfigTemplate(path); err != nil {
+			return usageError("%v", err)
+		}
+		fmt.Fprintf(o.out, "已创建配置文件 %s，密钥默认从环境变量读取\n", path)
+		return nil
+
+	case "show":
+		config, err := LoadConfig()
+		if err != nil {
+			return usageError("加载配置失败: %v", err)
+		}
+		// 输出时隐藏密钥
+		masked := *config
+		masked.Providers = make(map[string]ProviderConfig, len(config.Providers))
+		for name, pc := range config.Providers {
+			pc.APIKey = maskSecret(pc.APIKey)
+			pc.APISecret = maskSecret(pc.APISecret)
+			masked.Providers[name] = pc
+		}
+		return o.writeJSON(masked)
+
+	case "set-key":
+		return o.runSetKeyCommand(rest[1:])
+	}
+	return usageError("未知的 config 子命令: %s", rest[0])
+}
+
+// runSetKeyCommand 把密钥加密保存到密钥文件，并删除配置文件中对应的明文密钥
+// 密钥在终端中不回显地输入，非终端时从标准输入读取一行
+func (o *cliOptions) runSetKeyCommand(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return usageError("用法: config set-key <提供者> [api_key|api_secret]")
+	}
+	name, field := args[0], "api_key"
+	if len(args) == 2 {
+		field = args[1]
+	}
+	if field != "api_key" && field != "api_secret" {
+		return usageError("只能保存 api_key 或 api_secret，不支持 %s", field)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return usageError("加载配置失败: %v", err)
+	}
+	if _, ok := config.Providers[name]; !ok {
+		return usageError("providers 中没有 %s 的配置，可选 %s", name, strings.Join(supportedProviders, "、"))
+	}
+
+	path, err := config.secretsPath()
+	if err != nil {
+		return err
+	}
+	prompt, confirm := "请输入密钥文件口令: ", false
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		prompt, confirm = "设置密钥文件口令: ", true
+	}
+	passphrase, err := readPassphrase(prompt, confirm)
+	if err != nil {
+		return usageError("%v", err)
+	}
+	store, err := OpenSecretStore(path, passphrase)
+	if err != nil {
+		return usageError("打开密钥文件失败: %v", err)
+	}
+
+	var value string
+	if stdinIsTerminal() {
+		fmt.Fprintf(os.Stderr, "请输入 %s 的 %s: ", name, field)
+		value, err = readPassword(os.Stdin)
+	} else {
+		value, err = o.readLine()
+	}
+	if err != nil || strings.TrimSpace(value) == "" {
+		return usageError("没有读取到密钥")
+	}
+	if err := store.Set(name, field, strings.TrimSpace(value)); err != nil {
+		return usageError("%v", err)
+	}
+	if err := store.Save(); err != nil {
+		return fmt.Errorf("保存密钥文件失败: %v", err)
+	}
+	fmt.Fprintf(o.out, "已将 %s 的 %s 加密保存到 %s\n", name, field, path)
+
+	// 配置文件中的明文密钥不再需要
+	if config.Path() == "" {
+		return nil
+	}
+	raw, pc := config.rawProviders[name], config.Providers[name]
+	plaintext := func(v string) bool { return v != "" && !strings.Contains(v, "${") && !isPlaceholderKey(v) }
+	switch {
+	case field == "api_key" && plaintext(raw.APIKey):
+		pc.APIKey = ""
+	case field == "api_secret" && plaintext(raw.APISecret):
+		pc.APISecret = ""
+	default:
+		return nil
+	}
+	config.Providers[name] = pc
+	if err := saveConfig(config); err != nil {
+		return fmt.Errorf("删除配置文件中的明文密钥失败: %v", err)
+	}
+	fmt.Fprintf(o.out, "已删除 %s 中的明文密钥\n", config.Path())
+	return nil
+}
+
+// runCacheCLICommand 处理 cache 子命令
+func runCacheCLICommand(o *cliOptions, args []string) error {
+	config, err := LoadConfig()
+	if err != nil {
+		return usageError("加载配置失败: %v", err)
+	}
+	if err := runCacheCommand(config, args); err != nil {
+		return usageError("%v", err)
+	}
+	return nil
+}
+
+// runWatchCLICommand 处理 watch 子命令
+func runWatchCLICommand(o *cliOptions, args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	o.register(fs)
+	debounce := fs.Duration("debounce", 0, "最后一次文件事件后等待的时间，默认使用配置或5秒")
+	stable := fs.Duration("stable", 0, "文件大小保持不变多久才开始处理，默认使用配置或3秒")
+	inboxes, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := o.setupOutput(); err != nil {
+		return err
+	}
+	if o.Root != "" {
+		inboxes = append([]string{o.Root}, inboxes...)
+	}
+
+	org, err := o.loadOrganizer()
+	if err != nil {
+		return err
+	}
+	if _, err := o.openProvider(org); err != nil {
+		return err
+	}
+	ctx, stop := signalContext()
+	defer stop()
+	return runWatchCommand(ctx, o, org, inboxes, *debounce, *stable)
+}
+
+// signalContext 返回收到 SIGINT 或 SIGTERM 时取消的 context
+// 第一次信号只取消 context，之后恢复默认处理，再次按 Ctrl+C 时立即退出
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// maskSecret 隐藏密钥的中间部分
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return secret[:3] + "****" + secret[len(secret)-4:]
+}
+
+func nonNilFiles(files []FileInfo) []FileInfo {
+	if files == nil {
+		return []FileInfo{}
+	}
+	return files
+}
@@ -3,7 +3,13 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 )
 
 // ProviderConfig 定义单个提供者的配置
@@ -16,61 +22,340 @@ type ProviderConfig struct {
 
 // Config 定义配置结构
 type Config struct {
+	path         string                    // 加载配置的文件
+	rawProviders map[string]ProviderConfig // 展开环境变量前的提供者配置
+	envProviders map[string]ProviderConfig // 加载时展开环境变量后的提供者配置
+
+	secretProviders map[string]bool // 加密密钥文件中保存了密钥的提供者
+	secretsLoaded   bool            // 已尝试解密密钥文件
+	secretsErr      error           // 解密密钥文件的错误
+
 	DefaultProvider string                    `json:"default_provider"`
 	Providers       map[string]ProviderConfig `json:"providers"`
+	SecretsFile     string                    `json:"secrets_file,omitempty"`  // 加密密钥文件，默认在用户配置目录下
+	PathTemplate    string                    `json:"path_template,omitempty"` // 目标路径模板，默认 {category}/{name}{ext}
+	Prompt          PromptConfig              `json:"prompt"`
+	Naming          NamingConfig              `json:"naming"`
+	Log             LogConfig                 `json:"log"`
+	Scan            ScanOptions               `json:"scan"`
+	DuplicatePolicy string                    `json:"duplicate_policy,omitempty"` // 重复文件处理策略：off、report、move、hardlink
+	Conflict        ConflictConfig            `json:"conflict"`
+	Cache           CacheConfig               `json:"cache"`
+	Watch           WatchConfig               `json:"watch"`
 }
 
-// LoadConfig 从文件加载配置
-func LoadConfig() (*Config, error) {
-	config := &Config{
+// CacheConfig 定义分类缓存的配置
+type CacheConfig struct {
+	Disabled    bool   `json:"disabled,omitempty"`     // 关闭分类缓存
+	HashContent bool   `json:"hash_content,omitempty"` // 缓存键中包含文件内容哈希
+	Path        string `json:"path,omitempty"`         // 缓存文件位置，默认在用户缓存目录下
+}
+
+// configFile 由 -config 参数指定的配置文件
+var configFile string
+
+// configEnvVar 指定配置文件位置的环境变量
+const configEnvVar = "FILECLASSIFY_CONFIG"
+
+// providerKeyEnvAliases 各提供者常用的密钥环境变量，只在配置中没有有效密钥时使用
+var providerKeyEnvAliases = map[string]string{
+	"deepseek":    "DEEPSEEK_API_KEY",
+	"siliconflow": "SILICONFLOW_API_KEY",
+	"aliyun":      "DASHSCOPE_API_KEY",
+	"github":      "GITHUB_TOKEN",
+}
+
+// defaultConfig 返回内置默认配置
+func defaultConfig() *Config {
+	return &Config{
 		DefaultProvider: "deepseek",
 		Providers: map[string]ProviderConfig{
 			"deepseek": {
-				APIKey: "your_deepseek_api_key_here",
+				APIURL:    "https://api.deepseek.com/v1/chat/completions",
+				ModelName: "deepseek-chat",
 			},
 			"siliconflow": {
-				APIKey: "your_siliconflow_api_key_here",
+				APIURL:    "https://api.siliconflow.cn/v1/chat/completions",
+				ModelName: "deepseek-ai/DeepSeek-V3",
 			},
 			"aliyun": {
-				APIKey:    "your_aliyun_api_key_here",
-				APISecret: "your_aliyun_api_secret_here",
+				APIURL:    "https://dashscope.aliyuncs.com/compatible-mode/v1/chat/completions",
+				ModelName: "qwen-max",
 			},
 			"github": {
-				APIKey:    "your_github_api_key_here",
 				APIURL:    "https://models.inference.ai.azure.com/chat/completions",
 				ModelName: "gpt-4o",
 			},
 		},
+		Scan: ScanOptions{
+			// 复制一份，解析配置文件时会复用切片的底层数组，直接引用会改掉全局的默认规则
+			IgnorePatterns: append([]string(nil), defaultIgnorePatterns...),
+		},
 	}
+}
 
-	// 尝试读取配置文件
-	data, err := os.ReadFile("config.json")
+// userConfigPath 用户配置目录下的配置文件，Linux 下为 ~/.config/fileclassify/config.json
+func userConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
 	if err != nil {
-		if os.IsNotExist(err) {
-			// 如果文件不存在，创建默认配置文件
-			if err := saveConfig(config); err != nil {
-				return nil, fmt.Errorf("创建默认配置文件失败: %v", err)
+		return "", err
+	}
+	return filepath.Join(dir, "fileclassify", "config.json"), nil
+}
+
+// findConfigFile 按 -config、$FILECLASSIFY_CONFIG、./config.json、用户配置目录的顺序查找配置文件
+// 通过参数或环境变量明确指定的文件不存在时返回错误；都没有找到时返回空路径
+func findConfigFile() (string, error) {
+	for _, explicit := range []string{configFile, os.Getenv(configEnvVar)} {
+		if explicit == "" {
+			continue
+		}
+		path := expandHome(explicit)
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("找不到配置文件 %s: %v", path, err)
+		}
+		return path, nil
+	}
+
+	candidates := []string{"config.json"}
+	if path, err := userConfigPath(); err == nil {
+		candidates = append(candidates, path)
+	}
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// LoadConfig 查找并加载配置文件，没有配置文件时使用内置默认配置，不会自动创建文件
+// 配置值中的 ${ENV} 会被替换为环境变量，密钥可以通过环境变量提供
+func LoadConfig() (*Config, error) {
+	config := defaultConfig()
+
+	path, err := findConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取配置文件失败: %v", err)
+		}
+		if err := json.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("解析配置文件 %s 失败: %v", path, err)
+		}
+		config.path = path
+
+		// 明文密钥不能让其他用户读到
+		if config.hasPlaintextKeys() {
+			if info, err := os.Stat(path); err == nil {
+				if err := checkPrivateFile(path, info); err != nil {
+					return nil, fmt.Errorf("%v，或使用 config set-key 把密钥保存到加密密钥文件", err)
+				}
 			}
-			return config, nil
 		}
-		return nil, fmt.Errorf("读取配置文件失败: %v", err)
 	}
 
-	// 解析配置文件
-	if err := json.Unmarshal(data, config); err != nil {
-		return nil, fmt.Errorf("解析配置文件失败: %v", err)
+	secretsPath, err := config.secretsPath()
+	if err != nil {
+		return nil, err
+	}
+	sf, err := readSecretsHeader(secretsPath)
+	if err != nil {
+		return nil, err
+	}
+	config.secretProviders = map[string]bool{}
+	if sf != nil {
+		for _, name := range sf.Providers {
+			config.secretProviders[name] = true
+		}
 	}
 
+	config.applyEnv()
 	return config, nil
 }
 
-// saveConfig 保存配置到文件
+// hasPlaintextKeys 判断配置文件中是否直接写有密钥，${ENV} 引用和示例值不算
+func (c *Config) hasPlaintextKeys() bool {
+	for _, pc := range c.Providers {
+		for _, value := range []string{pc.APIKey, pc.APISecret} {
+			if value != "" && !strings.Contains(value, "${") && !isPlaceholderKey(value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// secretsPath 返回加密密钥文件的位置
+func (c *Config) secretsPath() (string, error) {
+	if c.SecretsFile != "" {
+		return expandHome(expandEnvRefs(c.SecretsFile)), nil
+	}
+	return defaultSecretsPath()
+}
+
+// unlockSecrets 在需要时解密密钥文件，填入配置中没有有效密钥的提供者，只询问一次口令
+func (c *Config) unlockSecrets(name string) error {
+	if !c.secretProviders[name] {
+		return nil
+	}
+	if c.secretsLoaded {
+		return c.secretsErr
+	}
+	c.secretsLoaded = true
+
+	path, err := c.secretsPath()
+	if err != nil {
+		c.secretsErr = err
+		return err
+	}
+	passphrase, err := readPassphrase("请输入密钥文件口令: ", false)
+	if err != nil {
+		c.secretsErr = err
+		return err
+	}
+	store, err := OpenSecretStore(path, passphrase)
+	if err != nil {
+		c.secretsErr = err
+		return err
+	}
+
+	for provider, secret := range store.Providers {
+		pc, ok := c.Providers[provider]
+		if !ok {
+			continue
+		}
+		if secret.APIKey != "" && (pc.APIKey == "" || isPlaceholderKey(pc.APIKey)) {
+			pc.APIKey = secret.APIKey
+		}
+		if secret.APISecret != "" && (pc.APISecret == "" || isPlaceholderKey(pc.APISecret)) {
+			pc.APISecret = secret.APISecret
+		}
+		// 解密得到的密钥和环境变量一样，不会被 saveConfig 写入配置文件
+		if loaded, ok := c.envProviders[provider]; ok && loaded == c.Providers[provider] {
+			c.envProviders[provider] = pc
+		}
+		c.Providers[provider] = pc
+	}
+	return nil
+}
+
+// Path 返回加载的配置文件路径，使用内置默认配置时为空
+func (c *Config) Path() string {
+	return c.path
+}
+
+// applyEnv 展开提供者配置中的 ${ENV}，并使用环境变量中的密钥
+// 原始值会被保留，保存配置时环境变量中的值不会写入文件
+func (c *Config) applyEnv() {
+	if name := os.Getenv("FILECLASSIFY_PROVIDER"); name != "" {
+		c.DefaultProvider = name
+	}
+
+	c.rawProviders = make(map[string]ProviderConfig, len(c.Providers))
+	c.envProviders = make(map[string]ProviderConfig, len(c.Providers))
+	for name, raw := range c.Providers {
+		pc := ProviderConfig{
+			APIKey:    expandEnvRefs(raw.APIKey),
+			APISecret: expandEnvRefs(raw.APISecret),
+			APIURL:    expandEnvRefs(raw.APIURL),
+			ModelName: expandEnvRefs(raw.ModelName),
+		}
+
+		prefix := "FILECLASSIFY_" + strings.ToUpper(name) + "_"
+		if key := os.Getenv(prefix + "API_KEY"); key != "" {
+			pc.APIKey = key
+		} else if alias := providerKeyEnvAliases[name]; alias != "" && !c.secretProviders[name] && (pc.APIKey == "" || isPlaceholderKey(pc.APIKey)) {
+			if key := os.Getenv(alias); key != "" {
+				pc.APIKey = key
+			}
+		}
+		if secret := os.Getenv(prefix + "API_SECRET"); secret != "" {
+			pc.APISecret = secret
+		}
+		if url := os.Getenv(prefix + "API_URL"); url != "" {
+			pc.APIURL = url
+		}
+		if model := os.Getenv(prefix + "MODEL"); model != "" {
+			pc.ModelName = model
+		}
+
+		c.rawProviders[name] = raw
+		c.envProviders[name] = pc
+		c.Providers[name] = pc
+	}
+}
+
+// envRefPattern 匹配 ${NAME} 形式的环境变量引用
+var envRefPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnvRefs 替换字符串中的 ${NAME}，只处理带花括号的写法，避免误改包含 $ 的密钥
+func expandEnvRefs(value string) string {
+	if !strings.Contains(value, "${") {
+		return value
+	}
+	return envRefPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		return os.Getenv(envRefPattern.FindStringSubmatch(ref)[1])
+	})
+}
+
+// saveConfig 将配置写回加载时的文件，没有配置文件时写到用户配置目录，文件权限为 0600
+// 未修改过的提供者字段按原始值写回，来自环境变量、密钥文件的值和 ${ENV} 引用不会被展开写入文件
 func saveConfig(config *Config) error {
-	data, err := json.MarshalIndent(config, "", "    ")
+	path := config.path
+	if path == "" {
+		var err error
+		if path, err = userConfigPath(); err != nil {
+			return err
+		}
+	}
+
+	out := *config
+	out.Providers = make(map[string]ProviderConfig, len(config.Providers))
+	for name, pc := range config.Providers {
+		loaded, raw := config.envProviders[name], config.rawProviders[name]
+		if pc.APIKey == loaded.APIKey {
+			pc.APIKey = raw.APIKey
+		}
+		if pc.APISecret == loaded.APISecret {
+			pc.APISecret = raw.APISecret
+		}
+		if pc.APIURL == loaded.APIURL {
+			pc.APIURL = raw.APIURL
+		}
+		if pc.ModelName == loaded.ModelName {
+			pc.ModelName = raw.ModelName
+		}
+		out.Providers[name] = pc
+	}
+
+	data, err := json.MarshalIndent(&out, "", "    ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile("config.json", data, 0644)
+	if err := writePrivateFile(path, data); err != nil {
+		return err
+	}
+	config.path = path
+	return nil
+}
+
+// writeConfigTemplate 写入一份新的配置文件，密钥引用环境变量
+func writeConfigTemplate(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("配置文件已存在: %s", path)
+	}
+	config := defaultConfig()
+	for name := range config.Providers {
+		pc := config.Providers[name]
+		pc.APIKey = "${FILECLASSIFY_" + strings.ToUpper(name) + "_API_KEY}"
+		config.Providers[name] = pc
+	}
+	config.path = path
+	return saveConfig(config)
 }
 
 // GetProviderConfig 获取指定提供者的配置
@@ -79,9 +364,186 @@ func (c *Config) GetProviderConfig(providerType string) (ProviderConfig, error)
 		providerType = c.DefaultProvider
 	}
 
-	config, exists := c.Providers[providerType]
-	if !exists {
+	if _, exists := c.Providers[providerType]; !exists {
 		return ProviderConfig{}, fmt.Errorf("不支持的模型类型: %s", providerType)
 	}
-	return config, nil
+	if err := c.unlockSecrets(providerType); err != nil {
+		return ProviderConfig{}, fmt.Errorf("读取加密密钥失败: %v", err)
+	}
+	provider := c.Providers[providerType]
+	registerLogSecret(provider.APIKey)
+	registerLogSecret(provider.APISecret)
+	return provider, nil
+}
+
+// GetPathTemplate 解析配置中的目标路径模板，未配置时使用默认模板
+func (c *Config) GetPathTemplate() (*PathTemplate, error) {
+	if c.PathTemplate == "" {
+		return ParsePathTemplate(defaultPathTemplate)
+	}
+	return ParsePathTemplate(c.PathTemplate)
+}
+
+// promptConfig 返回提示词配置，分类语言未单独设置时按 naming.language 生成
+func (c *Config) promptConfig() PromptConfig {
+	pc := c.Prompt
+	lang := c.Naming.language()
+	if pc.Language == "" {
+		pc.Language = lang.prompt
+	}
+	pc.other = lang.other
+	return pc
+}
+
+// OpenCache 按配置打开分类缓存，关闭缓存时返回nil
+func (c *Config) OpenCache() (*ClassificationCache, error) {
+	if c.Cache.Disabled {
+		return nil, nil
+	}
+	return OpenClassificationCache(expandHome(expandEnvRefs(c.Cache.Path)))
+}
+
+// ConfigIssue 配置校验发现的问题
+type ConfigIssue struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	Warning bool   `json:"warning,omitempty"` // 警告不影响运行
+}
+
+func (i ConfigIssue) String() string {
+	return i.Field + ": " + i.Message
+}
+
+// Validate 校验整个配置；默认提供者的问题是错误，其他提供者的问题只作为警告
+func (c *Config) Validate() []ConfigIssue {
+	var issues []ConfigIssue
+	add := func(field string, err error) {
+		if err != nil {
+			issues = append(issues, ConfigIssue{Field: field, Message: err.Error()})
+		}
+	}
+
+	if c.DefaultProvider == "" {
+		issues = append(issues, ConfigIssue{Field: "default_provider", Message: "未设置默认提供者"})
+	} else if _, ok := c.Providers[c.DefaultProvider]; !ok {
+		issues = append(issues, ConfigIssue{Field: "default_provider", Message: fmt.Sprintf("providers 中没有 %s 的配置", c.DefaultProvider)})
+	}
+
+	names := make([]string, 0, len(c.Providers))
+	for name := range c.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		providerIssues := c.ValidateProvider(name)
+		if name != c.DefaultProvider {
+			for i := range providerIssues {
+				providerIssues[i].Warning = true
+			}
+		}
+		issues = append(issues, providerIssues...)
+	}
+
+	if c.PathTemplate != "" {
+		_, err := ParsePathTemplate(c.PathTemplate)
+		add("path_template", err)
+	}
+	add("naming", c.Naming.Validate())
+	add("log", c.Log.Validate())
+	if _, err := c.promptConfig().LoadPrompt(); err != nil {
+		add("prompt", err)
+	}
+	add("duplicate_policy", validateDuplicatePolicy(c.DuplicatePolicy))
+	add("scan.symlink_policy", validateSymlinkPolicy(c.Scan.SymlinkPolicy))
+	if _, err := NewIgnoreMatcher(c.Scan.IgnorePatterns); err != nil {
+		add("scan.ignore_patterns", err)
+	}
+	add("conflict", c.Conflict.Validate())
+	if c.Watch.DebounceSeconds < 0 || c.Watch.StableSeconds < 0 {
+		add("watch", fmt.Errorf("时间不能为负数"))
+	}
+	return issues
+}
+
+// ValidateProvider 校验单个提供者的配置
+func (c *Config) ValidateProvider(name string) []ConfigIssue {
+	field := "providers." + name
+	pc, ok := c.Providers[name]
+	if !ok {
+		return []ConfigIssue{{Field: field, Message: "没有该提供者的配置"}}
+	}
+
+	supported := false
+	for _, known := range supportedProviders {
+		if name == known {
+			supported = true
+		}
+	}
+	if !supported {
+		return []ConfigIssue{{Field: field, Message: fmt.Sprintf("不支持的提供者，可选 %s", strings.Join(supportedProviders, "、"))}}
+	}
+
+	if pc.APIKey == "" || isPlaceholderKey(pc.APIKey) {
+		if err := c.unlockSecrets(name); err != nil {
+			return []ConfigIssue{{Field: field + ".api_key", Message: fmt.Sprintf("读取加密密钥失败: %v", err)}}
+		}
+		pc = c.Providers[name]
+	}
+
+	var issues []ConfigIssue
+	switch {
+	case pc.APIKey == "":
+		issues = append(issues, ConfigIssue{Field: field + ".api_key", Message: fmt.Sprintf("未设置密钥，可在配置中填写或设置环境变量 FILECLASSIFY_%s_API_KEY", strings.ToUpper(name))})
+	case isPlaceholderKey(pc.APIKey):
+		issues = append(issues, ConfigIssue{Field: field + ".api_key", Message: "密钥是示例值，请替换为真实密钥"})
+	}
+	if pc.APISecret != "" && isPlaceholderKey(pc.APISecret) {
+		issues = append(issues, ConfigIssue{Field: field + ".api_secret", Message: "密钥是示例值，请替换为真实密钥"})
+	}
+
+	if pc.APIURL == "" {
+		issues = append(issues, ConfigIssue{Field: field + ".api_url", Message: "未设置接口地址"})
+	} else if u, err := url.Parse(pc.APIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		issues = append(issues, ConfigIssue{Field: field + ".api_url", Message: fmt.Sprintf("接口地址格式不正确: %s", pc.APIURL)})
+	} else if u.Scheme == "http" && !isLoopbackHost(u.Hostname()) {
+		issues = append(issues, ConfigIssue{Field: field + ".api_url", Message: "接口地址未使用 https，密钥会以明文发送", Warning: true})
+	}
+
+	if strings.TrimSpace(pc.ModelName) == "" {
+		issues = append(issues, ConfigIssue{Field: field + ".model_name", Message: "未设置模型名称"})
+	}
+	return issues
+}
+
+// isPlaceholderKey 判断密钥是否是示例值，如 your_xxx_api_key_here、sk-、sk-xxxx、<api-key>
+func isPlaceholderKey(key string) bool {
+	k := strings.ToLower(strings.TrimSpace(key))
+	switch {
+	case strings.HasPrefix(k, "your_") || strings.HasPrefix(k, "your-") || strings.HasSuffix(k, "_here"):
+		return true
+	case strings.HasPrefix(k, "<") && strings.HasSuffix(k, ">"):
+		return true
+	case strings.HasPrefix(k, "sk-"):
+		return strings.Trim(strings.TrimPrefix(k, "sk-"), "x*.") == ""
+	}
+	return false
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// configErrors 返回校验结果中的错误，忽略警告
+func configErrors(issues []ConfigIssue) []ConfigIssue {
+	var errs []ConfigIssue
+	for _, issue := range issues {
+		if !issue.Warning {
+			errs = append(errs, issue)
+		}
+	}
+	return errs
 }
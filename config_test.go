@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadConfigKeepsDefaultIgnorePatterns(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv(configEnvVar, "")
+	want := append([]string(nil), defaultIgnorePatterns...)
+
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{"scan": {"ignore_patterns": ["*.tmp", "x"]}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := configFile
+	configFile = path
+	defer func() { configFile = old }()
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := config.Scan.IgnorePatterns; !reflect.DeepEqual(got, []string{"*.tmp", "x"}) {
+		t.Errorf("IgnorePatterns = %q", got)
+	}
+	if !reflect.DeepEqual(defaultIgnorePatterns, want) {
+		t.Errorf("加载配置后默认规则被修改为 %q", defaultIgnorePatterns)
+	}
+	if got := defaultConfig().Scan.IgnorePatterns; !reflect.DeepEqual(got, want) {
+		t.Errorf("defaultConfig().Scan.IgnorePatterns = %q，应为 %q", got, want)
+	}
+}
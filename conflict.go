@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 目标文件已存在时的处理策略
+const (
+	ConflictSkip           = "skip"            // 跳过，源文件保留在原处
+	ConflictOverwriteNewer = "overwrite_newer" // 源文件较新时覆盖，否则跳过
+	ConflictOverwrite      = "overwrite"       // 直接覆盖
+	ConflictRename         = "rename"          // 按重命名模式生成新文件名
+	ConflictDedupe         = "dedupe"          // 内容相同时删除源文件，不同时重命名
+	ConflictAsk            = "ask"             // 交互式询问
+)
+
+// defaultRenamePattern 默认的重命名模式，与原来的 name_N.ext 行为一致
+const defaultRenamePattern = "{name}_{n}{ext}"
+
+// ConflictConfig 定义目标冲突的处理配置
+type ConflictConfig struct {
+	Policy        string            `json:"policy,omitempty"`         // 全局策略，默认 rename
+	RenamePattern string            `json:"rename_pattern,omitempty"` // 重命名模式，可用 {name}、{n}、{ext}
+	Categories    map[string]string `json:"categories,omitempty"`     // 按分类覆盖全局策略
+}
+
+// ConflictDecision 记录一次目标冲突的处理结果
+type ConflictDecision struct {
+	Src      string `json:"src"`
+	Dst      string `json:"dst"`
+	Policy   string `json:"policy"`
+	Action   string `json:"action"`
+	FinalDst string `json:"final_dst,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// ConflictResolver 按配置处理目标冲突
+type ConflictResolver struct {
+	config ConflictConfig
+
+	// Ask 交互式询问时调用，返回选择的策略；为nil时 ask 策略退化为 rename
+	Ask func(src, dst string) (policy string, applyToAll bool)
+
+	// 交互时选择"全部使用"后记住的策略
+	remembered string
+}
+
+// validateConflictPolicy 检查冲突策略是否有效
+func validateConflictPolicy(policy string) error {
+	switch policy {
+	case "", ConflictSkip, ConflictOverwriteNewer, ConflictOverwrite, ConflictRename, ConflictDedupe, ConflictAsk:
+		return nil
+	}
+	return fmt.Errorf("不支持的冲突处理策略: %s（可选 skip、overwrite_newer、overwrite、rename、dedupe、ask）", policy)
+}
+
+// Validate 检查冲突配置
+func (c ConflictConfig) Validate() error {
+	if err := validateConflictPolicy(c.Policy); err != nil {
+		return err
+	}
+	for category, policy := range c.Categories {
+		if err := validateConflictPolicy(policy); err != nil {
+			return fmt.Errorf("分类 %s: %v", category, err)
+		}
+	}
+	if c.RenamePattern != "" && !strings.Contains(c.RenamePattern, "{n}") {
+		return fmt.Errorf("重命名模式必须包含 {n}: %s", c.RenamePattern)
+	}
+	if strings.ContainsAny(c.RenamePattern, "/\\") {
+		return fmt.Errorf("重命名模式不能包含路径分隔符: %s", c.RenamePattern)
+	}
+	return nil
+}
+
+// NewConflictResolver 创建冲突处理器
+func NewConflictResolver(config ConflictConfig) *ConflictResolver {
+	return &ConflictResolver{config: config}
+}
+
+// policyFor 返回指定分类使用的策略
+func (r *ConflictResolver) policyFor(category string) string {
+	if policy, ok := r.config.Categories[category]; ok && policy != "" {
+		return policy
+	}
+	if r.config.Policy != "" {
+		return r.config.Policy
+	}
+	return ConflictRename
+}
+
+// Resolve 在目标文件已存在时决定如何处理
+func (r *ConflictResolver) Resolve(op MoveOp, dst string) (ConflictDecision, error) {
+	policy := r.policyFor(op.File.Category)
+	decision := ConflictDecision{Src: op.Src, Dst: dst, Policy: policy}
+
+	if policy == ConflictAsk {
+		switch {
+		case r.remembered != "":
+			policy = r.remembered
+		case r.Ask != nil:
+			chosen, applyToAll := r.Ask(op.Src, dst)
+			if applyToAll {
+				r.remembered = chosen
+			}
+			policy = chosen
+		default:
+			policy = ConflictRename
+		}
+	}
+
+	// 目录整体移动，不覆盖也不合并已有目标，只能跳过或改名
+	if op.File.IsDir && policy != ConflictSkip {
+		policy = ConflictRename
+	}
+
+	switch policy {
+	case ConflictSkip:
+		decision.Action = ConflictSkip
+		decision.Reason = "目标文件已存在"
+
+	case ConflictOverwrite:
+		decision.Action = ConflictOverwrite
+		decision.FinalDst = dst
+
+	case ConflictOverwriteNewer:
+		srcInfo, err := os.Stat(op.Src)
+		if err != nil {
+			return decision, err
+		}
+		dstInfo, err := os.Stat(dst)
+		if err != nil {
+			return decision, err
+		}
+		if srcInfo.ModTime().After(dstInfo.ModTime()) {
+			decision.Action = ConflictOverwrite
+			decision.FinalDst = dst
+			decision.Reason = "源文件较新"
+		} else {
+			decision.Action = ConflictSkip
+			decision.Reason = "目标文件不比源文件旧"
+		}
+
+	case ConflictDedupe:
+		same, err := sameContent(op.Src, dst)
+		if err != nil {
+			return decision, err
+		}
+		if same {
+			decision.Action = ConflictDedupe
+			decision.FinalDst = dst
+			decision.Reason = "内容相同"
+			break
+		}
+		renamed, err := r.renamedPath(dst)
+		if err != nil {
+			return decision, err
+		}
+		decision.Action = ConflictRename
+		decision.FinalDst = renamed
+		decision.Reason = "内容不同"
+
+	default:
+		renamed, err := r.renamedPath(dst)
+		if err != nil {
+			return decision, err
+		}
+		decision.Action = ConflictRename
+		decision.FinalDst = renamed
+	}
+
+	return decision, nil
+}
+
+// renamedPath 按重命名模式查找第一个不存在的文件名
+func (r *ConflictResolver) renamedPath(dst string) (string, error) {
+	pattern := r.config.RenamePattern
+	if pattern == "" {
+		pattern = defaultRenamePattern
+	}
+
+	dir := filepath.Dir(dst)
+	ext := filepath.Ext(dst)
+	name := strings.TrimSuffix(filepath.Base(dst), ext)
+	for n := 1; n < 100000; n++ {
+		candidate := strings.NewReplacer("{name}", name, "{ext}", ext, "{n}", strconv.Itoa(n)).Replace(pattern)
+		candidatePath := filepath.Join(dir, candidate)
+		if _, err := os.Lstat(candidatePath); os.IsNotExist(err) {
+			return candidatePath, nil
+		}
+	}
+	return "", fmt.Errorf("无法为 %s 找到可用的文件名", dst)
+}
+
+// sameContent 比较两个文件的内容是否相同
+func sameContent(a, b string) (bool, error) {
+	infoA, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	infoB, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+	hashA, err := hashFile(a)
+	if err != nil {
+		return false, err
+	}
+	hashB, err := hashFile(b)
+	if err != nil {
+		return false, err
+	}
+	return hashA == hashB, nil
+}
+
+// parseConflictAnswer 解析交互式输入，大写字母表示之后的冲突都使用该选择
+func parseConflictAnswer(answer string) (string, bool, bool) {
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return ConflictRename, false, true
+	}
+	applyToAll := strings.ToUpper(answer) == answer && strings.ToLower(answer) != answer
+	switch strings.ToLower(answer) {
+	case "s":
+		return ConflictSkip, applyToAll, true
+	case "o":
+		return ConflictOverwrite, applyToAll, true
+	case "n":
+		return ConflictOverwriteNewer, applyToAll, true
+	case "r":
+		return ConflictRename, applyToAll, true
+	case "d":
+		return ConflictDedupe, applyToAll, true
+	}
+	return "", false, false
+}
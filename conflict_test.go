@@ -0,0 +1,176 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConflictResolverResolve(t *testing.T) {
+	older := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		config     ConflictConfig
+		category   string
+		isDir      bool
+		src, dst   string    // 源文件和目标文件的内容
+		srcTime    time.Time // 为零值时不修改
+		dstTime    time.Time
+		existing   []string // 目标目录中已有的其他文件
+		wantAction string
+		wantFinal  string // 最终目标的文件名，为空表示没有
+	}{
+		{name: "默认重命名", src: "a", dst: "b", wantAction: ConflictRename, wantFinal: "a_1.txt"},
+		{name: "重命名跳过已占用的编号", src: "a", dst: "b", existing: []string{"a_1.txt", "a_2.txt"}, wantAction: ConflictRename, wantFinal: "a_3.txt"},
+		{name: "自定义重命名模式", config: ConflictConfig{RenamePattern: "{name} ({n}){ext}"}, src: "a", dst: "b", wantAction: ConflictRename, wantFinal: "a (1).txt"},
+		{name: "跳过", config: ConflictConfig{Policy: ConflictSkip}, src: "a", dst: "b", wantAction: ConflictSkip},
+		{name: "覆盖", config: ConflictConfig{Policy: ConflictOverwrite}, src: "a", dst: "b", wantAction: ConflictOverwrite, wantFinal: "a.txt"},
+		{name: "源文件较新时覆盖", config: ConflictConfig{Policy: ConflictOverwriteNewer}, src: "a", dst: "b", srcTime: newer, dstTime: older, wantAction: ConflictOverwrite, wantFinal: "a.txt"},
+		{name: "源文件较旧时跳过", config: ConflictConfig{Policy: ConflictOverwriteNewer}, src: "a", dst: "b", srcTime: older, dstTime: newer, wantAction: ConflictSkip},
+		{name: "时间相同时跳过", config: ConflictConfig{Policy: ConflictOverwriteNewer}, src: "a", dst: "b", srcTime: older, dstTime: older, wantAction: ConflictSkip},
+		{name: "内容相同时去重", config: ConflictConfig{Policy: ConflictDedupe}, src: "same", dst: "same", wantAction: ConflictDedupe, wantFinal: "a.txt"},
+		{name: "内容不同时重命名", config: ConflictConfig{Policy: ConflictDedupe}, src: "aaaa", dst: "bbbb", wantAction: ConflictRename, wantFinal: "a_1.txt"},
+		{name: "分类策略优先", config: ConflictConfig{Policy: ConflictOverwrite, Categories: map[string]string{"图片": ConflictSkip}}, category: "图片", src: "a", dst: "b", wantAction: ConflictSkip},
+		{name: "其他分类使用全局策略", config: ConflictConfig{Policy: ConflictOverwrite, Categories: map[string]string{"图片": ConflictSkip}}, category: "文档", src: "a", dst: "b", wantAction: ConflictOverwrite, wantFinal: "a.txt"},
+		{name: "没有询问函数时 ask 按重命名处理", config: ConflictConfig{Policy: ConflictAsk}, src: "a", dst: "b", wantAction: ConflictRename, wantFinal: "a_1.txt"},
+		{name: "目录不覆盖", config: ConflictConfig{Policy: ConflictOverwrite}, isDir: true, wantAction: ConflictRename, wantFinal: "a_1.txt"},
+		{name: "目录可以跳过", config: ConflictConfig{Policy: ConflictSkip}, isDir: true, wantAction: ConflictSkip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			src, dst := filepath.Join(root, "a.txt"), filepath.Join(root, "分类", "a.txt")
+			if tt.isDir {
+				mkdirAll(t, src)
+				mkdirAll(t, dst)
+			} else {
+				writeFile(t, src, tt.src)
+				writeFile(t, dst, tt.dst)
+			}
+			for _, name := range tt.existing {
+				writeFile(t, filepath.Join(root, "分类", name), "x")
+			}
+			if !tt.srcTime.IsZero() {
+				os.Chtimes(src, tt.srcTime, tt.srcTime)
+				os.Chtimes(dst, tt.dstTime, tt.dstTime)
+			}
+
+			resolver := NewConflictResolver(tt.config)
+			op := MoveOp{File: FileInfo{Path: "a.txt", Category: tt.category, IsDir: tt.isDir}, Src: src, Dst: dst}
+			decision, err := resolver.Resolve(op, dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decision.Action != tt.wantAction {
+				t.Errorf("Action = %s，应为 %s", decision.Action, tt.wantAction)
+			}
+			final := ""
+			if decision.FinalDst != "" {
+				if filepath.Dir(decision.FinalDst) != filepath.Dir(dst) {
+					t.Errorf("FinalDst = %s，应在目标目录中", decision.FinalDst)
+				}
+				final = filepath.Base(decision.FinalDst)
+			}
+			if final != tt.wantFinal {
+				t.Errorf("FinalDst = %q，应为 %q", final, tt.wantFinal)
+			}
+		})
+	}
+}
+
+func TestConflictResolverAsk(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a.txt"), "a")
+	writeFile(t, filepath.Join(root, "d", "a.txt"), "b")
+	op := MoveOp{File: FileInfo{Path: "a.txt"}, Src: filepath.Join(root, "a.txt"), Dst: filepath.Join(root, "d", "a.txt")}
+
+	tests := []struct {
+		name       string
+		answers    []string // 每次询问的回答
+		applyToAll bool
+		want       []string // 每次处理的结果
+		asked      int      // 询问的次数
+	}{
+		{"每次询问", []string{ConflictSkip, ConflictOverwrite}, false, []string{ConflictSkip, ConflictOverwrite}, 2},
+		{"之后都这样处理", []string{ConflictSkip}, true, []string{ConflictSkip, ConflictSkip, ConflictSkip}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := NewConflictResolver(ConflictConfig{Policy: ConflictAsk})
+			asked := 0
+			resolver.Ask = func(src, dst string) (string, bool) {
+				answer := tt.answers[asked]
+				asked++
+				return answer, tt.applyToAll
+			}
+			for i, want := range tt.want {
+				decision, err := resolver.Resolve(op, op.Dst)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if decision.Action != want {
+					t.Errorf("第 %d 次 Action = %s，应为 %s", i+1, decision.Action, want)
+				}
+				if decision.Policy != ConflictAsk {
+					t.Errorf("Policy = %s，应记录配置的 ask", decision.Policy)
+				}
+			}
+			if asked != tt.asked {
+				t.Errorf("询问 %d 次，应为 %d 次", asked, tt.asked)
+			}
+		})
+	}
+}
+
+func TestConflictConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  ConflictConfig
+		wantErr string
+	}{
+		{"默认配置", ConflictConfig{}, ""},
+		{"全部有效", ConflictConfig{Policy: ConflictDedupe, RenamePattern: "{name}-{n}{ext}", Categories: map[string]string{"图片": ConflictSkip}}, ""},
+		{"未知策略", ConflictConfig{Policy: "merge"}, "不支持的冲突处理策略"},
+		{"分类中的未知策略", ConflictConfig{Categories: map[string]string{"图片": "merge"}}, "分类 图片"},
+		{"重命名模式没有编号", ConflictConfig{RenamePattern: "{name}_copy{ext}"}, "{n}"},
+		{"重命名模式包含分隔符", ConflictConfig{RenamePattern: "副本/{name}_{n}{ext}"}, "路径分隔符"},
+	}
+	for _, tt := range tests {
+		err := tt.config.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: 错误 = %v，应包含 %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseConflictAnswer(t *testing.T) {
+	tests := []struct {
+		answer     string
+		policy     string
+		applyToAll bool
+		ok         bool
+	}{
+		{"", ConflictRename, false, true},
+		{"s", ConflictSkip, false, true},
+		{"S", ConflictSkip, true, true},
+		{" o ", ConflictOverwrite, false, true},
+		{"N", ConflictOverwriteNewer, true, true},
+		{"r", ConflictRename, false, true},
+		{"D", ConflictDedupe, true, true},
+		{"x", "", false, false},
+		{"skip", "", false, false},
+	}
+	for _, tt := range tests {
+		policy, applyToAll, ok := parseConflictAnswer(tt.answer)
+		if policy != tt.policy || applyToAll != tt.applyToAll || ok != tt.ok {
+			t.Errorf("parseConflictAnswer(%q) = %q, %v, %v，应为 %q, %v, %v", tt.answer, policy, applyToAll, ok, tt.policy, tt.applyToAll, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// 重复文件处理策略
+const (
+	DuplicatePolicyOff      = "off"      // 不检测重复文件
+	DuplicatePolicyReport   = "report"   // 每组只整理一个，其余副本留在原处并报告
+	DuplicatePolicyMove     = "move"     // 其余副本移动到"重复文件"目录
+	DuplicatePolicyHardlink = "hardlink" // 其余副本替换为指向保留文件的硬链接
+)
+
+// 计算部分哈希时读取的字节数
+const partialHashSize = 64 * 1024
+
+// DuplicateGroup 一组内容完全相同的文件，Files[0] 为保留的文件
+type DuplicateGroup struct {
+	Hash  string
+	Size  int64
+	Files []FileInfo
+}
+
+// validateDuplicatePolicy 检查重复文件策略是否有效
+func validateDuplicatePolicy(policy string) error {
+	switch policy {
+	case "", DuplicatePolicyOff, DuplicatePolicyReport, DuplicatePolicyMove, DuplicatePolicyHardlink:
+		return nil
+	}
+	return fmt.Errorf("不支持的重复文件策略: %s（可选 off、report、move、hardlink）", policy)
+}
+
+// findDuplicates 查找内容完全相同的文件
+// 先按大小分组，再比较开头部分的哈希，最后才计算完整哈希，避免读取所有文件
+func findDuplicates(root string, files []FileInfo) ([]DuplicateGroup, error) {
+	bySize := make(map[int64][]int)
+	for i, file := range files {
+		// 符号链接和空文件不参与去重
+		if file.Size > 0 && !file.IsSymlink && !file.IsDir {
+			bySize[file.Size] = append(bySize[file.Size], i)
+		}
+	}
+
+	var groups []DuplicateGroup
+	for size, indexes := range bySize {
+		if len(indexes) < 2 {
+			continue
+		}
+
+		byPartial := make(map[string][]int)
+		for _, i := range indexes {
+			hash, err := partialHash(filepath.Join(root, files[i].Path))
+			if err != nil {
+				slog.Warn("计算文件哈希失败", "path", files[i].Path, "error", err)
+				continue
+			}
+			byPartial[hash] = append(byPartial[hash], i)
+		}
+
+		for partial, candidates := range byPartial {
+			if len(candidates) < 2 {
+				continue
+			}
+
+			byFull := make(map[string][]int)
+			for _, i := range candidates {
+				// 小于部分哈希大小的文件，部分哈希就是完整哈希
+				hash := partial
+				if size > partialHashSize {
+					hash = files[i].Hash
+					if hash == "" {
+						full, err := hashFile(filepath.Join(root, files[i].Path))
+						if err != nil {
+							slog.Warn("计算文件哈希失败", "path", files[i].Path, "error", err)
+							continue
+						}
+						hash = full
+					}
+				}
+				byFull[hash] = append(byFull[hash], i)
+			}
+
+			for hash, same := range byFull {
+				if len(same) < 2 {
+					continue
+				}
+				group := DuplicateGroup{Hash: hash, Size: size}
+				for _, i := range same {
+					group.Files = append(group.Files, files[i])
+				}
+				sortDuplicateGroup(group.Files)
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Files[0].Path < groups[j].Files[0].Path
+	})
+	return groups, nil
+}
+
+// sortDuplicateGroup 把最早修改的文件排在最前面作为保留的文件
+func sortDuplicateGroup(files []FileInfo) {
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].ModTime.Equal(files[j].ModTime) {
+			return files[i].ModTime.Before(files[j].ModTime)
+		}
+		if len(files[i].Path) != len(files[j].Path) {
+			return len(files[i].Path) < len(files[j].Path)
+		}
+		return files[i].Path < files[j].Path
+	})
+}
+
+// partialHash 计算文件开头部分的SHA-256
+func partialHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, io.LimitReader(f, partialHashSize)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// withoutDuplicateCopies 去掉每组中除保留文件外的副本，副本不发送给模型
+func withoutDuplicateCopies(files []FileInfo, groups []DuplicateGroup) []FileInfo {
+	copies := make(map[string]bool)
+	for _, group := range groups {
+		for _, file := range group.Files[1:] {
+			copies[file.Path] = true
+		}
+	}
+
+	var result []FileInfo
+	for _, file := range files {
+		if !copies[file.Path] {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// printDuplicateGroups 打印重复文件报告
+func printDuplicateGroups(w io.Writer, groups []DuplicateGroup) {
+	if len(groups) == 0 {
+		return
+	}
+	copies := 0
+	for _, group := range groups {
+		copies += len(group.Files) - 1
+	}
+	fmt.Fprintf(w, "\n发现 %d 组重复文件，共 %d 个多余副本：\n", len(groups), copies)
+	for _, group := range groups {
+		fmt.Fprintf(w, "- 保留 %s（%d 字节）\n", group.Files[0].Path, group.Size)
+		for _, file := range group.Files[1:] {
+			fmt.Fprintf(w, "    重复: %s\n", file.Path)
+		}
+	}
+}
+
+// duplicateMoveOps 根据策略为重复副本生成移动计划
+// move 策略将副本放入 duplicatesCategory 分类；hardlink 策略让副本跟随保留文件的分类，并在移动时改为硬链接
+func duplicateMoveOps(root string, groups []DuplicateGroup, classifiedFiles map[string][]FileInfo, policy string, tmpl *PathTemplate, duplicatesCategory string) ([]MoveOp, error) {
+	if policy != DuplicatePolicyMove && policy != DuplicatePolicyHardlink {
+		return nil, nil
+	}
+
+	keptCategory := make(map[string]string)
+	for category, files := range classifiedFiles {
+		for _, file := range files {
+			keptCategory[file.Path] = category
+		}
+	}
+
+	var ops []MoveOp
+	for _, group := range groups {
+		kept := group.Files[0]
+		for _, file := range group.Files[1:] {
+			linkTo := ""
+			file.Category = duplicatesCategory
+			if policy == DuplicatePolicyHardlink {
+				category, ok := keptCategory[kept.Path]
+				if !ok {
+					continue
+				}
+				file.Category = category
+				linkTo = kept.Path
+			}
+
+			planned, err := buildMovePlan(root, map[string][]FileInfo{file.Category: {file}}, tmpl)
+			if err != nil {
+				return nil, err
+			}
+			for _, op := range planned {
+				op.LinkTo = linkTo
+				ops = append(ops, op)
+			}
+		}
+	}
+	return ops, nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dupFile 测试用的文件，age 越大修改时间越早
+type dupFile struct {
+	path    string
+	content string
+	age     int
+	symlink bool
+}
+
+// writeDupFiles 写入文件并返回扫描结果形式的文件列表
+func writeDupFiles(t *testing.T, root string, files []dupFile) []FileInfo {
+	t.Helper()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var infos []FileInfo
+	for _, f := range files {
+		path := filepath.Join(root, filepath.FromSlash(f.path))
+		writeFile(t, path, f.content)
+		modTime := base.Add(-time.Duration(f.age) * time.Hour)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+		infos = append(infos, FileInfo{Path: f.path, Size: int64(len(f.content)), ModTime: modTime, IsSymlink: f.symlink})
+	}
+	return infos
+}
+
+func TestFindDuplicates(t *testing.T) {
+	large := strings.Repeat("x", partialHashSize+100)
+	tests := []struct {
+		name  string
+		files []dupFile
+		want  [][]string // 每组的文件，保留的文件在最前面
+	}{
+		{
+			name:  "内容相同时保留最早修改的文件",
+			files: []dupFile{{"a.txt", "same", 1, false}, {"b/a.txt", "same", 3, false}, {"c.txt", "diff", 2, false}},
+			want:  [][]string{{"b/a.txt", "a.txt"}},
+		},
+		{
+			name:  "修改时间相同时保留路径较短的文件",
+			files: []dupFile{{"x/long.txt", "same", 1, false}, {"a.txt", "same", 1, false}, {"b.txt", "same", 1, false}},
+			want:  [][]string{{"a.txt", "b.txt", "x/long.txt"}},
+		},
+		{
+			name:  "大小相同但内容不同",
+			files: []dupFile{{"a.txt", "ab", 1, false}, {"b.txt", "cd", 1, false}},
+		},
+		{
+			name:  "空文件不参与去重",
+			files: []dupFile{{"a.txt", "", 1, false}, {"b.txt", "", 1, false}},
+		},
+		{
+			name:  "符号链接不参与去重",
+			files: []dupFile{{"a.txt", "same", 1, false}, {"link.txt", "same", 1, true}},
+		},
+		{
+			name:  "开头相同、超过部分哈希长度后不同的大文件",
+			files: []dupFile{{"a.bin", large + "1", 1, false}, {"b.bin", large + "2", 1, false}},
+		},
+		{
+			name:  "完全相同的大文件",
+			files: []dupFile{{"a.bin", large, 2, false}, {"b.bin", large, 1, false}},
+			want:  [][]string{{"a.bin", "b.bin"}},
+		},
+		{
+			name: "多组按保留的文件排序",
+			files: []dupFile{
+				{"z1.txt", "zz", 2, false}, {"z2.txt", "zz", 1, false},
+				{"a1.txt", "aaa", 2, false}, {"a2.txt", "aaa", 1, false},
+			},
+			want: [][]string{{"a1.txt", "a2.txt"}, {"z1.txt", "z2.txt"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			groups, err := findDuplicates(root, writeDupFiles(t, root, tt.files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got [][]string
+			for _, group := range groups {
+				var paths []string
+				for _, file := range group.Files {
+					paths = append(paths, file.Path)
+				}
+				got = append(got, paths)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findDuplicates() = %q，应为 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDuplicateMoveOps(t *testing.T) {
+	root := t.TempDir()
+	files := writeDupFiles(t, root, []dupFile{
+		{"a.txt", "same", 2, false}, {"copy/a.txt", "same", 1, false},
+		{"b.txt", "other", 2, false}, {"copy/b.txt", "other", 1, false},
+	})
+	groups, err := findDuplicates(root, files)
+	if err != nil || len(groups) != 2 {
+		t.Fatalf("findDuplicates() = %v, %v", groups, err)
+	}
+	if kept := withoutDuplicateCopies(files, groups); len(kept) != 2 || kept[0].Path != "a.txt" || kept[1].Path != "b.txt" {
+		t.Fatalf("withoutDuplicateCopies() = %v", kept)
+	}
+	tmpl, err := ParsePathTemplate(defaultPathTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 只有 a.txt 已分类，b.txt 的副本在 hardlink 策略下没有可以跟随的分类
+	classified := map[string][]FileInfo{"文档": {files[0]}}
+
+	tests := []struct {
+		policy string
+		want   []string // 目标路径 <- 硬链接指向的文件
+	}{
+		{DuplicatePolicyReport, nil},
+		{DuplicatePolicyMove, []string{"重复文件/a.txt <- ", "重复文件/b.txt <- "}},
+		{DuplicatePolicyHardlink, []string{"文档/a.txt <- a.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			ops, err := duplicateMoveOps(root, groups, classified, tt.policy, tmpl, "重复文件")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, op := range ops {
+				rel, _ := filepath.Rel(root, op.Dst)
+				got = append(got, filepath.ToSlash(rel)+" <- "+op.LinkTo)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("duplicateMoveOps() = %q，应为 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyMovesHardlinkDuplicates(t *testing.T) {
+	root := t.TempDir()
+	files := writeDupFiles(t, root, []dupFile{{"a.txt", "same", 2, false}, {"copy/a.txt", "same", 1, false}})
+	kept, dup := files[0], files[1]
+	kept.Category, dup.Category = "文档", "文档"
+	ops := []MoveOp{
+		{File: kept, Src: filepath.Join(root, "a.txt"), Dst: filepath.Join(root, "文档", "a.txt")},
+		// 保留的文件已经占用了 文档/a.txt，副本按冲突策略改名后链接到它
+		{File: dup, Src: filepath.Join(root, "copy", "a.txt"), Dst: filepath.Join(root, "文档", "a.txt"), LinkTo: "a.txt"},
+	}
+	state, err := LoadOrganizeState(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := applyMoves(context.Background(), root, ops, organizeOptions{State: state})
+	if len(report.Failures) > 0 {
+		t.Fatalf("移动失败: %v", report.Failures)
+	}
+	keptInfo, err := os.Stat(filepath.Join(root, "文档", "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	renamed := strings.NewReplacer("{name}", "a", "{ext}", ".txt", "{n}", "1").Replace(defaultRenamePattern)
+	linkInfo, err := os.Stat(filepath.Join(root, "文档", renamed))
+	if err != nil {
+		t.Fatalf("副本应改名后链接到保留的文件: %v", err)
+	}
+	if !os.SameFile(keptInfo, linkInfo) {
+		t.Error("副本应为指向保留文件的硬链接")
+	}
+	if _, err := os.Lstat(filepath.Join(root, "copy", "a.txt")); !os.IsNotExist(err) {
+		t.Error("副本的源文件应被删除")
+	}
+}
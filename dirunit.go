@@ -0,0 +1,130 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// projectMarkers 目录中包含这些文件或目录时，说明它是版本库或代码项目，必须整体移动
+var projectMarkers = []string{
+	".git", ".hg", ".svn",
+	"go.mod", "package.json", "Cargo.toml", "pyproject.toml", "setup.py", "requirements.txt",
+	"pom.xml", "build.gradle", "build.gradle.kts", "CMakeLists.txt", "Makefile",
+	"composer.json", "Gemfile", "mix.exs", "pubspec.yaml",
+}
+
+// projectMarkerSuffixes 以这些后缀结尾的文件同样说明目录是代码项目
+var projectMarkerSuffixes = []string{".sln", ".csproj", ".xcodeproj", ".xcworkspace"}
+
+// bundleDirSuffixes 以这些后缀结尾的目录是 macOS 应用或程序包
+var bundleDirSuffixes = []string{".app", ".bundle", ".framework", ".plugin", ".photoslibrary", ".xcodeproj"}
+
+// installedAppMarkers 目录中包含这些文件时，说明它是已安装的应用
+var installedAppMarkers = []string{"AppRun", "unins000.exe", "uninstall.exe", "Uninstall.exe"}
+
+// albumMediaExts 相册中的照片和视频格式
+var albumMediaExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".heic": true, ".heif": true, ".gif": true,
+	".webp": true, ".tif": true, ".tiff": true, ".bmp": true, ".raw": true, ".dng": true,
+	".cr2": true, ".cr3": true, ".nef": true, ".arw": true, ".orf": true, ".rw2": true,
+	".mp4": true, ".mov": true, ".m4v": true, ".avi": true, ".3gp": true, ".mts": true,
+}
+
+// albumSidecarExts 相册中常见的附属文件，不影响相册判断
+var albumSidecarExts = map[string]bool{".xmp": true, ".aae": true, ".thm": true, ".ini": true, ".db": true}
+
+// minAlbumMedia 至少包含这么多照片或视频才认为是相册
+const minAlbumMedia = 3
+
+// atomicDirKind 判断目录是否必须整体移动，返回目录类型，空字符串表示可以拆分
+func atomicDirKind(path string) string {
+	name := filepath.Base(path)
+	if hasAnySuffix(strings.ToLower(name), bundleDirSuffixes) {
+		return "应用程序"
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return ""
+	}
+
+	var exeCount, dllCount, mediaCount, otherCount int
+	hasSubdir := false
+	for _, entry := range entries {
+		entryName := entry.Name()
+		for _, marker := range projectMarkers {
+			if entryName == marker {
+				return "代码项目"
+			}
+		}
+		lower := strings.ToLower(entryName)
+		if hasAnySuffix(lower, projectMarkerSuffixes) {
+			return "代码项目"
+		}
+		for _, marker := range installedAppMarkers {
+			if entryName == marker {
+				return "应用程序"
+			}
+		}
+
+		if entry.IsDir() {
+			if !strings.HasPrefix(entryName, ".") {
+				hasSubdir = true
+			}
+			continue
+		}
+		ext := filepath.Ext(lower)
+		switch {
+		case ext == ".exe":
+			exeCount++
+		case ext == ".dll":
+			dllCount++
+		case albumMediaExts[ext]:
+			mediaCount++
+		case albumSidecarExts[ext] || strings.HasPrefix(entryName, "."):
+		default:
+			otherCount++
+		}
+	}
+
+	// 可执行文件和它依赖的动态库放在一起，拆开后无法运行
+	if exeCount > 0 && dllCount > 0 {
+		return "应用程序"
+	}
+	// 只包含照片和视频的目录视为相册
+	if !hasSubdir && otherCount == 0 && exeCount == 0 && dllCount == 0 && mediaCount >= minAlbumMedia {
+		return "相册"
+	}
+	if strings.EqualFold(name, "DCIM") {
+		return "相册"
+	}
+	return ""
+}
+
+func hasAnySuffix(name string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// dirSize 统计目录中普通文件的总大小，不跟随符号链接
+func dirSize(path string) int64 {
+	var size int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
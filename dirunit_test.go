@@ -0,0 +1,66 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAtomicDirKind(t *testing.T) {
+	tests := []struct {
+		name  string
+		dir   string
+		files []string // 目录中的文件，以 / 结尾的是子目录
+		want  string
+	}{
+		{"git 版本库", "repo", []string{".git/", "README.md"}, "代码项目"},
+		{"Go 项目", "tool", []string{"go.mod", "main.go", "internal/"}, "代码项目"},
+		{"Makefile", "c", []string{"Makefile", "main.c"}, "代码项目"},
+		{"Visual Studio 解决方案", "app", []string{"App.sln", "src/"}, "代码项目"},
+		{"macOS 应用", "Safari.app", []string{"Contents/"}, "应用程序"},
+		{"AppImage 解压的应用", "tool", []string{"AppRun", "usr/"}, "应用程序"},
+		{"带卸载程序的应用", "game", []string{"game.exe", "unins000.exe"}, "应用程序"},
+		{"可执行文件和 DLL", "portable", []string{"run.exe", "core.dll", "readme.txt"}, "应用程序"},
+		{"只有 exe 不算应用", "setup", []string{"setup.exe", "readme.txt"}, ""},
+		{"只有照片的目录是相册", "假期", []string{"1.jpg", "2.JPG", "3.heic"}, "相册"},
+		{"相册可以包含附属文件和隐藏文件", "假期", []string{"1.jpg", "2.jpg", "3.mov", "1.xmp", ".DS_Store"}, "相册"},
+		{"少于 3 张照片", "假期", []string{"1.jpg", "2.jpg"}, ""},
+		{"照片和文档混在一起", "下载", []string{"1.jpg", "2.jpg", "3.jpg", "a.pdf"}, ""},
+		{"有子目录的照片目录", "照片", []string{"1.jpg", "2.jpg", "3.jpg", "2024/"}, ""},
+		{"隐藏的子目录不影响相册", "假期", []string{"1.jpg", "2.jpg", "3.jpg", ".thumbnails/"}, "相册"},
+		{"DCIM", "DCIM", []string{"100APPLE/"}, "相册"},
+		{"普通目录", "文档", []string{"a.txt", "b.docx"}, ""},
+		{"空目录", "空", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), tt.dir)
+			mkdirAll(t, dir)
+			for _, file := range tt.files {
+				if strings.HasSuffix(file, "/") {
+					mkdirAll(t, filepath.Join(dir, file))
+				} else {
+					writeFile(t, filepath.Join(dir, file), "x")
+				}
+			}
+			if got := atomicDirKind(dir); got != tt.want {
+				t.Errorf("atomicDirKind() = %q，应为 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.txt"), "12345")
+	writeFile(t, filepath.Join(dir, "sub", "b.txt"), "123")
+	// 符号链接不跟随，不计入大小
+	big := filepath.Join(t.TempDir(), "big.bin")
+	writeFile(t, big, strings.Repeat("x", 1000))
+	symlink(t, big, filepath.Join(dir, "link"))
+
+	if got := dirSize(dir); got != 8 {
+		t.Errorf("dirSize() = %d，应为 8", got)
+	}
+}
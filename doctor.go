@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 检查结果状态
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// doctorCheck 一项检查的结果
+type doctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// runDoctorCommand 检查配置、模型连接和目录写入权限，结果汇总在一张表中
+func runDoctorCommand(o *cliOptions, args []string) error {
+	if _, err := o.parseCommandFlags("doctor", args, true); err != nil {
+		return err
+	}
+
+	var checks []doctorCheck
+	config, err := LoadConfig()
+	if err != nil {
+		checks = append(checks, doctorCheck{Name: "配置文件", Status: checkFail, Detail: err.Error()})
+		return o.finishDoctor(checks)
+	}
+	if config.Path() == "" {
+		checks = append(checks, doctorCheck{Name: "配置文件", Status: checkWarn, Detail: "没有找到配置文件，使用内置默认配置"})
+	} else {
+		checks = append(checks, doctorCheck{Name: "配置文件", Status: checkOK, Detail: config.Path()})
+	}
+
+	// 日志配置有误时在配置校验中报告
+	o.setupLogging(config)
+
+	// 配置校验
+	issues := config.Validate()
+	if len(issues) == 0 {
+		checks = append(checks, doctorCheck{Name: "配置校验", Status: checkOK, Detail: "没有发现问题"})
+	}
+	for _, issue := range issues {
+		status := checkFail
+		if issue.Warning {
+			status = checkWarn
+		}
+		checks = append(checks, doctorCheck{Name: issue.Field, Status: status, Detail: issue.Message})
+	}
+
+	// 模型连接，配置不完整的提供者跳过
+	names := make([]string, 0, len(config.Providers))
+	for name := range config.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		check := doctorCheck{Name: "连接 " + name}
+		if len(configErrors(config.ValidateProvider(name))) > 0 {
+			check.Status, check.Detail = checkSkip, "配置不完整"
+		} else if latency, err := pingProvider(config.Providers[name]); err != nil {
+			check.Status, check.Detail = checkFail, err.Error()
+			if name != config.DefaultProvider {
+				check.Status = checkWarn
+			}
+		} else {
+			check.Status, check.Detail = checkOK, fmt.Sprintf("%s，耗时 %s", config.Providers[name].ModelName, latency.Round(time.Millisecond))
+		}
+		checks = append(checks, check)
+	}
+
+	// 目录写入权限
+	if o.Root != "" {
+		root, err := filepath.Abs(expandHome(o.Root))
+		if err != nil {
+			root = o.Root
+		}
+		checks = append(checks, checkWritableDir("整理目录", root, false))
+	}
+	if dir, err := journalDir(); err == nil {
+		checks = append(checks, checkWritableDir("运行日志目录", dir, true))
+	}
+	if !config.Cache.Disabled {
+		path := expandHome(expandEnvRefs(config.Cache.Path))
+		if path == "" {
+			path, _ = defaultCachePath()
+		}
+		if path != "" {
+			checks = append(checks, checkWritableDir("缓存目录", filepath.Dir(path), true))
+		}
+	}
+
+	return o.finishDoctor(checks)
+}
+
+// finishDoctor 输出检查结果，有失败项时以 exitError 退出
+func (o *cliOptions) finishDoctor(checks []doctorCheck) error {
+	failed := 0
+	for _, check := range checks {
+		if check.Status == checkFail {
+			failed++
+		}
+	}
+
+	if o.Format == formatJSON {
+		if err := o.writeJSON(checks); err != nil {
+			return err
+		}
+	} else {
+		printDoctorTable(o.out, checks)
+	}
+	if failed > 0 {
+		return exitWith(exitError, fmt.Errorf("%d 项检查未通过", failed))
+	}
+	return nil
+}
+
+// pingProvider 发送一个最小的请求测试模型连接，返回耗时
+func pingProvider(pc ProviderConfig) (time.Duration, error) {
+	payload, err := json.Marshal(APIRequest{
+		Model:     pc.ModelName,
+		Messages:  []map[string]string{{"role": "user", "content": "ping"}},
+		MaxTokens: 1,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest("POST", pc.APIURL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+pc.APIKey)
+
+	client := &http.Client{Timeout: 20 * time.Second}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("无法连接: %v", err)
+	}
+	defer resp.Body.Close()
+	latency := time.Since(start)
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return latency, nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return 0, fmt.Errorf("密钥无效或没有权限（状态码 %d）", resp.StatusCode)
+	case resp.StatusCode == http.StatusNotFound:
+		return 0, fmt.Errorf("接口地址或模型不存在（状态码 404）")
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+	return 0, fmt.Errorf("状态码 %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// checkWritableDir 通过创建临时文件检查目录是否可写，create 为 true 时目录不存在会先创建
+func checkWritableDir(name, dir string, create bool) doctorCheck {
+	check := doctorCheck{Name: name, Detail: dir}
+	if create {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			check.Status, check.Detail = checkFail, fmt.Sprintf("无法创建 %s: %v", dir, err)
+			return check
+		}
+	} else if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		check.Status, check.Detail = checkFail, fmt.Sprintf("目录不存在: %s", dir)
+		return check
+	}
+
+	f, err := os.CreateTemp(dir, ".fileclassify-doctor-*")
+	if err != nil {
+		check.Status, check.Detail = checkFail, fmt.Sprintf("无法写入 %s: %v", dir, err)
+		return check
+	}
+	f.Close()
+	os.Remove(f.Name())
+	check.Status = checkOK
+	return check
+}
+
+// printDoctorTable 按列对齐打印检查结果，中文按两个字符宽度计算
+func printDoctorTable(w io.Writer, checks []doctorCheck) {
+	statusNames := map[string]string{checkOK: "通过", checkWarn: "警告", checkFail: "失败", checkSkip: "跳过"}
+	nameWidth := displayWidth("检查项")
+	for _, check := range checks {
+		if width := displayWidth(check.Name); width > nameWidth {
+			nameWidth = width
+		}
+	}
+
+	row := func(name, status, detail string) {
+		fmt.Fprintf(w, "%s%s  %s  %s\n", name, strings.Repeat(" ", nameWidth-displayWidth(name)), status, detail)
+	}
+	row("检查项", "状态", "说明")
+	row(strings.Repeat("-", nameWidth), "----", strings.Repeat("-", 20))
+	for _, check := range checks {
+		row(check.Name, statusNames[check.Status], check.Detail)
+	}
+}
+
+// displayWidth 估算字符串在终端中的显示宽度
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		if r >= 0x1100 && utf8.RuneLen(r) > 1 {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
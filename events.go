@@ -0,0 +1,76 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// ProgressEventKind 进度事件的类型
+type ProgressEventKind string
+
+// 整理流程中各阶段发出的进度事件
+const (
+	EventScanStarted     ProgressEventKind = "scan_started"     // 开始扫描目录
+	EventScanProgress    ProgressEventKind = "scan_progress"    // 扫描中，Done 为已找到的文件数
+	EventScanFinished    ProgressEventKind = "scan_finished"    // 扫描完成，Total 为文件总数
+	EventClassifyStarted ProgressEventKind = "classify_started" // 开始分类，Total 为需要分类的文件数
+	EventChunkSent       ProgressEventKind = "chunk_sent"       // 一批文件已发送给模型
+	EventChunkReceived   ProgressEventKind = "chunk_received"   // 收到一批文件的分类结果
+	EventChunkFailed     ProgressEventKind = "chunk_failed"     // 一批文件分类失败
+	EventRetry           ProgressEventKind = "retry"            // API 调用失败，等待后重试
+	EventMoveStarted     ProgressEventKind = "move_started"     // 开始移动，Total 为计划中的文件数
+	EventFileMoved       ProgressEventKind = "file_moved"       // 文件已移动到 Dst
+	EventFileSkipped     ProgressEventKind = "file_skipped"     // 文件未移动，原因在 Message 中
+	EventFileFailed      ProgressEventKind = "file_failed"      // 文件移动失败，原因在 Message 中
+	EventRunFinished     ProgressEventKind = "run_finished"     // 移动结束，Done 为移动成功的文件数，取消时 Message 说明未移动的文件
+)
+
+// ProgressEvent 一个进度事件，未使用的字段为零值
+type ProgressEvent struct {
+	Kind    ProgressEventKind `json:"kind"`
+	Time    time.Time         `json:"time"`
+	Root    string            `json:"root,omitempty"`
+	Path    string            `json:"path,omitempty"`    // 相对于整理目录的路径
+	Dst     string            `json:"dst,omitempty"`     // 相对于整理目录的目标路径
+	Chunk   int               `json:"chunk,omitempty"`   // 批次序号，从 1 开始
+	Chunks  int               `json:"chunks,omitempty"`  // 批次总数
+	Files   int               `json:"files,omitempty"`   // 本批次的文件数
+	Attempt int               `json:"attempt,omitempty"` // 重试次数，从 1 开始
+	Done    int               `json:"done,omitempty"`
+	Total   int               `json:"total,omitempty"`
+	Message string            `json:"message,omitempty"`
+}
+
+// ProgressHandler 接收进度事件，调用是串行的，但可能来自不同的协程
+type ProgressHandler func(ProgressEvent)
+
+var (
+	progressMu      sync.Mutex
+	progressHandler ProgressHandler // 为nil时不输出进度
+)
+
+// setProgressHandler 设置接收进度事件的函数，返回恢复原设置的函数
+func setProgressHandler(handler ProgressHandler) (restore func()) {
+	progressMu.Lock()
+	previous := progressHandler
+	progressHandler = handler
+	progressMu.Unlock()
+	return func() {
+		progressMu.Lock()
+		progressHandler = previous
+		progressMu.Unlock()
+	}
+}
+
+// emitProgress 发出一个进度事件
+func emitProgress(event ProgressEvent) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	if progressHandler == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	progressHandler(event)
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"log/slog"
+	"sort"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -23,7 +26,7 @@ type MyMainWindow struct {
 
 // 创建主窗口
 func createMainWindow() {
-	fmt.Println("开始创建主窗口...")
+	slog.Debug("开始创建主窗口")
 
 	// 创建应用
 	a := app.NewWithID("com.fileclean.app")
@@ -47,20 +50,32 @@ func createMainWindow() {
 		}, w)
 	})
 
-	// 创建模型选择部分
-	providerSelect := widget.NewSelect([]string{"deepseek", "siliconflow", "aliyun", "github"}, nil)
+	// 创建模型选择部分，选项为配置中的提供者
+	providerSelect := widget.NewSelect(supportedProviders, nil)
 	providerSelect.SetSelected("deepseek")
+	updateProviders := func(config *Config) {
+		var names []string
+		for name := range config.Providers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		providerSelect.Options = names
+		providerSelect.SetSelected(config.DefaultProvider)
+	}
+	if config, err := LoadConfig(); err == nil {
+		updateProviders(config)
+	}
 
 	// 创建复选框
-	recursiveCheck := widget.NewCheck("不递归处理子目录", func(checked bool) {
-		treatDirsAsFiles = checked
-	})
+	recursiveCheck := widget.NewCheck("不递归处理子目录", nil)
 	recursiveCheck.SetChecked(false)
 
-	// 创建开始按钮
-	var startBtn *widget.Button
+	// 创建开始按钮和设置按钮
+	var startBtn, settingsBtn *widget.Button
 	startBtn = widget.NewButton("开始整理", func() {
-		if folderEntry.Text == "" {
+		// 在主线程中读取控件的值，整理过程中只使用这些副本
+		folder, provider, dirs := folderEntry.Text, providerSelect.Selected, recursiveCheck.Checked
+		if folder == "" {
 			dialog.ShowError(fmt.Errorf("请先选择要整理的文件夹"), w)
 			return
 		}
@@ -71,14 +86,22 @@ func createMainWindow() {
 		providerSelect.Disable()
 		recursiveCheck.Disable()
 		browseButton.Disable()
+		settingsBtn.Disable()
 
-		// 设置全局变量
-		providerType = providerSelect.Selected
-		treatDirsAsFiles = recursiveCheck.Checked
-
-		// 在新协程中执行文件整理
+		// 在新协程中执行文件整理，进度显示在对话框中
+		ctx, cancel := context.WithCancel(context.Background())
+		progress := newProgressDialog(w, cancel)
 		go func() {
+			restore := setProgressHandler(progress.handle)
+			completed := false
 			defer func() {
+				restore()
+				cancel()
+				if completed {
+					progress.finish()
+				} else {
+					progress.hide()
+				}
 				// 在主线程中恢复控件状态
 				fyne.Do(func() {
 					startBtn.Enable()
@@ -86,6 +109,7 @@ func createMainWindow() {
 					providerSelect.Enable()
 					recursiveCheck.Enable()
 					browseButton.Enable()
+					settingsBtn.Enable()
 					w.Canvas().Refresh(startBtn)
 					w.Canvas().Refresh(folderEntry)
 					w.Canvas().Refresh(providerSelect)
@@ -102,139 +126,81 @@ func createMainWindow() {
 				})
 				return
 			}
-
-			// 获取指定提供者的配置
-			providerConfig, err := config.GetProviderConfig(providerType)
-			if err != nil {
+			if err := setupLogging(config.Log); err != nil {
 				fyne.Do(func() {
-					dialog.ShowError(fmt.Errorf("获取模型配置失败: %v", err), w)
+					dialog.ShowError(err, w)
 				})
 				return
 			}
+			restoreLogs := progress.captureLogs()
+			defer restoreLogs()
 
-			// 创建大模型提供者
-			provider, err := NewLLMProvider(providerType, map[string]string{
-				"api_key":    providerConfig.APIKey,
-				"api_secret": providerConfig.APISecret,
-				"api_url":    providerConfig.APIURL,
-				"model_name": providerConfig.ModelName,
-				"timeout":    "120", // 设置120秒超时
+			// 与命令行使用相同的整理流程，界面只接入扫描警告和分类预览
+			org, err := NewOrganizer(config, OrganizerOptions{
+				Provider: provider,
+				Dirs:     dirs,
 			})
 			if err != nil {
 				fyne.Do(func() {
-					dialog.ShowError(fmt.Errorf("创建模型提供者失败: %v", err), w)
+					dialog.ShowError(err, w)
 				})
 				return
 			}
+			org.Hooks.ScanWarnings = func(warnings []string) {
+				fyne.Do(func() {
+					dialog.ShowInformation("扫描警告", strings.Join(warnings, "\n"), w)
+				})
+			}
+			// 移动前预览分类结果，用户可以调整，点击应用后才开始移动
+			org.Hooks.Review = func(classified map[string][]FileInfo) (map[string][]FileInfo, error) {
+				classified, ok := showMovePreview(w, classified)
+				if !ok {
+					return nil, errReviewCancelled
+				}
+				return classified, nil
+			}
 
-			// 获取文件列表
-			files, err := getFileList(folderEntry.Text)
-			if err != nil {
+			if _, err := org.OpenProvider(); err != nil {
 				fyne.Do(func() {
-					dialog.ShowError(fmt.Errorf("获取文件列表失败: %v", err), w)
+					dialog.ShowError(err, w)
 				})
 				return
 			}
-
-			// 使用大模型对文件进行分类
-			classifiedFiles, err := provider.ClassifyFiles(files)
+			scan, err := org.Scan(folder)
 			if err != nil {
 				fyne.Do(func() {
-					dialog.ShowError(fmt.Errorf("分类失败: %v", err), w)
+					dialog.ShowError(err, w)
 				})
 				return
 			}
 
-			// 创建分类目录并移动文件
-			for category, files := range classifiedFiles {
-				categoryPath := filepath.Join(folderEntry.Text, category)
-				if err := os.MkdirAll(categoryPath, 0755); err != nil {
-					fyne.Do(func() {
-						dialog.ShowError(fmt.Errorf("创建分类目录失败: %v", err), w)
-					})
-					continue
-				}
-
-				for _, file := range files {
-					srcPath := filepath.Join(folderEntry.Text, file.Path)
-					dstPath := filepath.Join(categoryPath, filepath.Base(file.Path))
-
-					// 检查源文件是否存在
-					if _, err := os.Stat(srcPath); os.IsNotExist(err) {
-						continue
-					}
-
-					// 如果是目录
-					if file.IsDir {
-						// 直接移动整个目录
-						if err := os.Rename(srcPath, dstPath); err != nil {
-							// 如果移动失败，尝试复制后删除
-							if err := copyDir(srcPath, dstPath); err != nil {
-								fyne.Do(func() {
-									dialog.ShowError(fmt.Errorf("移动目录失败: %v", err), w)
-								})
-								continue
-							}
-							if err := os.RemoveAll(srcPath); err != nil {
-								fyne.Do(func() {
-									dialog.ShowError(fmt.Errorf("删除原目录失败: %v", err), w)
-								})
-							}
-						}
-					} else {
-						// 处理普通文件
-						// 检查目标文件是否已存在
-						if _, err := os.Stat(dstPath); err == nil {
-							// 如果目标文件已存在，添加数字后缀
-							ext := filepath.Ext(dstPath)
-							base := filepath.Base(dstPath[:len(dstPath)-len(ext)])
-							counter := 1
-							for {
-								newDstPath := filepath.Join(categoryPath, fmt.Sprintf("%s_%d%s", base, counter, ext))
-								if _, err := os.Stat(newDstPath); os.IsNotExist(err) {
-									dstPath = newDstPath
-									break
-								}
-								counter++
-							}
-						}
-
-						// 使用Copy+Remove替代Rename
-						if err := copyFile(srcPath, dstPath); err != nil {
-							fyne.Do(func() {
-								dialog.ShowError(fmt.Errorf("复制文件失败: %v", err), w)
-							})
-							continue
-						}
-						if err := os.Remove(srcPath); err != nil {
-							fyne.Do(func() {
-								dialog.ShowError(fmt.Errorf("删除原文件失败: %v", err), w)
-							})
-						}
-					}
-				}
+			// 分类并生成移动计划，取消时没有移动任何文件
+			plan, err := org.Plan(ctx, folder, scan.Files, scan.Existing)
+			if ctx.Err() != nil {
+				progress.showCancelled()
+				completed = true
+				return
+			}
+			if errors.Is(err, errReviewCancelled) {
+				return
+			}
+			if err != nil {
+				fyne.Do(func() {
+					dialog.ShowError(err, w)
+				})
+				return
 			}
 
-			// 删除空文件夹
-			for {
-				emptyDirs, err := findEmptyDirs(folderEntry.Text)
-				if err != nil {
-					break
-				}
-
-				if len(emptyDirs) == 0 {
-					break
-				}
-
-				for _, dir := range emptyDirs {
-					os.Remove(dir)
-				}
+			// 移动文件并记录运行日志，每个文件的结果显示在进度对话框中；取消时在当前文件处理完后停止
+			if _, _, err := org.Apply(ctx, plan, scan.State, nil); err != nil {
+				fyne.Do(func() {
+					dialog.ShowError(err, w)
+				})
+				return
 			}
 
-			// 显示完成消息
-			fyne.Do(func() {
-				dialog.ShowInformation("完成", "文件整理完成！", w)
-			})
+			// 进度对话框中显示完成和每个文件的结果
+			completed = true
 		}()
 	})
 
@@ -245,7 +211,13 @@ func createMainWindow() {
 		providerSelect,
 		recursiveCheck,
 	))
-	actionGroup := widget.NewCard("操作", "", container.NewCenter(startBtn))
+	settingsBtn = widget.NewButton("设置...", func() {
+		showSettingsWindow(a, w, updateProviders)
+	})
+	historyBtn := widget.NewButton("运行记录...", func() {
+		showHistoryWindow(a)
+	})
+	actionGroup := widget.NewCard("操作", "", container.NewCenter(container.NewHBox(startBtn, settingsBtn, historyBtn)))
 
 	// 创建主布局
 	content := container.NewVBox(
@@ -263,37 +235,3 @@ func createMainWindow() {
 	w.CenterOnScreen()
 	w.ShowAndRun()
 }
-
-// copyDir 复制整个目录
-func copyDir(src, dst string) error {
-	// 创建目标目录
-	if err := os.MkdirAll(dst, 0755); err != nil {
-		return err
-	}
-
-	// 读取源目录
-	entries, err := os.ReadDir(src)
-	if err != nil {
-		return err
-	}
-
-	// 遍历源目录中的所有文件和子目录
-	for _, entry := range entries {
-		srcPath := filepath.Join(src, entry.Name())
-		dstPath := filepath.Join(dst, entry.Name())
-
-		if entry.IsDir() {
-			// 递归复制子目录
-			if err := copyDir(srcPath, dstPath); err != nil {
-				return err
-			}
-		} else {
-			// 复制文件
-			if err := copyFile(srcPath, dstPath); err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
-}
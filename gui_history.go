@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// journalActionNames 运行日志中操作类型的显示名称
+var journalActionNames = map[string]string{
+	JournalMove:   "移动",
+	JournalLink:   "替换为硬链接",
+	JournalDedupe: "删除重复文件",
+}
+
+// historyWindow 运行记录窗口：列出之前的整理，查看每个文件的操作并一键撤销
+type historyWindow struct {
+	window   fyne.Window
+	journals []*RunJournal
+	selected *RunJournal
+
+	runs       *widget.List
+	info       *widget.Label
+	entries    *widget.List
+	undoButton *widget.Button
+}
+
+// showHistoryWindow 打开运行记录窗口，与命令行的 history 和 undo 使用相同的运行日志
+func showHistoryWindow(a fyne.App) {
+	h := &historyWindow{window: a.NewWindow("运行记录")}
+
+	h.runs = widget.NewList(
+		func() int { return len(h.journals) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(runSummary(h.journals[id]))
+		},
+	)
+	h.runs.OnSelected = func(id widget.ListItemID) {
+		h.selectRun(h.journals[id])
+	}
+
+	h.info = widget.NewLabel("选择一条运行记录查看详情")
+	h.info.Wrapping = fyne.TextWrapWord
+	h.entries = widget.NewList(
+		func() int {
+			if h.selected == nil {
+				return 0
+			}
+			return len(h.selected.Entries)
+		},
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(entrySummary(h.selected, h.selected.Entries[id]))
+		},
+	)
+	h.undoButton = widget.NewButton("撤销这次整理", h.undo)
+	h.undoButton.Importance = widget.DangerImportance
+	h.undoButton.Disable()
+
+	detail := container.NewBorder(h.info, container.NewHBox(h.undoButton), nil, nil, h.entries)
+	split := container.NewVSplit(h.runs, detail)
+	split.Offset = 0.4
+	toolbar := container.NewHBox(widget.NewButton("刷新", h.reload))
+
+	h.window.SetContent(container.NewPadded(container.NewBorder(toolbar, nil, nil, nil, split)))
+	h.window.Resize(fyne.NewSize(760, 560))
+	h.window.CenterOnScreen()
+	h.reload()
+	h.window.Show()
+}
+
+// runSummary 列表中一次运行的摘要：时间、目录、模型和文件数
+func runSummary(j *RunJournal) string {
+	provider := j.Provider
+	if provider == "" {
+		provider = j.Model
+	}
+	text := fmt.Sprintf("%s  %s  %s  %d 个文件", j.StartedAt.Format("2006-01-02 15:04"), j.Root, provider, len(j.Entries))
+	switch {
+	case !j.UndoneAt.IsZero():
+		text += "（已撤销）"
+	case j.FinishedAt.IsZero():
+		text += "（未完成）"
+	}
+	return text
+}
+
+// entrySummary 一个操作的说明，路径相对于整理目录
+func entrySummary(j *RunJournal, entry JournalEntry) string {
+	action := journalActionNames[entry.Action]
+	if action == "" {
+		action = entry.Action
+	}
+	text := fmt.Sprintf("%s  %s -> %s", action, j.rel(entry.Src), j.rel(entry.Dst))
+	if entry.Overwrote {
+		text += "（覆盖了原文件）"
+	}
+	return text
+}
+
+// reload 重新读取运行日志，保持选中的运行
+func (h *historyWindow) reload() {
+	journals, err := ListRunJournals()
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("读取运行记录失败: %v", err), h.window)
+		return
+	}
+	h.journals = journals
+	h.runs.UnselectAll()
+	h.runs.Refresh()
+
+	previous := h.selected
+	h.selectRun(nil)
+	for i, j := range journals {
+		if previous != nil && j.ID == previous.ID {
+			h.runs.Select(i)
+		}
+	}
+	if len(journals) == 0 {
+		h.info.SetText("没有运行记录")
+	}
+}
+
+// selectRun 显示一次运行的详情
+func (h *historyWindow) selectRun(j *RunJournal) {
+	h.selected = j
+	h.entries.Refresh()
+	if j == nil {
+		h.info.SetText("选择一条运行记录查看详情")
+		h.undoButton.Disable()
+		return
+	}
+
+	lines := []string{
+		"编号: " + j.ID,
+		"目录: " + j.Root,
+		"开始时间: " + j.StartedAt.Format("2006-01-02 15:04:05"),
+	}
+	if j.Provider != "" || j.Model != "" {
+		lines = append(lines, "模型: "+strings.TrimSpace(j.Provider+" "+j.Model))
+	}
+	switch {
+	case !j.UndoneAt.IsZero():
+		lines = append(lines, "已于 "+j.UndoneAt.Format("2006-01-02 15:04:05")+" 撤销")
+	case j.FinishedAt.IsZero():
+		lines = append(lines, "运行未正常结束，已完成的操作可以撤销")
+	}
+	h.info.SetText(strings.Join(lines, "\n"))
+
+	if j.UndoneAt.IsZero() {
+		h.undoButton.Enable()
+	} else {
+		h.undoButton.Disable()
+	}
+}
+
+// undo 先检查哪些文件无法恢复，确认后在后台撤销并显示结果
+func (h *historyWindow) undo() {
+	j := h.selected
+	if j == nil {
+		return
+	}
+	count, conflicts := j.Undo(true, nil)
+	if count == 0 {
+		showLinesDialog("撤销整理", "没有可以恢复的文件。", conflicts, h.window)
+		return
+	}
+
+	message := fmt.Sprintf("将把 %s 中的 %d 个文件恢复到整理前的位置。", j.Root, count)
+	if len(conflicts) > 0 {
+		message += fmt.Sprintf("\n以下 %d 个文件无法恢复，将保持现状：", len(conflicts))
+	}
+	content := linesContent(message, conflicts)
+	dialog.ShowCustomConfirm("撤销整理", "撤销", "取消", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		h.undoButton.Disable()
+		progress := dialog.NewCustomWithoutButtons("正在撤销", widget.NewProgressBarInfinite(), h.window)
+		progress.Show()
+		go func() {
+			restored, failures := j.Undo(false, nil)
+			fyne.Do(func() {
+				progress.Hide()
+				h.reload()
+				message := fmt.Sprintf("已恢复 %d 个文件。", restored)
+				if len(failures) > 0 {
+					message += fmt.Sprintf("\n以下 %d 个问题需要手动处理：", len(failures))
+				}
+				showLinesDialog("撤销完成", message, failures, h.window)
+			})
+		}()
+	}, h.window)
+}
+
+// linesContent 说明文字和可滚动的多行列表，用于显示冲突和失败的文件
+func linesContent(message string, lines []string) fyne.CanvasObject {
+	header := widget.NewLabel(message)
+	header.Wrapping = fyne.TextWrapWord
+	if len(lines) == 0 {
+		return header
+	}
+	scroll := container.NewVScroll(widget.NewLabel(strings.Join(lines, "\n")))
+	scroll.SetMinSize(fyne.NewSize(560, 200))
+	return container.NewBorder(header, nil, nil, nil, scroll)
+}
+
+// showLinesDialog 显示说明文字和多行列表
+func showLinesDialog(title, message string, lines []string, w fyne.Window) {
+	dialog.ShowCustom(title, "关闭", linesContent(message, lines), w)
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 预览树中节点编号的前缀，分类节点为 c/名称，文件节点为 f/路径
+const (
+	previewCategoryPrefix = "c/"
+	previewFilePrefix     = "f/"
+)
+
+// movePreview 移动前的分类预览：树形显示分类和文件，可以把文件拖到其他分类，重命名或删除分类
+type movePreview struct {
+	review *classificationReview // 与终端审查界面共用的编辑状态，只在主线程中访问
+	window fyne.Window
+	tree   *widget.Tree
+	status *widget.Label
+
+	renameButton  *widget.Button
+	deleteButton  *widget.Button
+	excludeButton *widget.Button
+
+	nodes    []*previewNode // 树中创建过的所有行，拖动结束时据此查找放下的位置
+	selected widget.TreeNodeID
+	dragging widget.TreeNodeID
+	dropAt   fyne.Position
+}
+
+// previewNode 预览树中的一行，文件行可以拖到其他分类上，右键可以选择目标分类
+type previewNode struct {
+	widget.Label
+	preview *movePreview
+	uid     widget.TreeNodeID
+}
+
+func newPreviewNode(p *movePreview) *previewNode {
+	n := &previewNode{preview: p}
+	n.ExtendBaseWidget(n)
+	return n
+}
+
+// Dragged 记录拖动的文件和当前位置
+func (n *previewNode) Dragged(event *fyne.DragEvent) {
+	n.preview.drag(n.uid, event.AbsolutePosition)
+}
+
+// DragEnd 在松开的位置放下文件
+func (n *previewNode) DragEnd() {
+	n.preview.drop()
+}
+
+// TappedSecondary 右键菜单
+func (n *previewNode) TappedSecondary(event *fyne.PointEvent) {
+	n.preview.showMenu(n.uid, event.AbsolutePosition)
+}
+
+// newMovePreview 创建分类预览，需要在主线程中调用
+func newMovePreview(w fyne.Window, classified map[string][]FileInfo) *movePreview {
+	p := &movePreview{
+		review: newClassificationReview(classified),
+		window: w,
+		status: widget.NewLabel(""),
+	}
+	p.status.Wrapping = fyne.TextWrapWord
+	p.tree = widget.NewTree(p.childUIDs, p.isBranch,
+		func(bool) fyne.CanvasObject {
+			n := newPreviewNode(p)
+			p.nodes = append(p.nodes, n)
+			return n
+		},
+		p.updateNode,
+	)
+	p.tree.OnSelected = func(uid widget.TreeNodeID) {
+		p.selected = uid
+		p.updateButtons()
+	}
+	p.tree.OnUnselected = func(widget.TreeNodeID) {
+		p.selected = ""
+		p.updateButtons()
+	}
+
+	p.renameButton = widget.NewButton("重命名分类", p.renameSelected)
+	p.deleteButton = widget.NewButton("删除分类", p.deleteSelected)
+	p.excludeButton = widget.NewButton("不移动", p.toggleSelected)
+	p.updateButtons()
+	p.tree.OpenAllBranches()
+	p.showStatus()
+	return p
+}
+
+// content 返回预览界面，上方为操作按钮，下方为说明和状态
+func (p *movePreview) content() fyne.CanvasObject {
+	hint := widget.NewLabel("将文件拖到其他分类上可以改变分类，右键文件可以选择目标分类")
+	hint.Wrapping = fyne.TextWrapWord
+	buttons := container.NewHBox(p.renameButton, p.deleteButton, p.excludeButton)
+	return container.NewBorder(buttons, container.NewVBox(hint, p.status), nil, nil, p.tree)
+}
+
+// lookup 返回节点对应的分类和文件序号，分类节点的文件序号为 -1，找不到时分类序号为 -1
+func (p *movePreview) lookup(uid widget.TreeNodeID) (int, int) {
+	switch {
+	case strings.HasPrefix(uid, previewCategoryPrefix):
+		return p.review.findCategory(strings.TrimPrefix(uid, previewCategoryPrefix)), -1
+	case strings.HasPrefix(uid, previewFilePrefix):
+		path := strings.TrimPrefix(uid, previewFilePrefix)
+		for ci, category := range p.review.categories {
+			for fi, file := range category.files {
+				if file.Path == path {
+					return ci, fi
+				}
+			}
+		}
+	}
+	return -1, -1
+}
+
+func (p *movePreview) childUIDs(uid widget.TreeNodeID) []widget.TreeNodeID {
+	var ids []widget.TreeNodeID
+	if uid == "" {
+		for _, category := range p.review.categories {
+			ids = append(ids, previewCategoryPrefix+category.name)
+		}
+		return ids
+	}
+	if ci, _ := p.lookup(uid); ci >= 0 {
+		for _, file := range p.review.categories[ci].files {
+			ids = append(ids, previewFilePrefix+file.Path)
+		}
+	}
+	return ids
+}
+
+func (p *movePreview) isBranch(uid widget.TreeNodeID) bool {
+	return uid == "" || strings.HasPrefix(uid, previewCategoryPrefix)
+}
+
+func (p *movePreview) updateNode(uid widget.TreeNodeID, branch bool, obj fyne.CanvasObject) {
+	n := obj.(*previewNode)
+	n.uid = uid
+	ci, fi := p.lookup(uid)
+	if ci < 0 {
+		n.SetText("")
+		return
+	}
+	category := p.review.categories[ci]
+	if fi < 0 {
+		text := fmt.Sprintf("%s（%d）", category.name, len(category.files))
+		if excluded := p.review.excludedCount(category); excluded > 0 {
+			text += fmt.Sprintf("，%d 个不移动", excluded)
+		}
+		n.SetText(text)
+		return
+	}
+	file := category.files[fi]
+	text := file.Path
+	if file.IsDir {
+		text += "/"
+	}
+	if p.review.excluded[file.Path] {
+		text += "（不移动）"
+	}
+	n.SetText(text)
+}
+
+// refresh 修改分类后刷新树和状态，保持分类展开
+func (p *movePreview) refresh() {
+	p.tree.OpenAllBranches()
+	p.tree.Refresh()
+	p.updateButtons()
+	p.showStatus()
+}
+
+// showStatus 显示最近一次操作的结果，没有操作时显示统计
+func (p *movePreview) showStatus() {
+	if p.review.message != "" {
+		p.status.SetText(p.review.message)
+		p.review.message = ""
+		return
+	}
+	files, excluded := p.review.totals()
+	p.status.SetText(fmt.Sprintf("共 %d 个分类，%d 个文件，%d 个不移动", len(p.review.categories), files, excluded))
+}
+
+// updateButtons 按选中的节点启用按钮
+func (p *movePreview) updateButtons() {
+	ci, fi := p.lookup(p.selected)
+	for _, button := range []*widget.Button{p.renameButton, p.deleteButton, p.excludeButton} {
+		if ci < 0 {
+			button.Disable()
+		} else {
+			button.Enable()
+		}
+	}
+	if ci >= 0 && fi >= 0 {
+		// 选中文件时重命名和删除作用于文件所在的分类，只有排除针对文件本身
+		if p.review.excluded[p.review.categories[ci].files[fi].Path] {
+			p.excludeButton.SetText("恢复移动")
+		} else {
+			p.excludeButton.SetText("不移动")
+		}
+	} else {
+		p.excludeButton.SetText("整个分类不移动")
+	}
+}
+
+// drag 拖动文件时记录位置，并提示将要放到哪个分类
+func (p *movePreview) drag(uid widget.TreeNodeID, pos fyne.Position) {
+	if !strings.HasPrefix(uid, previewFilePrefix) {
+		return
+	}
+	p.dragging, p.dropAt = uid, pos
+	if target := p.categoryAt(pos); target != "" {
+		p.status.SetText(fmt.Sprintf("移到 %s", target))
+	} else {
+		p.status.SetText("拖到分类或其中的文件上松开")
+	}
+}
+
+// drop 将拖动的文件放到松开位置所在的分类
+func (p *movePreview) drop() {
+	uid := p.dragging
+	p.dragging = ""
+	if uid == "" {
+		return
+	}
+	if target := p.categoryAt(p.dropAt); target != "" {
+		p.moveTo(uid, target)
+		return
+	}
+	p.showStatus()
+}
+
+// categoryAt 返回位置所在行的分类名称，不在任何行上时返回空字符串
+func (p *movePreview) categoryAt(pos fyne.Position) string {
+	driver := fyne.CurrentApp().Driver()
+	if !p.contains(p.tree, pos) {
+		return ""
+	}
+	for _, n := range p.nodes {
+		// 回收到缓存中的行不在画布上，编号可能已经过期
+		if n.uid == "" || !n.Visible() || driver.CanvasForObject(n) == nil || !p.contains(n, pos) {
+			continue
+		}
+		if ci, _ := p.lookup(n.uid); ci >= 0 {
+			return p.review.categories[ci].name
+		}
+	}
+	return ""
+}
+
+// contains 判断绝对位置是否在控件范围内
+func (p *movePreview) contains(obj fyne.CanvasObject, pos fyne.Position) bool {
+	origin := fyne.CurrentApp().Driver().AbsolutePositionForObject(obj)
+	size := obj.Size()
+	return pos.X >= origin.X && pos.Y >= origin.Y && pos.X < origin.X+size.Width && pos.Y < origin.Y+size.Height
+}
+
+// moveTo 将文件移到指定名称的分类，分类不存在时新建
+func (p *movePreview) moveTo(uid widget.TreeNodeID, category string) {
+	ci, fi := p.lookup(uid)
+	if ci < 0 || fi < 0 {
+		return
+	}
+	p.review.moveFile(ci, fi, category)
+	p.refresh()
+}
+
+// showMenu 在文件上右键时列出可以移到的分类
+func (p *movePreview) showMenu(uid widget.TreeNodeID, pos fyne.Position) {
+	ci, fi := p.lookup(uid)
+	if ci < 0 {
+		return
+	}
+	p.tree.Select(uid)
+
+	var items []*fyne.MenuItem
+	if fi >= 0 {
+		var targets []*fyne.MenuItem
+		for i, category := range p.review.categories {
+			if i == ci {
+				continue
+			}
+			name := category.name
+			targets = append(targets, fyne.NewMenuItem(name, func() { p.moveTo(uid, name) }))
+		}
+		targets = append(targets, fyne.NewMenuItem("新分类...", func() {
+			p.askName("移到新分类", "", func(name string) { p.moveTo(uid, name) })
+		}))
+		moveItem := fyne.NewMenuItem("移到", nil)
+		moveItem.ChildMenu = fyne.NewMenu("", targets...)
+		items = append(items, moveItem)
+	}
+	items = append(items,
+		fyne.NewMenuItem(p.excludeButton.Text, p.toggleSelected),
+		fyne.NewMenuItem("重命名分类...", p.renameSelected),
+		fyne.NewMenuItem("删除分类", p.deleteSelected),
+	)
+	widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", items...), p.window.Canvas(), pos)
+}
+
+// askName 弹出输入分类名称的对话框
+func (p *movePreview) askName(title, initial string, done func(string)) {
+	entry := widget.NewEntry()
+	entry.SetText(initial)
+	dialog.ShowForm(title, "确定", "取消", []*widget.FormItem{widget.NewFormItem("分类名称", entry)}, func(ok bool) {
+		if name := strings.TrimSpace(entry.Text); ok && name != "" {
+			done(name)
+		}
+	}, p.window)
+}
+
+// renameSelected 重命名选中的分类，改为已有的名称时合并
+func (p *movePreview) renameSelected() {
+	ci, _ := p.lookup(p.selected)
+	if ci < 0 {
+		return
+	}
+	old := p.review.categories[ci].name
+	p.askName("重命名分类", old, func(name string) {
+		if ci := p.review.findCategory(old); ci >= 0 {
+			p.review.rename(ci, name)
+			p.tree.Select(previewCategoryPrefix + name)
+			p.refresh()
+		}
+	})
+}
+
+// deleteSelected 删除选中的分类，其中的文件保留在原位置
+func (p *movePreview) deleteSelected() {
+	ci, _ := p.lookup(p.selected)
+	if ci < 0 {
+		return
+	}
+	name := p.review.categories[ci].name
+	message := fmt.Sprintf("删除分类 %s 后，其中的 %d 个文件将保留在原位置，确定吗？", name, len(p.review.categories[ci].files))
+	dialog.ShowConfirm("删除分类", message, func(ok bool) {
+		if ci := p.review.findCategory(name); ok && ci >= 0 {
+			p.review.removeCategory(ci)
+			p.tree.UnselectAll()
+			p.refresh()
+		}
+	}, p.window)
+}
+
+// toggleSelected 排除或恢复选中的文件，选中分类时对整个分类操作
+func (p *movePreview) toggleSelected() {
+	ci, fi := p.lookup(p.selected)
+	if ci < 0 {
+		return
+	}
+	p.review.toggleExclude(reviewRow{category: ci, file: fi})
+	p.refresh()
+}
+
+// showMovePreview 在主窗口中显示分类预览，阻塞等待用户应用或取消；取消时返回 false
+func showMovePreview(w fyne.Window, classified map[string][]FileInfo) (map[string][]FileInfo, bool) {
+	result := make(chan map[string][]FileInfo, 1)
+	fyne.Do(func() {
+		p := newMovePreview(w, classified)
+		d := dialog.NewCustomConfirm("确认分类结果", "应用", "取消", p.content(), func(ok bool) {
+			if !ok {
+				result <- nil
+				return
+			}
+			result <- p.review.result()
+		}, w)
+		d.Resize(fyne.NewSize(700, 520))
+		d.Show()
+	})
+	classified = <-result
+	return classified, classified != nil
+}
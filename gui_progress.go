@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// progressPhases 整理的各个阶段，进度条显示当前阶段的进度
+var progressPhases = []string{"扫描文件", "模型分类", "移动文件"}
+
+// progressLogLimit 日志面板最多保留的行数
+const progressLogLimit = 5000
+
+// progressDialog 整理过程中显示的进度对话框：分阶段的进度条、滚动的日志和取消按钮
+type progressDialog struct {
+	dialog *dialog.CustomDialog
+	phase  *widget.Label
+	stage  *widget.Label
+	bar    *widget.ProgressBar
+	list   *widget.List
+	cancel *widget.Button
+	stop   context.CancelFunc
+
+	// 以下字段只在主线程中访问
+	logs      []string
+	current   int // 当前阶段，从 1 开始
+	received  int // 已完成的批次数
+	cancelled bool
+}
+
+// newProgressDialog 创建并显示进度对话框，点击取消时调用 stop
+func newProgressDialog(w fyne.Window, stop context.CancelFunc) *progressDialog {
+	p := &progressDialog{
+		phase: widget.NewLabel(""),
+		stage: widget.NewLabel("准备中..."),
+		bar:   widget.NewProgressBar(),
+		stop:  stop,
+	}
+	p.phase.TextStyle = fyne.TextStyle{Bold: true}
+	p.list = widget.NewList(
+		func() int { return len(p.logs) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(p.logs[id])
+		},
+	)
+	p.cancel = widget.NewButton("取消", p.requestCancel)
+	scroll := container.NewVScroll(p.list)
+	scroll.SetMinSize(fyne.NewSize(600, 300))
+	content := container.NewBorder(container.NewVBox(p.phase, p.stage, p.bar), nil, nil, nil, scroll)
+
+	fyne.Do(func() {
+		p.dialog = dialog.NewCustomWithoutButtons("正在整理", content, w)
+		p.dialog.SetButtons([]fyne.CanvasObject{p.cancel})
+		p.dialog.Show()
+	})
+	return p
+}
+
+// requestCancel 取消整理：中止正在进行的API请求，移动中时在当前文件处理完后停止
+func (p *progressDialog) requestCancel() {
+	p.cancelled = true
+	p.cancel.Disable()
+	p.stage.SetText("正在取消，当前文件处理完后停止...")
+	p.addLog("用户取消")
+	p.stop()
+}
+
+// setPhase 切换到第 n 个阶段（从 1 开始），进度条从零开始
+func (p *progressDialog) setPhase(n int) {
+	p.current = n
+	p.phase.SetText(fmt.Sprintf("第 %d/%d 步：%s", n, len(progressPhases), progressPhases[n-1]))
+	p.bar.SetValue(0)
+}
+
+// handle 处理进度事件，可以在任意协程中调用
+func (p *progressDialog) handle(event ProgressEvent) {
+	fyne.Do(func() {
+		switch event.Kind {
+		case EventScanStarted:
+			p.setPhase(1)
+			p.stage.SetText("正在扫描 " + event.Root)
+			p.addLog("开始扫描 " + event.Root)
+		case EventScanProgress:
+			p.stage.SetText(fmt.Sprintf("正在扫描，已找到 %d 个文件", event.Done))
+		case EventScanFinished:
+			p.stage.SetText(fmt.Sprintf("找到 %d 个文件", event.Total))
+			p.bar.SetValue(1)
+			p.addLog(fmt.Sprintf("扫描完成，找到 %d 个文件", event.Total))
+		case EventClassifyStarted:
+			p.setPhase(2)
+			p.stage.SetText(fmt.Sprintf("正在使用模型分类 %d 个文件...", event.Total))
+		case EventChunkSent:
+			// 批次并发发送，发出第一批时切换阶段
+			if p.current < 2 {
+				p.setPhase(2)
+			}
+			p.stage.SetText(fmt.Sprintf("正在使用模型分类，共 %d 批", event.Chunks))
+			p.addLog(fmt.Sprintf("发送第 %d/%d 批（%d 个文件）", event.Chunk, event.Chunks, event.Files))
+		case EventChunkReceived, EventChunkFailed:
+			p.received++
+			p.bar.SetValue(float64(p.received) / float64(event.Chunks))
+			if event.Kind == EventChunkFailed {
+				p.addLog(fmt.Sprintf("第 %d 批文件分类失败: %s", event.Chunk, event.Message))
+			} else {
+				p.addLog(fmt.Sprintf("收到第 %d/%d 批的分类结果", event.Chunk, event.Chunks))
+			}
+		case EventRetry:
+			p.addLog(fmt.Sprintf("API调用失败，第 %d 次重试: %s", event.Attempt, event.Message))
+		case EventMoveStarted:
+			p.setPhase(3)
+			p.stage.SetText(fmt.Sprintf("正在移动 %d 个文件...", event.Total))
+		case EventFileMoved:
+			p.addFileLog(event, "已移动 "+event.Path+" -> "+event.Dst)
+		case EventFileSkipped:
+			p.addFileLog(event, "已跳过 "+event.Path+"（"+event.Message+"）")
+		case EventFileFailed:
+			p.addFileLog(event, "失败 "+event.Path+"（"+event.Message+"）")
+		case EventRunFinished:
+			if p.cancelled {
+				p.stage.SetText(fmt.Sprintf("已取消，移动了 %d 个文件，其余文件保持原样", event.Done))
+			} else {
+				p.stage.SetText(fmt.Sprintf("整理完成，移动了 %d 个文件", event.Done))
+				p.bar.SetValue(1)
+			}
+		}
+	})
+}
+
+// addFileLog 记录一个文件的处理结果并更新移动进度
+func (p *progressDialog) addFileLog(event ProgressEvent, text string) {
+	if event.Total > 0 {
+		p.bar.SetValue(float64(event.Done) / float64(event.Total))
+	}
+	p.addLog(text)
+}
+
+// addLog 在日志面板末尾添加一行并滚动到最新一行，需要在主线程中调用
+func (p *progressDialog) addLog(text string) {
+	p.logs = append(p.logs, time.Now().Format("15:04:05 ")+text)
+	if len(p.logs) > progressLogLimit {
+		p.logs = p.logs[len(p.logs)-progressLogLimit:]
+	}
+	p.list.Refresh()
+	p.list.ScrollToBottom()
+}
+
+// log 在任意协程中添加一行日志
+func (p *progressDialog) log(text string) {
+	fyne.Do(func() {
+		p.addLog(text)
+	})
+}
+
+// captureLogs 整理期间将警告和错误日志同时显示在日志面板中，返回恢复原设置的函数
+func (p *progressDialog) captureLogs() (restore func()) {
+	previous := slog.Default()
+	slog.SetDefault(slog.New(progressLogHandler{Handler: previous.Handler(), progress: p}))
+	return func() {
+		slog.SetDefault(previous)
+	}
+}
+
+// progressLogHandler 在原有日志处理之外，把警告和错误显示到进度对话框中
+type progressLogHandler struct {
+	slog.Handler
+	progress *progressDialog
+}
+
+func (h progressLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= slog.LevelWarn {
+		text := r.Message
+		r.Attrs(func(a slog.Attr) bool {
+			text += fmt.Sprintf(" %s=%s", a.Key, redactText(a.Value.String()))
+			return true
+		})
+		h.progress.log(text)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h progressLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return progressLogHandler{Handler: h.Handler.WithAttrs(attrs), progress: h.progress}
+}
+
+func (h progressLogHandler) WithGroup(name string) slog.Handler {
+	return progressLogHandler{Handler: h.Handler.WithGroup(name), progress: h.progress}
+}
+
+// finish 整理结束后将取消按钮换成关闭按钮，保留日志供查看
+func (p *progressDialog) finish() {
+	fyne.Do(func() {
+		p.dialog.SetButtons([]fyne.CanvasObject{widget.NewButton("关闭", p.dialog.Hide)})
+	})
+}
+
+// showCancelled 在移动前取消时显示结果
+func (p *progressDialog) showCancelled() {
+	fyne.Do(func() {
+		p.stage.SetText("已取消，没有移动任何文件")
+	})
+}
+
+// hide 出错时关闭对话框
+func (p *progressDialog) hide() {
+	fyne.Do(func() {
+		p.dialog.Hide()
+	})
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// settingsWindow 编辑配置的窗口：模型提供者、忽略规则和整理规则，保存前校验
+type settingsWindow struct {
+	window  fyne.Window
+	config  *Config
+	onSaved func(*Config)
+
+	names   []string // 已配置的提供者，按名称排序
+	current string   // 正在编辑的提供者
+
+	providerList  *widget.List
+	defaultSelect *widget.Select
+	removeButton  *widget.Button
+	urlEntry      *widget.Entry
+	modelEntry    *widget.Entry
+	keyEntry      *widget.Entry
+	secretEntry   *widget.Entry
+	keyHint       *widget.Label
+
+	templateEntry   *widget.Entry
+	duplicateSelect *widget.Select
+	conflictSelect  *widget.Select
+	symlinkSelect   *widget.Select
+	conflictRules   *widget.Entry // 每行一条：分类=策略
+	ignoreEntry     *widget.Entry // 每行一条，gitignore 格式
+}
+
+// showSettingsWindow 打开设置窗口，保存成功后调用 onSaved
+func showSettingsWindow(a fyne.App, parent fyne.Window, onSaved func(*Config)) {
+	config, err := LoadConfig()
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("加载配置失败: %v", err), parent)
+		return
+	}
+
+	s := &settingsWindow{
+		window:  a.NewWindow("设置"),
+		config:  config,
+		onSaved: onSaved,
+	}
+	tabs := container.NewAppTabs(
+		container.NewTabItem("模型", s.providersTab()),
+		container.NewTabItem("整理规则", s.rulesTab()),
+		container.NewTabItem("忽略规则", s.ignoreTab()),
+	)
+
+	path := config.Path()
+	if path == "" {
+		path, _ = userConfigPath()
+	}
+	saveButton := widget.NewButton("保存", s.save)
+	saveButton.Importance = widget.HighImportance
+	bottom := container.NewBorder(nil, nil, widget.NewLabel("配置文件: "+path), container.NewHBox(widget.NewButton("取消", s.window.Close), saveButton))
+
+	s.window.SetContent(container.NewPadded(container.NewBorder(nil, bottom, nil, nil, tabs)))
+	s.window.Resize(fyne.NewSize(720, 520))
+	s.window.CenterOnScreen()
+	s.window.Show()
+}
+
+// providersTab 左侧为提供者列表，右侧编辑选中的提供者
+func (s *settingsWindow) providersTab() fyne.CanvasObject {
+	s.refreshNames()
+
+	s.urlEntry = widget.NewEntry()
+	s.urlEntry.SetPlaceHolder("https://.../chat/completions")
+	s.modelEntry = widget.NewEntry()
+	s.keyEntry = widget.NewPasswordEntry()
+	s.secretEntry = widget.NewPasswordEntry()
+	s.secretEntry.SetPlaceHolder("仅阿里云需要")
+	s.keyHint = widget.NewLabel("")
+	s.keyHint.Wrapping = fyne.TextWrapWord
+
+	s.providerList = widget.NewList(
+		func() int { return len(s.names) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			name := s.names[id]
+			if name == s.config.DefaultProvider {
+				name += "（默认）"
+			}
+			item.(*widget.Label).SetText(name)
+		},
+	)
+	s.providerList.OnSelected = func(id widget.ListItemID) {
+		s.selectProvider(s.names[id])
+	}
+
+	s.defaultSelect = widget.NewSelect(s.names, func(name string) {
+		s.config.DefaultProvider = name
+		s.providerList.Refresh()
+	})
+	s.defaultSelect.SetSelected(s.config.DefaultProvider)
+
+	addButton := widget.NewButton("添加", s.addProvider)
+	s.removeButton = widget.NewButton("删除", s.removeProvider)
+	left := container.NewBorder(nil, container.NewHBox(addButton, s.removeButton), nil, nil, s.providerList)
+
+	form := widget.NewForm(
+		widget.NewFormItem("接口地址", s.urlEntry),
+		widget.NewFormItem("模型名称", s.modelEntry),
+		widget.NewFormItem("API 密钥", s.keyEntry),
+		widget.NewFormItem("API Secret", s.secretEntry),
+	)
+	right := container.NewVBox(
+		form,
+		s.keyHint,
+		container.NewHBox(widget.NewButton("测试连接", s.testConnection)),
+	)
+	top := container.NewBorder(nil, nil, widget.NewLabel("默认提供者："), nil, s.defaultSelect)
+
+	split := container.NewHSplit(left, container.NewVScroll(right))
+	split.Offset = 0.3
+	if len(s.names) > 0 {
+		s.providerList.Select(0)
+	} else {
+		s.selectProvider("")
+	}
+	return container.NewBorder(top, nil, nil, nil, split)
+}
+
+// refreshNames 按名称排序已配置的提供者
+func (s *settingsWindow) refreshNames() {
+	s.names = s.names[:0]
+	for name := range s.config.Providers {
+		s.names = append(s.names, name)
+	}
+	sort.Strings(s.names)
+}
+
+// selectProvider 保存正在编辑的提供者，切换到另一个提供者
+func (s *settingsWindow) selectProvider(name string) {
+	s.commitProvider()
+	s.current = name
+	pc := s.config.Providers[name]
+	s.urlEntry.SetText(pc.APIURL)
+	s.modelEntry.SetText(pc.ModelName)
+	s.keyEntry.SetText(pc.APIKey)
+	s.secretEntry.SetText(pc.APISecret)
+
+	s.keyHint.SetText("")
+	if pc.APIKey == "" && s.config.secretProviders[name] {
+		s.keyHint.SetText("密钥保存在加密密钥文件中，留空即可继续使用；填写后会以明文保存到配置文件（权限 0600）")
+	} else if name != "" {
+		s.keyHint.SetText(fmt.Sprintf("也可以留空，改用环境变量 FILECLASSIFY_%s_API_KEY 提供密钥", strings.ToUpper(name)))
+	}
+
+	for _, entry := range []*widget.Entry{s.urlEntry, s.modelEntry, s.keyEntry, s.secretEntry} {
+		if name == "" {
+			entry.Disable()
+		} else {
+			entry.Enable()
+		}
+	}
+	if name == "" {
+		s.removeButton.Disable()
+	} else {
+		s.removeButton.Enable()
+	}
+}
+
+// commitProvider 将表单中的值写回正在编辑的提供者
+func (s *settingsWindow) commitProvider() {
+	if _, ok := s.config.Providers[s.current]; !ok {
+		return
+	}
+	s.config.Providers[s.current] = s.formProvider()
+}
+
+// formProvider 返回表单中的提供者配置
+func (s *settingsWindow) formProvider() ProviderConfig {
+	return ProviderConfig{
+		APIURL:    strings.TrimSpace(s.urlEntry.Text),
+		ModelName: strings.TrimSpace(s.modelEntry.Text),
+		APIKey:    strings.TrimSpace(s.keyEntry.Text),
+		APISecret: strings.TrimSpace(s.secretEntry.Text),
+	}
+}
+
+// addProvider 从尚未配置的提供者中选择一个添加
+func (s *settingsWindow) addProvider() {
+	var available []string
+	for _, name := range supportedProviders {
+		if _, ok := s.config.Providers[name]; !ok {
+			available = append(available, name)
+		}
+	}
+	if len(available) == 0 {
+		dialog.ShowInformation("添加提供者", "支持的提供者都已配置", s.window)
+		return
+	}
+
+	choice := widget.NewSelect(available, nil)
+	choice.SetSelected(available[0])
+	dialog.ShowForm("添加提供者", "添加", "取消", []*widget.FormItem{widget.NewFormItem("提供者", choice)}, func(ok bool) {
+		if !ok || choice.Selected == "" {
+			return
+		}
+		// 内置默认配置中有接口地址的提供者沿用默认值
+		s.config.Providers[choice.Selected] = defaultConfig().Providers[choice.Selected]
+		s.refreshNames()
+		s.defaultSelect.Options = s.names
+		s.defaultSelect.Refresh()
+		s.providerList.Refresh()
+		for i, name := range s.names {
+			if name == choice.Selected {
+				s.providerList.Select(i)
+			}
+		}
+	}, s.window)
+}
+
+// removeProvider 删除正在编辑的提供者，默认提供者不能删除
+func (s *settingsWindow) removeProvider() {
+	name := s.current
+	if name == "" {
+		return
+	}
+	if name == s.config.DefaultProvider {
+		dialog.ShowInformation("删除提供者", "不能删除默认提供者，请先选择其他默认提供者", s.window)
+		return
+	}
+	dialog.ShowConfirm("删除提供者", fmt.Sprintf("确定删除 %s 的配置吗？", name), func(ok bool) {
+		if !ok {
+			return
+		}
+		delete(s.config.Providers, name)
+		s.current = ""
+		s.refreshNames()
+		s.defaultSelect.Options = s.names
+		s.defaultSelect.Refresh()
+		s.providerList.UnselectAll()
+		s.providerList.Refresh()
+		s.selectProvider("")
+	}, s.window)
+}
+
+// testConnection 用表单中的值发送一个最小的请求，不需要先保存
+func (s *settingsWindow) testConnection() {
+	if s.current == "" {
+		return
+	}
+	pc := s.formProvider()
+	if pc.APIKey == "" {
+		dialog.ShowInformation("测试连接", "请先填写 API 密钥", s.window)
+		return
+	}
+	pc.APIKey, pc.APIURL, pc.ModelName = expandEnvRefs(pc.APIKey), expandEnvRefs(pc.APIURL), expandEnvRefs(pc.ModelName)
+	registerLogSecret(pc.APIKey)
+
+	progress := dialog.NewCustomWithoutButtons("测试连接", widget.NewProgressBarInfinite(), s.window)
+	progress.Show()
+	name := s.current
+	go func() {
+		latency, err := pingProvider(pc)
+		fyne.Do(func() {
+			progress.Hide()
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("%s 连接失败: %v", name, err), s.window)
+				return
+			}
+			dialog.ShowInformation("测试连接", fmt.Sprintf("%s 连接正常，耗时 %d 毫秒", name, latency.Milliseconds()), s.window)
+		})
+	}()
+}
+
+// rulesTab 路径模板、重复文件、符号链接和目标冲突的处理规则
+func (s *settingsWindow) rulesTab() fyne.CanvasObject {
+	s.templateEntry = widget.NewEntry()
+	s.templateEntry.SetPlaceHolder(defaultPathTemplate)
+	s.templateEntry.SetText(s.config.PathTemplate)
+
+	s.duplicateSelect = widget.NewSelect([]string{DuplicatePolicyOff, DuplicatePolicyReport, DuplicatePolicyMove, DuplicatePolicyHardlink}, nil)
+	s.duplicateSelect.SetSelected(valueOrDefault(s.config.DuplicatePolicy, DuplicatePolicyOff))
+
+	s.symlinkSelect = widget.NewSelect([]string{SymlinkSkip, SymlinkMove, SymlinkFollow}, nil)
+	s.symlinkSelect.SetSelected(valueOrDefault(s.config.Scan.SymlinkPolicy, SymlinkSkip))
+
+	// 界面中不能逐个询问，不提供 ask
+	policies := []string{ConflictRename, ConflictSkip, ConflictOverwriteNewer, ConflictOverwrite, ConflictDedupe}
+	s.conflictSelect = widget.NewSelect(policies, nil)
+	s.conflictSelect.SetSelected(valueOrDefault(s.config.Conflict.Policy, ConflictRename))
+
+	var rules []string
+	for category, policy := range s.config.Conflict.Categories {
+		rules = append(rules, category+"="+policy)
+	}
+	sort.Strings(rules)
+	s.conflictRules = widget.NewMultiLineEntry()
+	s.conflictRules.SetPlaceHolder("照片=skip\n文档=overwrite_newer")
+	s.conflictRules.SetText(strings.Join(rules, "\n"))
+	s.conflictRules.SetMinRowsVisible(5)
+
+	form := widget.NewForm(
+		widget.NewFormItem("目标路径模板", s.templateEntry),
+		widget.NewFormItem("重复文件", s.duplicateSelect),
+		widget.NewFormItem("符号链接", s.symlinkSelect),
+		widget.NewFormItem("目标冲突", s.conflictSelect),
+		widget.NewFormItem("按分类处理冲突", s.conflictRules),
+	)
+	hint := widget.NewLabel("路径模板可用 {category}、{year}、{month}、{name}、{ext} 等变量；按分类处理冲突每行一条，格式为 分类=策略")
+	hint.Wrapping = fyne.TextWrapWord
+	return container.NewVScroll(container.NewVBox(form, hint))
+}
+
+// ignoreTab 全局忽略规则，每行一条
+func (s *settingsWindow) ignoreTab() fyne.CanvasObject {
+	s.ignoreEntry = widget.NewMultiLineEntry()
+	s.ignoreEntry.SetText(strings.Join(s.config.Scan.IgnorePatterns, "\n"))
+	hint := widget.NewLabel("每行一条，gitignore 格式，如 *.tmp、node_modules/、!keep.log；目录中的 .fileclassifyignore 文件同样生效")
+	hint.Wrapping = fyne.TextWrapWord
+	return container.NewBorder(hint, nil, nil, nil, s.ignoreEntry)
+}
+
+// valueOrDefault 配置未设置时显示默认值
+func valueOrDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// parseConflictRules 解析 分类=策略 形式的规则，每行一条
+func parseConflictRules(text string) (map[string]string, error) {
+	rules := make(map[string]string)
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		category, policy, ok := strings.Cut(line, "=")
+		category, policy = strings.TrimSpace(category), strings.TrimSpace(policy)
+		if !ok || category == "" || policy == "" {
+			return nil, fmt.Errorf("第 %d 行格式应为 分类=策略: %s", i+1, line)
+		}
+		rules[category] = policy
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return rules, nil
+}
+
+// splitLines 按行拆分，去掉空行和首尾空白
+func splitLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// validateSettings 校验设置；界面中不解密密钥文件，密钥保存在密钥文件中的提供者不检查密钥
+func validateSettings(c *Config) []ConfigIssue {
+	check := *c
+	check.secretsLoaded = true
+	var issues []ConfigIssue
+	for _, issue := range check.Validate() {
+		name, field, _ := strings.Cut(strings.TrimPrefix(issue.Field, "providers."), ".")
+		if strings.HasPrefix(issue.Field, "providers.") && field == "api_key" && c.secretProviders[name] && c.Providers[name].APIKey == "" {
+			continue
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// save 校验并保存配置，有错误时不保存
+func (s *settingsWindow) save() {
+	s.commitProvider()
+	rules, err := parseConflictRules(s.conflictRules.Text)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("按分类处理冲突: %v", err), s.window)
+		return
+	}
+
+	c := s.config
+	c.PathTemplate = strings.TrimSpace(s.templateEntry.Text)
+	c.DuplicatePolicy = s.duplicateSelect.Selected
+	c.Scan.SymlinkPolicy = s.symlinkSelect.Selected
+	c.Scan.IgnorePatterns = splitLines(s.ignoreEntry.Text)
+	c.Conflict.Policy = s.conflictSelect.Selected
+	c.Conflict.Categories = rules
+
+	issues := validateSettings(c)
+	if errs := configErrors(issues); len(errs) > 0 {
+		lines := make([]string, len(errs))
+		for i, issue := range errs {
+			lines[i] = issue.String()
+		}
+		dialog.ShowError(errors.New("配置有误，未保存：\n"+strings.Join(lines, "\n")), s.window)
+		return
+	}
+	if err := saveConfig(c); err != nil {
+		dialog.ShowError(fmt.Errorf("保存配置失败: %v", err), s.window)
+		return
+	}
+
+	if s.onSaved != nil {
+		s.onSaved(c)
+	}
+	if len(issues) == 0 {
+		s.window.Close()
+		return
+	}
+	// 只有警告时已经保存，列出警告供检查
+	lines := make([]string, len(issues))
+	for i, issue := range issues {
+		lines[i] = issue.String()
+	}
+	dialog.ShowInformation("已保存", "配置已保存，但有以下警告：\n"+strings.Join(lines, "\n"), s.window)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreFileName 忽略规则文件名，可以放在整理目录及其任意子目录中
+const ignoreFileName = ".fileclassifyignore"
+
+// defaultIgnorePatterns 默认的全局忽略规则：隐藏文件、锁文件和配置文件不发送给模型也不移动
+var defaultIgnorePatterns = []string{
+	".*",
+	"*.lock",
+	"*-lock.json",
+	"*.lck",
+	"~$*",
+	"config.json",
+}
+
+// ignoreRule 一条 gitignore 格式的规则
+type ignoreRule struct {
+	base    string // 规则文件所在目录，相对于扫描根目录，使用 / 分隔
+	pattern string
+	negate  bool
+	dirOnly bool
+	re      *regexp.Regexp
+}
+
+// IgnoreMatcher 按 gitignore 语义匹配路径，后面的规则优先
+type IgnoreMatcher struct {
+	rules []ignoreRule
+}
+
+// NewIgnoreMatcher 使用全局规则创建匹配器
+func NewIgnoreMatcher(patterns []string) (*IgnoreMatcher, error) {
+	rules, err := parseIgnoreLines("", patterns)
+	if err != nil {
+		return nil, err
+	}
+	return &IgnoreMatcher{rules: rules}, nil
+}
+
+// loadIgnoreMatcher 创建匹配器并加载目录下的忽略规则文件
+func loadIgnoreMatcher(root string, patterns []string) (*IgnoreMatcher, error) {
+	m, err := NewIgnoreMatcher(patterns)
+	if err != nil {
+		return nil, err
+	}
+	return m.withDir(root, "")
+}
+
+// withDir 读取目录中的忽略规则文件，返回追加了这些规则的新匹配器
+// 规则只作用于该目录及其子目录，文件不存在时返回原匹配器
+func (m *IgnoreMatcher) withDir(dir, relDir string) (*IgnoreMatcher, error) {
+	f, err := os.Open(filepath.Join(dir, ignoreFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return m, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return m, err
+	}
+
+	rules, err := parseIgnoreLines(filepath.ToSlash(relDir), lines)
+	if err != nil {
+		return m, fmt.Errorf("%s: %v", filepath.Join(relDir, ignoreFileName), err)
+	}
+	combined := make([]ignoreRule, 0, len(m.rules)+len(rules))
+	combined = append(combined, m.rules...)
+	combined = append(combined, rules...)
+	return &IgnoreMatcher{rules: combined}, nil
+}
+
+// Match 判断相对路径是否被忽略
+func (m *IgnoreMatcher) Match(relPath string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		path := relPath
+		if rule.base != "" {
+			if !strings.HasPrefix(path, rule.base+"/") {
+				continue
+			}
+			path = strings.TrimPrefix(path, rule.base+"/")
+		}
+		if rule.re.MatchString(path) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// parseIgnoreLines 解析 gitignore 格式的规则
+func parseIgnoreLines(base string, lines []string) ([]ignoreRule, error) {
+	var rules []ignoreRule
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		line = trimIgnoreTrailingSpaces(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: base, pattern: line}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// 开头或中间包含 / 的规则相对于规则文件所在目录，否则匹配任意层级的名称
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+
+		re, err := compileIgnorePattern(line, anchored)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行规则无效 %q: %v", i+1, rule.pattern, err)
+		}
+		rule.re = re
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// trimIgnoreTrailingSpaces 去掉行尾未转义的空格
+func trimIgnoreTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// compileIgnorePattern 将 gitignore 通配符转换为正则表达式
+func compileIgnorePattern(pattern string, anchored bool) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/") && (i == 0 || pattern[i-1] == '/'):
+			// 开头或中间的 **/ 匹配零个或多个目录
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case pattern[i:] == "**" && i > 0 && pattern[i-1] == '/':
+			// 结尾的 /** 匹配目录中的所有内容
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			for i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+			}
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end == -1 {
+				b.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	// 规则匹配目录时，目录下的内容同样被忽略
+	b.WriteString("(?:/.*)?$")
+	return regexp.Compile(b.String())
+}
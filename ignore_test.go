@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreMatcherMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{"扩展名", []string{"*.log"}, "a.log", false, true},
+		{"任意层级的扩展名", []string{"*.log"}, "x/y/a.log", false, true},
+		{"扩展名不匹配", []string{"*.log"}, "a.log.txt", false, false},
+		{"开头的 / 只匹配根目录", []string{"/build"}, "build", false, true},
+		{"开头的 / 不匹配子目录", []string{"/build"}, "src/build", false, false},
+		{"中间的 / 相对于根目录", []string{"docs/*.md"}, "docs/a.md", false, true},
+		{"* 不跨目录", []string{"docs/*.md"}, "docs/sub/a.md", false, false},
+		{"中间的 / 不匹配其他层级", []string{"docs/*.md"}, "x/docs/a.md", false, false},
+		{"开头的 **/", []string{"**/temp"}, "a/b/temp", false, true},
+		{"开头的 **/ 匹配根目录", []string{"**/temp"}, "temp", false, true},
+		{"中间的 **/ 匹配零层目录", []string{"a/**/b"}, "a/b", false, true},
+		{"中间的 **/ 匹配多层目录", []string{"a/**/b"}, "a/x/y/b", false, true},
+		{"结尾的 /** 匹配目录内容", []string{"logs/**"}, "logs/x/y.txt", false, true},
+		{"结尾的 /** 不匹配目录本身", []string{"logs/**"}, "logs", true, false},
+		{"目录规则匹配目录", []string{"build/"}, "x/build", true, true},
+		{"目录规则不匹配文件", []string{"build/"}, "build", false, false},
+		{"目录下的内容同样被忽略", []string{"node_modules"}, "node_modules/x/y.js", false, true},
+		{"取反", []string{"*.txt", "!keep.txt"}, "keep.txt", false, false},
+		{"取反只影响匹配的文件", []string{"*.txt", "!keep.txt"}, "a.txt", false, true},
+		{"后面的规则优先", []string{"!keep.txt", "*.txt"}, "keep.txt", false, true},
+		{"? 匹配一个字符", []string{"file?.txt"}, "file1.txt", false, true},
+		{"? 不匹配多个字符", []string{"file?.txt"}, "file12.txt", false, false},
+		{"字符类", []string{"[abc].txt"}, "b.txt", false, true},
+		{"字符类不匹配", []string{"[abc].txt"}, "d.txt", false, false},
+		{"取反的字符类", []string{"[!abc].txt"}, "d.txt", false, true},
+		{"转义的 #", []string{`\#notes`}, "#notes", false, true},
+		{"转义的 !", []string{`\!important`}, "!important", false, true},
+		{"注释", []string{"# a.txt"}, "# a.txt", false, false},
+		{"去掉行尾空格", []string{"trailing  "}, "trailing", false, true},
+		{"保留转义的行尾空格", []string{`name\ `}, "name ", false, true},
+		{"Windows 换行", []string{"*.bak\r"}, "a.bak", false, true},
+		{"默认规则忽略隐藏文件", defaultIgnorePatterns, "a/.hidden", false, true},
+		{"默认规则忽略 Office 临时文件", defaultIgnorePatterns, "~$报告.docx", false, true},
+		{"默认规则忽略锁文件", defaultIgnorePatterns, "web/package-lock.json", false, true},
+		{"默认规则不忽略普通文件", defaultIgnorePatterns, "照片/a.jpg", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewIgnoreMatcher(tt.patterns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.Match(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("%q 匹配 %q = %v，应为 %v", tt.patterns, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreMatcherNil(t *testing.T) {
+	var m *IgnoreMatcher
+	if m.Match("a.txt", false) {
+		t.Error("nil 匹配器不应忽略任何文件")
+	}
+}
+
+func TestIgnoreMatcherWithDir(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ignoreFileName), "*.tmp\n")
+	writeFile(t, filepath.Join(root, "sub", ignoreFileName), "/only\n!keep.tmp\n")
+
+	m, err := loadIgnoreMatcher(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, err := m.withDir(filepath.Join(root, "sub"), "sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		m    *IgnoreMatcher
+		path string
+		want bool
+	}{
+		{m, "a.tmp", true},
+		{m, "sub/only", false},
+		{sub, "sub/a.tmp", true},
+		{sub, "sub/only", true},
+		{sub, "sub/x/only", false},
+		{sub, "only", false},
+		{sub, "sub/keep.tmp", false},
+		{sub, "keep.tmp", true},
+	}
+	for _, tt := range tests {
+		if got := tt.m.Match(tt.path, false); got != tt.want {
+			t.Errorf("Match(%q) = %v，应为 %v", tt.path, got, tt.want)
+		}
+	}
+
+	// 没有规则文件的目录沿用原匹配器
+	if same, err := m.withDir(filepath.Join(root, "sub", "none"), "sub/none"); err != nil || same != m {
+		t.Errorf("没有规则文件时应返回原匹配器: %v", err)
+	}
+}
+
+func TestIgnoreMatcherInvalidPattern(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, ignoreFileName), []byte("ok.txt\n[z-a].txt\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := loadIgnoreMatcher(root, nil)
+	if err == nil || !strings.Contains(err.Error(), "第 2 行") {
+		t.Errorf("错误 = %v，应指出第 2 行", err)
+	}
+}
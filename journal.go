@@ -0,0 +1,460 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 日志中记录的操作类型
+const (
+	JournalMove   = "move"   // 文件或目录从 Src 移动到 Dst
+	JournalLink   = "link"   // 重复副本 Src 被删除，Dst 是指向保留文件的硬链接
+	JournalDedupe = "dedupe" // 目标 Dst 已有相同内容，Src 被删除
+)
+
+// JournalEntry 一次已完成的文件操作，路径均为完整路径
+type JournalEntry struct {
+	Action    string `json:"action"`
+	Src       string `json:"src"`
+	Dst       string `json:"dst"`
+	Category  string `json:"category,omitempty"`
+	IsDir     bool   `json:"is_dir,omitempty"`
+	Overwrote bool   `json:"overwrote,omitempty"` // 覆盖了已存在的目标，原目标无法恢复
+}
+
+// RunInfo 一次运行的基本信息
+type RunInfo struct {
+	ID       string `json:"id"`
+	Root     string `json:"root"`
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+}
+
+// journalRecord 日志文件中的一行
+type journalRecord struct {
+	Type  string        `json:"type"` // start、entry、finish、undo
+	Time  time.Time     `json:"time"`
+	Run   *RunInfo      `json:"run,omitempty"`
+	Entry *JournalEntry `json:"entry,omitempty"`
+}
+
+// RunJournal 一次运行的操作日志，用于撤销
+// 日志按行追加写入，运行中途退出时已完成的操作同样可以撤销
+type RunJournal struct {
+	RunInfo
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at,omitempty"`
+	UndoneAt   time.Time      `json:"undone_at,omitempty"`
+	Entries    []JournalEntry `json:"entries"`
+
+	path string
+	file *os.File
+}
+
+// journalDir 返回日志目录
+func journalDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "fileclassify", "journal"), nil
+}
+
+// StartRunJournal 创建新的运行日志，provider 和 model 为本次使用的模型，只用于显示
+func StartRunJournal(root, provider, model string) (*RunJournal, error) {
+	dir, err := journalDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("创建日志目录失败: %v", err)
+	}
+
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	j := &RunJournal{
+		RunInfo: RunInfo{
+			ID:       time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix),
+			Root:     root,
+			Provider: provider,
+			Model:    model,
+		},
+		StartedAt: time.Now(),
+	}
+	j.path = filepath.Join(dir, j.ID+".jsonl")
+
+	j.file, err = os.OpenFile(j.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("创建运行日志失败: %v", err)
+	}
+	if err := j.write(journalRecord{Type: "start", Time: j.StartedAt, Run: &j.RunInfo}); err != nil {
+		j.file.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+// write 追加一行记录并写入磁盘
+func (j *RunJournal) write(record journalRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入运行日志失败: %v", err)
+	}
+	return j.file.Sync()
+}
+
+// Add 记录一次已完成的操作，j 为nil时不记录
+func (j *RunJournal) Add(entry JournalEntry) error {
+	if j == nil {
+		return nil
+	}
+	j.Entries = append(j.Entries, entry)
+	return j.write(journalRecord{Type: "entry", Time: time.Now(), Entry: &entry})
+}
+
+// Finish 标记运行结束并关闭日志；没有任何操作的日志会被删除
+func (j *RunJournal) Finish() error {
+	if j == nil {
+		return nil
+	}
+	defer j.file.Close()
+	if len(j.Entries) == 0 {
+		return os.Remove(j.path)
+	}
+	j.FinishedAt = time.Now()
+	return j.write(journalRecord{Type: "finish", Time: j.FinishedAt})
+}
+
+// LoadRunJournal 读取运行日志
+func LoadRunJournal(path string) (*RunJournal, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	j := &RunJournal{path: path}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var record journalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// 最后一行可能在写入时中断
+			continue
+		}
+		switch record.Type {
+		case "start":
+			if record.Run != nil {
+				j.RunInfo = *record.Run
+			}
+			j.StartedAt = record.Time
+		case "entry":
+			if record.Entry != nil {
+				j.Entries = append(j.Entries, *record.Entry)
+			}
+		case "finish":
+			j.FinishedAt = record.Time
+		case "undo":
+			j.UndoneAt = record.Time
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if j.ID == "" {
+		j.ID = strings.TrimSuffix(filepath.Base(path), ".jsonl")
+	}
+	return j, nil
+}
+
+// ListRunJournals 返回所有运行日志，最近的在前
+func ListRunJournals() ([]*RunJournal, error) {
+	dir, err := journalDir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	var journals []*RunJournal
+	for _, path := range paths {
+		j, err := LoadRunJournal(path)
+		if err != nil {
+			continue
+		}
+		journals = append(journals, j)
+	}
+	sort.Slice(journals, func(a, b int) bool {
+		return journals[a].StartedAt.After(journals[b].StartedAt)
+	})
+	return journals, nil
+}
+
+// findRunJournal 按编号查找运行日志；编号为空时返回指定目录（为空则不限）最近一次未撤销的运行
+func findRunJournal(id, root string) (*RunJournal, error) {
+	journals, err := ListRunJournals()
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range journals {
+		if id != "" {
+			if j.ID == id {
+				return j, nil
+			}
+			continue
+		}
+		if j.UndoneAt.IsZero() && (root == "" || j.Root == root) {
+			return j, nil
+		}
+	}
+	if id != "" {
+		return nil, fmt.Errorf("找不到运行记录: %s", id)
+	}
+	return nil, fmt.Errorf("没有可以撤销的运行记录")
+}
+
+// Undo 按相反顺序撤销日志中的操作，dryRun 为 true 时只把将要执行的操作写到 preview（可以为nil）
+// 已经恢复过的操作会被跳过，因此撤销中途失败后可以再次执行；所有操作都恢复后才标记为已撤销
+func (j *RunJournal) Undo(dryRun bool, preview io.Writer) (int, []string) {
+	var (
+		restored   int
+		failures   []string
+		incomplete bool // 有操作没有恢复，之后还需要再次撤销
+		undone     []JournalEntry
+	)
+	for _, entry := range j.undoOrder() {
+		relSrc, relDst := j.rel(entry.Src), j.rel(entry.Dst)
+		nested := entry.Action == JournalMove && entry.IsDir && isNestedIn(entry.Dst, entry.Src)
+
+		_, srcErr := os.Lstat(entry.Src)
+		_, dstErr := os.Lstat(entry.Dst)
+		if srcErr == nil && (entry.Action != JournalMove || os.IsNotExist(dstErr)) {
+			// 原位置已有文件，说明已经恢复过
+			continue
+		}
+		if os.IsNotExist(dstErr) {
+			failures = append(failures, fmt.Sprintf("%s 已不存在，无法恢复 %s", relDst, relSrc))
+			incomplete = true
+			continue
+		}
+		// 目录被归入同名分类时原位置是分类目录本身，恢复时再检查其中是否还有其他文件
+		if srcErr == nil && !nested {
+			failures = append(failures, fmt.Sprintf("原位置 %s 已被占用", relSrc))
+			incomplete = true
+			continue
+		}
+
+		if dryRun {
+			if preview != nil {
+				fmt.Fprintf(preview, "  %s -> %s\n", relDst, relSrc)
+			}
+			restored++
+			continue
+		}
+
+		if err := j.undoEntry(entry); err != nil {
+			failures = append(failures, fmt.Sprintf("恢复 %s 失败: %v", relSrc, err))
+			incomplete = true
+			continue
+		}
+		// 被覆盖的原文件无论如何都无法恢复，只提示，不影响撤销完成
+		if entry.Overwrote {
+			failures = append(failures, fmt.Sprintf("%s 已恢复，但被它覆盖的原文件 %s 无法恢复", relSrc, relDst))
+		}
+		undone = append(undone, entry)
+		restored++
+	}
+
+	if dryRun {
+		return restored, failures
+	}
+
+	// 从整理状态中去掉已撤销的文件，否则增量模式会一直跳过它们
+	if state, err := LoadOrganizeState(j.Root); err != nil {
+		slog.Warn("读取整理状态失败", "root", j.Root, "error", err)
+	} else {
+		for _, entry := range undone {
+			if entry.Action == JournalDedupe {
+				continue
+			}
+			if rel, err := filepath.Rel(j.Root, entry.Dst); err == nil {
+				delete(state.Files, filepath.ToSlash(rel))
+			}
+		}
+		if err := state.Save(); err != nil {
+			slog.Warn("保存整理状态失败", "root", j.Root, "error", err)
+		}
+	}
+
+	if !incomplete {
+		j.markUndone()
+	}
+	return restored, failures
+}
+
+// undoOrder 返回撤销操作的顺序：按相反顺序撤销，归入同名分类的目录放在最后，
+// 这时同一分类中的其他文件已经移回原处，分类目录才能被删除
+func (j *RunJournal) undoOrder() []JournalEntry {
+	var order, nested []JournalEntry
+	for i := len(j.Entries) - 1; i >= 0; i-- {
+		entry := j.Entries[i]
+		if entry.Action == JournalMove && entry.IsDir && isNestedIn(entry.Dst, entry.Src) {
+			nested = append(nested, entry)
+			continue
+		}
+		order = append(order, entry)
+	}
+	return append(order, nested...)
+}
+
+// undoEntry 恢复单个操作
+func (j *RunJournal) undoEntry(entry JournalEntry) error {
+	if err := os.MkdirAll(filepath.Dir(entry.Src), 0755); err != nil {
+		return err
+	}
+
+	switch entry.Action {
+	case JournalMove:
+		if entry.IsDir && isNestedIn(entry.Dst, entry.Src) {
+			return j.undoNestedDir(entry)
+		}
+		var err error
+		if entry.IsDir {
+			err = moveDir(entry.Dst, entry.Src)
+		} else {
+			err = moveFile(entry.Dst, entry.Src)
+		}
+		if err != nil {
+			return err
+		}
+		removeEmptyParents(filepath.Dir(entry.Dst), j.Root)
+
+	case JournalLink:
+		// 复制内容而不是移动链接，恢复为独立的文件
+		if err := copyFileVerified(entry.Dst, entry.Src); err != nil {
+			return err
+		}
+		if err := os.Remove(entry.Dst); err != nil {
+			return err
+		}
+		removeEmptyParents(filepath.Dir(entry.Dst), j.Root)
+
+	case JournalDedupe:
+		return copyFileVerified(entry.Dst, entry.Src)
+
+	default:
+		return fmt.Errorf("未知的操作类型: %s", entry.Action)
+	}
+	return nil
+}
+
+// undoNestedDir 恢复被归入同名分类的目录，如 photos/photos 恢复为 photos
+// 先把目录移到临时名称，删除已经空了的分类目录后再改回原名；分类中还有其他文件时保持原样
+func (j *RunJournal) undoNestedDir(entry JournalEntry) error {
+	parent := filepath.Dir(entry.Src)
+	rel, err := filepath.Rel(parent, filepath.Dir(entry.Dst))
+	if err != nil || !isRealDirPath(parent, rel) {
+		return fmt.Errorf("原位置 %s 不是目录", j.rel(entry.Src))
+	}
+
+	tmp := entry.Src + ".fileclassify-undo"
+	if err := os.Rename(entry.Dst, tmp); err != nil {
+		return err
+	}
+	for dir := filepath.Dir(entry.Dst); ; dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			os.MkdirAll(filepath.Dir(entry.Dst), 0755)
+			os.Rename(tmp, entry.Dst)
+			return fmt.Errorf("原位置 %s 中还有其他文件", j.rel(entry.Src))
+		}
+		if dir == entry.Src {
+			break
+		}
+	}
+	if err := os.Rename(tmp, entry.Src); err != nil {
+		return fmt.Errorf("目录已移到 %s，但改回原名失败: %v", j.rel(tmp), err)
+	}
+	return nil
+}
+
+// isNestedIn 判断 path 是否位于目录 dir 内部
+func isNestedIn(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && !strings.HasPrefix(rel, "..")
+}
+
+// markUndone 在日志中追加撤销记录
+func (j *RunJournal) markUndone() {
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	// 运行中途退出时最后一行可能不完整，先换行，否则撤销记录会和它连在一起无法解析
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if r, err := os.Open(j.path); err == nil {
+			if _, err := r.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+				f.WriteString("\n")
+			}
+			r.Close()
+		}
+	}
+	j.file = f
+	j.UndoneAt = time.Now()
+	j.write(journalRecord{Type: "undo", Time: j.UndoneAt})
+}
+
+func (j *RunJournal) rel(path string) string {
+	if rel, err := filepath.Rel(j.Root, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// removeEmptyParents 从 dir 开始向上删除空目录，直到整理目录为止
+// 符号链接和经过符号链接到达的目录属于链接目标，不会被删除
+func removeEmptyParents(dir, root string) {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") || !isRealDirPath(root, rel) {
+		return
+	}
+	for {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			return
+		}
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// isRealDirPath 判断 root 下的相对路径 rel 的每一级都是真实的目录而不是符号链接
+func isRealDirPath(root, rel string) bool {
+	path := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		path = filepath.Join(path, part)
+		info, err := os.Lstat(path)
+		if err != nil || !info.IsDir() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRemoveEmptyParents(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, root, outside string) string // 返回开始删除的目录
+		removed []string                                        // 应被删除的路径，相对于 root
+		kept    []string                                        // 应保留的路径，以 outside/ 开头的相对于链接目标所在目录
+	}{
+		{
+			name: "空目录逐级删除到整理目录为止",
+			setup: func(t *testing.T, root, outside string) string {
+				mkdirAll(t, filepath.Join(root, "a", "b", "c"))
+				return filepath.Join(root, "a", "b", "c")
+			},
+			removed: []string{"a/b/c", "a/b", "a"},
+			kept:    []string{"."},
+		},
+		{
+			name: "遇到非空目录停止",
+			setup: func(t *testing.T, root, outside string) string {
+				mkdirAll(t, filepath.Join(root, "a", "b"))
+				writeFile(t, filepath.Join(root, "a", "keep.txt"), "x")
+				return filepath.Join(root, "a", "b")
+			},
+			removed: []string{"a/b"},
+			kept:    []string{"a", "a/keep.txt"},
+		},
+		{
+			name: "不删除指向空目录的符号链接",
+			setup: func(t *testing.T, root, outside string) string {
+				mkdirAll(t, filepath.Join(outside, "empty"))
+				symlink(t, filepath.Join(outside, "empty"), filepath.Join(root, "link"))
+				return filepath.Join(root, "link")
+			},
+			kept: []string{"link", "outside/empty"},
+		},
+		{
+			name: "不删除经过符号链接到达的目录",
+			setup: func(t *testing.T, root, outside string) string {
+				mkdirAll(t, filepath.Join(outside, "target", "sub"))
+				symlink(t, filepath.Join(outside, "target"), filepath.Join(root, "link"))
+				return filepath.Join(root, "link", "sub")
+			},
+			kept: []string{"link", "outside/target/sub"},
+		},
+		{
+			name: "整理目录之外的路径不处理",
+			setup: func(t *testing.T, root, outside string) string {
+				mkdirAll(t, filepath.Join(outside, "other"))
+				return filepath.Join(outside, "other")
+			},
+			kept: []string{"outside/other"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := t.TempDir()
+			root, outside := filepath.Join(base, "root"), filepath.Join(base, "outside")
+			mkdirAll(t, root)
+			mkdirAll(t, outside)
+
+			removeEmptyParents(tt.setup(t, root, outside), root)
+
+			for _, rel := range tt.removed {
+				if _, err := os.Lstat(filepath.Join(root, filepath.FromSlash(rel))); !os.IsNotExist(err) {
+					t.Errorf("%s 应被删除", rel)
+				}
+			}
+			for _, rel := range tt.kept {
+				path := filepath.Join(root, filepath.FromSlash(rel))
+				if rest, ok := strings.CutPrefix(rel, "outside/"); ok {
+					path = filepath.Join(outside, filepath.FromSlash(rest))
+				}
+				if _, err := os.Lstat(path); err != nil {
+					t.Errorf("%s 应保留: %v", rel, err)
+				}
+			}
+		})
+	}
+}
+
+func TestUndo(t *testing.T) {
+	type move struct {
+		src, dst string
+		dir      bool
+	}
+	tests := []struct {
+		name       string
+		files      []string                        // 整理前的文件
+		moves      []move                          // 按顺序执行并记录到日志的移动
+		before     func(t *testing.T, root string) // 撤销前对目录的修改
+		fix        func(t *testing.T, root string) // 第一次撤销后的修复，之后再次撤销
+		want       []string                        // 撤销后应存在的文件
+		gone       []string                        // 撤销后应不存在的路径
+		failures   int                             // 第一次撤销的失败数
+		wantUndone bool                            // 最终是否标记为已撤销
+	}{
+		{
+			name:       "恢复移动的文件并删除空分类",
+			files:      []string{"a.txt", "b.jpg"},
+			moves:      []move{{src: "a.txt", dst: "文档/a.txt"}, {src: "b.jpg", dst: "图片/b.jpg"}},
+			want:       []string{"a.txt", "b.jpg"},
+			gone:       []string{"文档", "图片"},
+			wantUndone: true,
+		},
+		{
+			name:     "部分失败时不标记为已撤销，修复后可以再次撤销",
+			files:    []string{"a.txt", "b.txt"},
+			moves:    []move{{src: "a.txt", dst: "文档/a.txt"}, {src: "b.txt", dst: "文档/b.txt"}},
+			before:   func(t *testing.T, root string) { writeFile(t, filepath.Join(root, "a.txt"), "新文件") },
+			failures: 1,
+			fix: func(t *testing.T, root string) {
+				if err := os.Remove(filepath.Join(root, "a.txt")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want:       []string{"a.txt", "b.txt"},
+			gone:       []string{"文档"},
+			wantUndone: true,
+		},
+		{
+			name:     "目标已被删除时失败",
+			files:    []string{"a.txt"},
+			moves:    []move{{src: "a.txt", dst: "文档/a.txt"}},
+			before:   func(t *testing.T, root string) { os.Remove(filepath.Join(root, "文档", "a.txt")) },
+			failures: 1,
+			gone:     []string{"a.txt"},
+		},
+		{
+			name:       "目录归入同名分类",
+			files:      []string{"photos/1.jpg"},
+			moves:      []move{{src: "photos", dst: "photos/photos", dir: true}},
+			want:       []string{"photos/1.jpg"},
+			gone:       []string{"photos/photos"},
+			wantUndone: true,
+		},
+		{
+			name:       "同名分类中还有其他文件",
+			files:      []string{"photos/1.jpg", "2.jpg"},
+			moves:      []move{{src: "photos", dst: "photos/photos", dir: true}, {src: "2.jpg", dst: "photos/2.jpg"}},
+			want:       []string{"photos/1.jpg", "2.jpg"},
+			gone:       []string{"photos/photos", "photos/2.jpg"},
+			wantUndone: true,
+		},
+		{
+			name:     "同名分类中有不是本次移入的文件时保持原样",
+			files:    []string{"photos/1.jpg"},
+			moves:    []move{{src: "photos", dst: "photos/photos", dir: true}},
+			before:   func(t *testing.T, root string) { writeFile(t, filepath.Join(root, "photos", "new.jpg"), "x") },
+			failures: 1,
+			want:     []string{"photos/photos/1.jpg", "photos/new.jpg"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := t.TempDir()
+			t.Setenv("HOME", base)
+			t.Setenv("XDG_CONFIG_HOME", filepath.Join(base, "config"))
+			root := filepath.Join(base, "root")
+			for _, file := range tt.files {
+				writeFile(t, filepath.Join(root, filepath.FromSlash(file)), file)
+			}
+
+			journal, err := StartRunJournal(root, "test", "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, m := range tt.moves {
+				src, dst := filepath.Join(root, filepath.FromSlash(m.src)), filepath.Join(root, filepath.FromSlash(m.dst))
+				mkdirAll(t, filepath.Dir(dst))
+				move := moveFile
+				if m.dir {
+					move = moveDir
+				}
+				if err := move(src, dst); err != nil {
+					t.Fatal(err)
+				}
+				if err := journal.Add(JournalEntry{Action: JournalMove, Src: src, Dst: dst, IsDir: m.dir}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := journal.Finish(); err != nil {
+				t.Fatal(err)
+			}
+			if tt.before != nil {
+				tt.before(t, root)
+			}
+
+			if _, failures := journal.Undo(false, nil); len(failures) != tt.failures {
+				t.Fatalf("失败数 = %d，应为 %d: %v", len(failures), tt.failures, failures)
+			}
+			if tt.fix != nil {
+				if !journal.UndoneAt.IsZero() {
+					t.Fatal("部分失败时不应标记为已撤销")
+				}
+				tt.fix(t, root)
+				if _, failures := journal.Undo(false, nil); len(failures) != 0 {
+					t.Fatalf("再次撤销失败: %v", failures)
+				}
+			}
+
+			for _, rel := range tt.want {
+				if _, err := os.Lstat(filepath.Join(root, filepath.FromSlash(rel))); err != nil {
+					t.Errorf("%s 应存在: %v", rel, err)
+				}
+			}
+			for _, rel := range tt.gone {
+				if _, err := os.Lstat(filepath.Join(root, filepath.FromSlash(rel))); !os.IsNotExist(err) {
+					t.Errorf("%s 不应存在", rel)
+				}
+			}
+			reloaded, err := LoadRunJournal(journal.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if undone := !reloaded.UndoneAt.IsZero(); undone != tt.wantUndone {
+				t.Errorf("已撤销 = %v，应为 %v", undone, tt.wantUndone)
+			}
+		})
+	}
+}
+
+func TestLoadRunJournal(t *testing.T) {
+	base := t.TempDir()
+	t.Setenv("HOME", base)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(base, "config"))
+	root := filepath.Join(base, "root")
+
+	journal, err := StartRunJournal(root, "deepseek", "deepseek-chat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []JournalEntry{
+		{Action: JournalMove, Src: filepath.Join(root, "a.txt"), Dst: filepath.Join(root, "文档", "a.txt"), Category: "文档"},
+		{Action: JournalDedupe, Src: filepath.Join(root, "b.txt"), Dst: filepath.Join(root, "文档", "a.txt")},
+	}
+	for _, entry := range entries {
+		if err := journal.Add(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 运行中途退出：没有 finish 记录，最后一行只写了一半
+	journal.file.WriteString(`{"type":"entry","entry":{"action":"mo`)
+	journal.file.Close()
+
+	loaded, err := LoadRunJournal(journal.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.ID != journal.ID || loaded.Root != root || loaded.Model != "deepseek-chat" {
+		t.Errorf("运行信息 = %+v", loaded.RunInfo)
+	}
+	if !reflect.DeepEqual(loaded.Entries, entries) {
+		t.Errorf("Entries = %+v，应为 %+v", loaded.Entries, entries)
+	}
+	if !loaded.FinishedAt.IsZero() || !loaded.UndoneAt.IsZero() {
+		t.Error("中途退出的运行不应有结束或撤销时间")
+	}
+
+	// 不指定编号时返回该目录最近一次未撤销的运行
+	tests := []struct {
+		id, root string
+		want     string // 为空表示应返回错误
+	}{
+		{"", root, journal.ID},
+		{"", "", journal.ID},
+		{journal.ID, "", journal.ID},
+		{"", filepath.Join(base, "other"), ""},
+		{"20000101-000000-000000", "", ""},
+	}
+	for _, tt := range tests {
+		found, err := findRunJournal(tt.id, tt.root)
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("findRunJournal(%q, %q) 应返回错误", tt.id, tt.root)
+		case tt.want != "" && (err != nil || found.ID != tt.want):
+			t.Errorf("findRunJournal(%q, %q) = %v, %v", tt.id, tt.root, found, err)
+		}
+	}
+
+	loaded.markUndone()
+	if _, err := findRunJournal("", root); err == nil {
+		t.Error("已撤销的运行不应再被选中")
+	}
+}
+
+func mkdirAll(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	mkdirAll(t, filepath.Dir(path))
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func symlink(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("无法创建符号链接: %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LogConfig 定义日志的级别、位置和格式
+type LogConfig struct {
+	Level       string `json:"level,omitempty"`        // 日志级别：debug、info、warn（默认）、error
+	File        string `json:"file,omitempty"`         // 日志文件，为空时输出到标准错误
+	Format      string `json:"format,omitempty"`       // 日志格式：text（默认）或 json
+	RedactPaths bool   `json:"redact_paths,omitempty"` // 将日志中的文件路径替换为哈希，模型的请求和响应内容只记录长度
+}
+
+// 日志格式
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// logLevels 支持的日志级别
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// redactedValue 替换敏感信息的文本
+const redactedValue = "[已隐藏]"
+
+var (
+	logMu      sync.Mutex
+	logOutput  *os.File // 当前打开的日志文件，输出到标准错误时为nil
+	logSecrets []string // 已加载的密钥，出现在日志中时会被隐藏
+)
+
+// secretKeyPattern 匹配日志中按名称判断为敏感信息的字段
+var secretKeyPattern = regexp.MustCompile(`(?i)(api_?key|api_?secret|authorization|password|passphrase|token$)`)
+
+// secretValuePatterns 匹配日志文本中常见格式的密钥
+var secretValuePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(bearer\s+)[^\s"',]+`),
+	regexp.MustCompile(`(?i)("?(?:api_?key|api_?secret|authorization|password)"?\s*[:=]\s*"?(?:bearer\s+)?)[^\s"',]+`),
+	regexp.MustCompile(`\b(sk-)[A-Za-z0-9_\-]{8,}`),
+	regexp.MustCompile(`\b(gh[pousr]_)[A-Za-z0-9]{16,}`),
+}
+
+// pathLogKeys 值为文件路径的日志字段，开启 redact_paths 时替换为哈希
+var pathLogKeys = map[string]bool{"path": true, "src": true, "dst": true, "root": true, "dir": true}
+
+// errorPathPattern 匹配错误信息中的绝对路径，前面必须是开头、空白、引号或括号，避免误伤 URL
+var errorPathPattern = regexp.MustCompile(`(^|[\s"'(\[=])((?:/|[A-Za-z]:\\)[^\s"'()\[\]:,;]+)`)
+
+// contentLogKeys 值为模型请求或响应内容的日志字段，其中包含文件名，开启 redact_paths 时只记录长度
+var contentLogKeys = map[string]bool{"prompt": true, "response": true, "content": true}
+
+// Validate 检查日志配置是否有效
+func (l LogConfig) Validate() error {
+	if _, ok := logLevels[strings.ToLower(l.Level)]; l.Level != "" && !ok {
+		return fmt.Errorf("不支持的日志级别: %s（可选 debug、info、warn、error）", l.Level)
+	}
+	switch l.Format {
+	case "", logFormatText, logFormatJSON:
+	default:
+		return fmt.Errorf("不支持的日志格式: %s（可选 text、json）", l.Format)
+	}
+	return nil
+}
+
+// setupLogging 按配置替换默认日志，再次调用时关闭之前打开的日志文件
+func setupLogging(l LogConfig) error {
+	if err := l.Validate(); err != nil {
+		return err
+	}
+	level := slog.LevelWarn
+	if l.Level != "" {
+		level = logLevels[strings.ToLower(l.Level)]
+	}
+
+	var w io.Writer = os.Stderr
+	var file *os.File
+	if l.File != "" {
+		path := expandHome(expandEnvRefs(l.File))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return fmt.Errorf("创建日志目录失败: %v", err)
+		}
+		// 日志中可能有文件名和模型响应，只允许当前用户读取
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("打开日志文件失败: %v", err)
+		}
+		w, file = f, f
+	}
+
+	opts := &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			return redactAttr(a, l.RedactPaths)
+		},
+	}
+	var handler slog.Handler
+	if l.Format == logFormatJSON {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+
+	logMu.Lock()
+	if logOutput != nil {
+		logOutput.Close()
+	}
+	logOutput = file
+	logMu.Unlock()
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// registerLogSecret 登记一个密钥，之后的日志中出现时会被隐藏
+func registerLogSecret(secret string) {
+	// 过短的值隐藏后会误伤正常文本
+	if len(secret) < 6 || isPlaceholderKey(secret) {
+		return
+	}
+	logMu.Lock()
+	defer logMu.Unlock()
+	for _, s := range logSecrets {
+		if s == secret {
+			return
+		}
+	}
+	logSecrets = append(logSecrets, secret)
+	// 长的先替换，避免一个密钥是另一个的前缀时只隐藏一部分
+	sort.Slice(logSecrets, func(i, j int) bool { return len(logSecrets[i]) > len(logSecrets[j]) })
+}
+
+// redactText 隐藏文本中已登记的密钥和常见格式的密钥
+func redactText(s string) string {
+	logMu.Lock()
+	secrets := logSecrets
+	logMu.Unlock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redactedValue)
+	}
+	for _, pattern := range secretValuePatterns {
+		s = pattern.ReplaceAllString(s, "${1}"+redactedValue)
+	}
+	return s
+}
+
+// redactAttr 处理一个日志字段：按名称隐藏密钥，文本中的密钥替换掉，按需隐藏路径
+func redactAttr(a slog.Attr, redactPaths bool) slog.Attr {
+	if a.Key == slog.TimeKey || a.Key == slog.LevelKey {
+		return a
+	}
+	if a.Key != slog.MessageKey && secretKeyPattern.MatchString(a.Key) {
+		return slog.String(a.Key, redactedValue)
+	}
+
+	var (
+		text   string
+		errVal error
+	)
+	switch a.Value.Kind() {
+	case slog.KindString:
+		text = a.Value.String()
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case error:
+			text, errVal = v.Error(), v
+		case fmt.Stringer:
+			text = v.String()
+		case []byte:
+			text = string(v)
+		default:
+			// 结构体和 map 中也可能有密钥，统一转为文本后处理
+			text = fmt.Sprint(v)
+		}
+	default:
+		return a
+	}
+
+	if redactPaths {
+		if pathLogKeys[a.Key] {
+			return slog.String(a.Key, redactPath(text))
+		}
+		if contentLogKeys[a.Key] {
+			return slog.String(a.Key, fmt.Sprintf("[%d 字节]", len(text)))
+		}
+		if errVal != nil || a.Key == "error" {
+			text = redactErrorPaths(text, errVal)
+		}
+	}
+	return slog.String(a.Key, redactText(text))
+}
+
+// redactErrorPaths 隐藏错误信息中的文件路径
+// os 返回的错误按其中记录的路径替换，包括相对路径和含空格的路径；其余的绝对路径按格式匹配
+func redactErrorPaths(text string, err error) string {
+	var paths []string
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		paths = append(paths, pathErr.Path)
+	}
+	var linkErr *os.LinkError
+	if errors.As(err, &linkErr) {
+		paths = append(paths, linkErr.Old, linkErr.New)
+	}
+	for _, path := range paths {
+		if path != "" {
+			text = strings.ReplaceAll(text, path, redactPath(path))
+		}
+	}
+	return errorPathPattern.ReplaceAllStringFunc(text, func(match string) string {
+		m := errorPathPattern.FindStringSubmatch(match)
+		return m[1] + redactPath(m[2])
+	})
+}
+
+// redactPath 将路径替换为哈希，保留扩展名，同一路径在日志中的哈希相同
+func redactPath(path string) string {
+	sum := sha256.Sum256([]byte(filepath.ToSlash(path)))
+	return "path:" + hex.EncodeToString(sum[:4]) + filepath.Ext(path)
+}
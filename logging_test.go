@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func TestRedactAttr(t *testing.T) {
+	home := "/home/u/照片/a b.jpg"
+	tests := []struct {
+		name        string
+		attr        slog.Attr
+		redactPaths bool
+		want        string
+	}{
+		{"路径字段", slog.String("path", "照片/a.jpg"), true, redactPath("照片/a.jpg")},
+		{"不隐藏路径时保持原样", slog.String("path", "照片/a.jpg"), false, "照片/a.jpg"},
+		{"请求内容只记录长度", slog.String("prompt", "12345"), true, "[5 字节]"},
+		{"按名称隐藏密钥", slog.String("api_key", "sk-123"), false, redactedValue},
+		{
+			"os 错误中含空格的路径",
+			slog.Any("error", &fs.PathError{Op: "open", Path: home, Err: fs.ErrPermission}),
+			true, "open " + redactPath(home) + ": permission denied",
+		},
+		{
+			"包装后的重命名错误",
+			slog.Any("error", fmt.Errorf("移动失败: %w", &os.LinkError{Op: "rename", Old: "/a/x.txt", New: "/b/x.txt", Err: fs.ErrExist})),
+			true, "移动失败: rename " + redactPath("/a/x.txt") + " " + redactPath("/b/x.txt") + ": file already exists",
+		},
+		{
+			"文本错误中的绝对路径",
+			slog.String("error", "读取 /srv/data/报告.pdf: 失败"),
+			true, "读取 " + redactPath("/srv/data/报告.pdf") + ": 失败",
+		},
+		{
+			"Windows 路径",
+			slog.Any("err", errors.New(`open "C:\Users\u\a.txt" failed`)),
+			true, `open "` + redactPath(`C:\Users\u\a.txt`) + `" failed`,
+		},
+		{
+			"URL 不是路径",
+			slog.Any("error", errors.New(`Post "https://api.deepseek.com/v1/chat/completions": timeout`)),
+			true, `Post "https://api.deepseek.com/v1/chat/completions": timeout`,
+		},
+		{
+			"不隐藏路径时错误保持原样",
+			slog.Any("error", &fs.PathError{Op: "open", Path: home, Err: fs.ErrNotExist}),
+			false, "open " + home + ": file does not exist",
+		},
+		{"错误中的密钥", slog.Any("error", errors.New("Authorization: Bearer abcdef123456")), true, "Authorization: Bearer " + redactedValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactAttr(tt.attr, tt.redactPaths).Value.String(); got != tt.want {
+				t.Errorf("redactAttr() = %q，应为 %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,175 +1,226 @@
 package main
 
 import (
-	"bufio"
-	"flag"
+	"context"
 	"fmt"
-	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
-	"strings"
+	"time"
 )
 
 // FileInfo 定义文件信息结构
 type FileInfo struct {
-	Path     string
-	Category string
-}
-
-// getFileList 获取指定目录下的所有文件列表
-func getFileList(root string) ([]FileInfo, error) {
-	var files []FileInfo
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			// 获取相对路径
-			relPath, err := filepath.Rel(root, path)
-			if err != nil {
-				return err
-			}
-			files = append(files, FileInfo{
-				Path: relPath,
-			})
-		}
-		return nil
-	})
-	return files, err
+	Path      string    `json:"path"`
+	Category  string    `json:"category,omitempty"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mod_time"`
+	Hash      string    `json:"hash,omitempty"`       // 内容哈希，仅在需要时计算
+	IsSymlink bool      `json:"is_symlink,omitempty"` // 符号链接本身，移动时不读取链接目标
+	IsDir     bool      `json:"is_dir,omitempty"`     // 作为整体移动的目录
 }
 
 func main() {
-	// 定义命令行参数
-	providerType := flag.String("provider", "", "指定使用的大模型类型 (deepseek, siliconflow, aliyun, github)")
-	flag.Parse()
-
-	// 加载配置
-	config, err := LoadConfig()
-	if err != nil {
-		fmt.Printf("加载配置失败: %v\n", err)
-		return
-	}
+	os.Exit(runCLI(os.Args[1:]))
+}
 
+// newProviderFromConfig 根据配置创建大模型提供者
+func newProviderFromConfig(config *Config, providerType string) (LLMProvider, error) {
 	// 获取指定提供者的配置
-	providerConfig, err := config.GetProviderConfig(*providerType)
+	providerConfig, err := config.GetProviderConfig(providerType)
 	if err != nil {
-		fmt.Printf("获取模型配置失败: %v\n", err)
-		return
+		return nil, fmt.Errorf("获取模型配置失败: %v", err)
+	}
+	if providerType == "" {
+		providerType = config.DefaultProvider
 	}
 
-	// 创建大模型提供者
-	provider, err := NewLLMProvider(*providerType, map[string]string{
+	provider, err := NewLLMProvider(providerType, map[string]string{
 		"api_key":    providerConfig.APIKey,
 		"api_secret": providerConfig.APISecret,
 		"api_url":    providerConfig.APIURL,
 		"model_name": providerConfig.ModelName,
 	})
 	if err != nil {
-		fmt.Printf("创建模型提供者失败: %v\n", err)
-		return
+		return nil, fmt.Errorf("创建模型提供者失败: %v", err)
 	}
+	return provider, nil
+}
 
-	// 获取用户输入的文件夹路径
-	fmt.Print("请输入要整理的文件夹路径: ")
-	reader := bufio.NewReader(os.Stdin)
-	folderPath, err := reader.ReadString('\n')
-	if err != nil {
-		fmt.Printf("读取输入失败: %v\n", err)
-		return
-	}
-	folderPath = strings.TrimSpace(folderPath)
+// organizeOptions 移动文件时的选项
+type organizeOptions struct {
+	State     *OrganizeState
+	Conflicts *ConflictResolver   // 为nil时使用默认的 rename 策略
+	Confirm   func([]MoveOp) bool // 为nil时不预览、直接移动；返回false时取消移动
+	Journal   *RunJournal         // 为nil时不记录运行日志，无法撤销
+	Report    *RunReport          // 为nil时在移动前新建，分类前创建可以把分类的耗时和用量计入报告
+}
 
-	// 获取文件列表
-	files, err := getFileList(folderPath)
-	if err != nil {
-		fmt.Printf("获取文件列表失败: %v\n", err)
-		return
+// applyMoves 按移动计划移动文件，单个文件失败不会中断整个流程，失败记录在返回的报告中
+// 取消 ctx 时在当前文件处理完后停止，剩余的文件保持原样，已移动的文件仍然记录在运行日志中，可以撤销
+func applyMoves(ctx context.Context, folderPath string, ops []MoveOp, opts organizeOptions) *RunReport {
+	state := opts.State
+	report := opts.Report
+	if report == nil {
+		report = NewRunReport(folderPath)
 	}
-
-	fmt.Printf("找到 %d 个文件\n", len(files))
-
-	// 使用大模型对文件进行分类
-	fmt.Println("正在使用模型进行分类...")
-	classifiedFiles, err := provider.ClassifyFiles(files)
-	if err != nil {
-		fmt.Printf("分类失败: %v\n", err)
-		return
+	defer report.Finish()
+	resolver := opts.Conflicts
+	if resolver == nil {
+		resolver = NewConflictResolver(ConflictConfig{})
 	}
 
-	fmt.Printf("分类完成，共 %d 个分类\n", len(classifiedFiles))
-	for category, files := range classifiedFiles {
-		fmt.Printf("- %s: %d 个文件\n", category, len(files))
+	if opts.Confirm != nil && !opts.Confirm(ops) {
+		report.Cancelled = true
+		return report
 	}
 
-	// 创建分类目录并移动文件
-	fmt.Println("\n开始移动文件...")
-	for category, files := range classifiedFiles {
-		categoryPath := filepath.Join(folderPath, category)
-		if err := os.MkdirAll(categoryPath, 0755); err != nil {
-			fmt.Printf("创建分类目录失败: %v\n", err)
+	// 创建目标目录并移动文件
+	emitProgress(ProgressEvent{Kind: EventMoveStarted, Root: folderPath, Total: len(ops)})
+	placed := make(map[string]string) // 源文件相对路径 -> 最终目标路径
+	finished := ProgressEvent{Kind: EventRunFinished, Root: folderPath, Total: len(ops)}
+	for i, op := range ops {
+		if ctx.Err() != nil {
+			report.Cancelled = true
+			for _, rest := range ops[i:] {
+				report.AddFile(rest.File, FileSkipped, "", "已取消")
+			}
+			finished.Message = fmt.Sprintf("已取消，%d 个文件未移动", len(ops)-i)
+			break
+		}
+		srcPath, dstPath := op.Src, op.Dst
+		event := ProgressEvent{Root: folderPath, Path: op.File.Path, Done: i + 1, Total: len(ops)}
+		fail := func(format string, args ...interface{}) {
+			report.Failf(format, args...)
+			event.Kind, event.Message = EventFileFailed, report.Failures[len(report.Failures)-1]
+			report.AddFile(op.File, FileFailed, "", event.Message)
+			emitProgress(event)
+		}
+		skip := func(reason string) {
+			event.Kind, event.Message = EventFileSkipped, reason
+			event.Dst, _ = filepath.Rel(folderPath, dstPath)
+			report.AddFile(op.File, FileSkipped, event.Dst, reason)
+			emitProgress(event)
+		}
+		moved := func(message string) {
+			event.Kind, event.Message = EventFileMoved, message
+			event.Dst, _ = filepath.Rel(folderPath, dstPath)
+			report.AddFile(op.File, FileMoved, event.Dst, message)
+			emitProgress(event)
+		}
+		if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+			fail("创建目标目录失败: %v", err)
+			continue
+		}
+
+		// 检查源文件是否存在
+		if _, err := os.Lstat(srcPath); os.IsNotExist(err) {
+			fail("源文件不存在: %s", srcPath)
 			continue
 		}
 
-		for _, file := range files {
-			srcPath := filepath.Join(folderPath, file.Path)
-			dstPath := filepath.Join(categoryPath, filepath.Base(file.Path))
+		// 已经在目标位置的文件只需更新状态
+		if srcPath == dstPath {
+			placed[op.File.Path] = dstPath
+			if err := state.Record(folderPath, dstPath, op.File.Category); err != nil {
+				slog.Warn("记录整理状态失败", "path", op.File.Path, "error", err)
+			}
+			skip("已在目标位置")
+			continue
+		}
 
-			// 检查源文件是否存在
-			if _, err := os.Stat(srcPath); os.IsNotExist(err) {
-				fmt.Printf("源文件不存在: %s\n", srcPath)
+		// 目标文件已存在时按冲突策略处理
+		overwrote := false
+		if _, err := os.Lstat(dstPath); err == nil {
+			decision, err := resolver.Resolve(op, dstPath)
+			if err != nil {
+				fail("处理目标冲突失败 %s: %v", op.File.Path, err)
 				continue
 			}
+			report.AddConflict(decision)
 
-			// 检查目标文件是否已存在
-			if _, err := os.Stat(dstPath); err == nil {
-				// 如果目标文件已存在，添加数字后缀
-				ext := filepath.Ext(dstPath)
-				base := strings.TrimSuffix(dstPath, ext)
-				counter := 1
-				for {
-					newDstPath := fmt.Sprintf("%s_%d%s", base, counter, ext)
-					if _, err := os.Stat(newDstPath); os.IsNotExist(err) {
-						dstPath = newDstPath
-						break
+			switch decision.Action {
+			case ConflictSkip:
+				skip(decision.Reason)
+				continue
+			case ConflictDedupe:
+				if err := os.Remove(srcPath); err != nil {
+					fail("删除重复的源文件失败 %s: %v", op.File.Path, err)
+					continue
+				}
+				placed[op.File.Path] = dstPath
+				if err := opts.Journal.Add(JournalEntry{Action: JournalDedupe, Src: srcPath, Dst: dstPath, Category: op.File.Category}); err != nil {
+					slog.Warn("写入运行日志失败", "path", op.File.Path, "error", err)
+				}
+				skip("目标位置已有相同内容，已删除源文件")
+				continue
+			case ConflictOverwrite:
+				overwrote = true
+				// 硬链接不能覆盖已有文件，需要先删除
+				if op.LinkTo != "" {
+					if err := os.Remove(dstPath); err != nil {
+						fail("删除目标文件失败 %s: %v", dstPath, err)
+						continue
 					}
-					counter++
 				}
 			}
+			dstPath = decision.FinalDst
+		}
 
-			// 使用Copy+Remove替代Rename
-			if err := copyFile(srcPath, dstPath); err != nil {
-				fmt.Printf("复制文件失败 %s: %v\n", file.Path, err)
+		// 重复副本改为指向保留文件的硬链接
+		if op.LinkTo != "" {
+			target, ok := placed[op.LinkTo]
+			if !ok {
+				fail("保留的文件未能移动，跳过重复副本 %s", op.File.Path)
+				continue
+			}
+			if err := os.Link(target, dstPath); err != nil {
+				fail("创建硬链接失败 %s: %v", op.File.Path, err)
 				continue
 			}
 			if err := os.Remove(srcPath); err != nil {
-				fmt.Printf("删除源文件失败 %s: %v\n", file.Path, err)
+				fail("删除重复副本失败 %s: %v", op.File.Path, err)
 				continue
 			}
-			fmt.Printf("成功移动文件: %s -> %s\n", file.Path, filepath.Base(dstPath))
+			if err := opts.Journal.Add(JournalEntry{Action: JournalLink, Src: srcPath, Dst: dstPath, Category: op.File.Category}); err != nil {
+				slog.Warn("写入运行日志失败", "path", op.File.Path, "error", err)
+			}
+			moved("重复副本已替换为硬链接")
+			continue
 		}
-	}
 
-	fmt.Println("文件整理完成！")
-}
-
-// copyFile 复制文件
-func copyFile(src, dst string) error {
-	sourceFile, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer sourceFile.Close()
-
-	destFile, err := os.Create(dst)
-	if err != nil {
-		return err
+		// 项目、相册等目录整体移动
+		if op.File.IsDir {
+			if err := moveDir(srcPath, dstPath); err != nil {
+				fail("移动目录失败 %s: %v", op.File.Path, err)
+				continue
+			}
+		} else if err := moveFile(srcPath, dstPath); err != nil {
+			fail("移动文件失败 %s: %v", op.File.Path, err)
+			continue
+		}
+		report.Moved++
+		if err := opts.Journal.Add(JournalEntry{
+			Action:    JournalMove,
+			Src:       srcPath,
+			Dst:       dstPath,
+			Category:  op.File.Category,
+			IsDir:     op.File.IsDir,
+			Overwrote: overwrote,
+		}); err != nil {
+			slog.Warn("写入运行日志失败", "path", op.File.Path, "error", err)
+		}
+		moved("")
+		placed[op.File.Path] = dstPath
+		if err := state.Record(folderPath, dstPath, op.File.Category); err != nil {
+			slog.Warn("记录整理状态失败", "path", op.File.Path, "error", err)
+		}
 	}
-	defer destFile.Close()
 
-	_, err = io.Copy(destFile, sourceFile)
-	if err != nil {
-		return err
+	if err := state.Save(); err != nil {
+		slog.Warn("保存整理状态失败", "root", folderPath, "error", err)
 	}
-
-	return destFile.Sync()
+	finished.Done = report.Moved
+	emitProgress(finished)
+	return report
 }
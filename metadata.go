@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// 读取元数据时最多读取的字节数，EXIF 和 ID3 标签都位于文件开头
+const maxMetadataBytes = 256 * 1024
+
+// readFileMetadata 读取图片EXIF或音频标签中的元数据
+// 返回的键与路径模板字段一致（taken_year、camera、artist 等），不支持的格式返回空结果
+func readFileMetadata(path string) (map[string]string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".jpg", ".jpeg", ".tif", ".tiff", ".dng", ".nef", ".cr2", ".arw", ".mp3":
+	default:
+		return map[string]string{}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxMetadataBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	if ext == ".mp3" {
+		return parseID3(data)
+	}
+	return parseEXIF(data)
+}
+
+// parseEXIF 从JPEG或TIFF格式的数据中提取拍摄时间和相机型号
+func parseEXIF(data []byte) (map[string]string, error) {
+	tiff, err := findTIFFHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("无效的TIFF字节序")
+	}
+	if order.Uint16(tiff[2:4]) != 42 {
+		return nil, fmt.Errorf("无效的TIFF标识")
+	}
+
+	result := make(map[string]string)
+	ifd0 := readIFD(tiff, order, order.Uint32(tiff[4:8]))
+
+	maker := strings.TrimSpace(ifd0[0x010F])
+	model := strings.TrimSpace(ifd0[0x0110])
+	if model != "" {
+		if maker != "" && !strings.HasPrefix(strings.ToLower(model), strings.ToLower(maker)) {
+			model = maker + " " + model
+		}
+		result["camera"] = model
+	}
+
+	dateTime := ifd0[0x0132]
+	if offset, ok := ifdLong(tiff, order, order.Uint32(tiff[4:8]), 0x8769); ok {
+		exifIFD := readIFD(tiff, order, offset)
+		if original := exifIFD[0x9003]; original != "" {
+			dateTime = original
+		}
+	}
+	if taken, err := time.Parse("2006:01:02 15:04:05", strings.TrimSpace(dateTime)); err == nil {
+		result["taken_year"] = taken.Format("2006")
+		result["taken_month"] = taken.Format("01")
+		result["taken_day"] = taken.Format("02")
+	}
+
+	return result, nil
+}
+
+// findTIFFHeader 定位EXIF中的TIFF头
+func findTIFFHeader(data []byte) ([]byte, error) {
+	if len(data) >= 8 && (bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*"))) {
+		return data, nil
+	}
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, fmt.Errorf("不是JPEG或TIFF文件")
+	}
+
+	// 遍历JPEG段，查找APP1中的Exif数据
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return nil, fmt.Errorf("JPEG段标记无效")
+		}
+		marker := data[pos+1]
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		if length < 2 || pos+2+length > len(data) {
+			break
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) && len(segment) >= 14 {
+			return segment[6:], nil
+		}
+		pos += 2 + length
+	}
+	return nil, fmt.Errorf("未找到EXIF数据")
+}
+
+// readIFD 读取IFD中的ASCII类型条目
+func readIFD(tiff []byte, order binary.ByteOrder, offset uint32) map[uint16]string {
+	values := make(map[uint16]string)
+	if int(offset)+2 > len(tiff) {
+		return values
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := int(offset) + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		tag := order.Uint16(tiff[entry:])
+		typ := order.Uint16(tiff[entry+2:])
+		n := order.Uint32(tiff[entry+4:])
+		if typ != 2 || n == 0 { // 只处理ASCII
+			continue
+		}
+		start := entry + 8
+		if n > 4 {
+			start = int(order.Uint32(tiff[entry+8:]))
+		}
+		end := start + int(n)
+		if start < 0 || end > len(tiff) {
+			continue
+		}
+		values[tag] = strings.TrimRight(string(tiff[start:end]), "\x00 ")
+	}
+	return values
+}
+
+// ifdLong 读取IFD中LONG类型的条目，用于定位Exif子IFD
+func ifdLong(tiff []byte, order binary.ByteOrder, offset uint32, tag uint16) (uint32, bool) {
+	if int(offset)+2 > len(tiff) {
+		return 0, false
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := int(offset) + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) == tag {
+			return order.Uint32(tiff[entry+8:]), true
+		}
+	}
+	return 0, false
+}
+
+// parseID3 从ID3v2标签中提取艺术家、专辑和年份
+func parseID3(data []byte) (map[string]string, error) {
+	if len(data) < 10 || string(data[:3]) != "ID3" {
+		return nil, fmt.Errorf("未找到ID3标签")
+	}
+	version := data[3]
+	size := syncsafe(data[6:10])
+	end := 10 + size
+	if end > len(data) {
+		end = len(data)
+	}
+
+	result := make(map[string]string)
+	pos := 10
+	for pos+10 <= end {
+		id := string(data[pos : pos+4])
+		if id[0] == 0 {
+			break
+		}
+		var frameSize int
+		if version >= 4 {
+			frameSize = syncsafe(data[pos+4 : pos+8])
+		} else {
+			frameSize = int(binary.BigEndian.Uint32(data[pos+4 : pos+8]))
+		}
+		pos += 10
+		if frameSize <= 0 || pos+frameSize > end {
+			break
+		}
+		frame := data[pos : pos+frameSize]
+		pos += frameSize
+
+		switch id {
+		case "TPE1":
+			result["artist"] = decodeID3Text(frame)
+		case "TALB":
+			result["album"] = decodeID3Text(frame)
+		case "TYER", "TDRC":
+			if year := decodeID3Text(frame); len(year) >= 4 {
+				result["taken_year"] = year[:4]
+			}
+		}
+	}
+	return result, nil
+}
+
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
+
+// decodeID3Text 按帧的编码字节解码文本帧
+func decodeID3Text(frame []byte) string {
+	if len(frame) < 2 {
+		return ""
+	}
+	encoding, text := frame[0], frame[1:]
+	var s string
+	switch encoding {
+	case 0: // ISO-8859-1
+		runes := make([]rune, len(text))
+		for i, c := range text {
+			runes[i] = rune(c)
+		}
+		s = string(runes)
+	case 1, 2: // UTF-16（带BOM）或 UTF-16BE
+		var order binary.ByteOrder = binary.BigEndian
+		if len(text) >= 2 && text[0] == 0xFF && text[1] == 0xFE {
+			order = binary.LittleEndian
+			text = text[2:]
+		} else if len(text) >= 2 && text[0] == 0xFE && text[1] == 0xFF {
+			text = text[2:]
+		}
+		units := make([]uint16, 0, len(text)/2)
+		for i := 0; i+1 < len(text); i += 2 {
+			units = append(units, order.Uint16(text[i:]))
+		}
+		s = string(utf16.Decode(units))
+	default: // UTF-8
+		s = string(text)
+	}
+	return strings.TrimSpace(strings.TrimRight(s, "\x00"))
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+// exifTag 测试用的 ASCII 类型 EXIF 条目
+type exifTag struct {
+	tag   uint16
+	value string
+}
+
+// buildTIFF 生成包含 IFD0 和可选 Exif 子 IFD 的 TIFF 数据，条目都是 ASCII 类型
+func buildTIFF(order binary.ByteOrder, ifd0, exif []exifTag) []byte {
+	n0 := len(ifd0)
+	if exif != nil {
+		n0++
+	}
+	exifOffset := 8 + 2 + 12*n0 + 4
+	dataOffset := exifOffset
+	if exif != nil {
+		dataOffset += 2 + 12*len(exif) + 4
+	}
+
+	buf := make([]byte, dataOffset)
+	if order == binary.LittleEndian {
+		copy(buf, "II")
+	} else {
+		copy(buf, "MM")
+	}
+	order.PutUint16(buf[2:], 42)
+	order.PutUint32(buf[4:], 8)
+
+	writeIFD := func(offset int, tags []exifTag, exifPointer bool) {
+		count := len(tags)
+		if exifPointer {
+			count++
+		}
+		order.PutUint16(buf[offset:], uint16(count))
+		entry := offset + 2
+		for _, t := range tags {
+			value := append([]byte(t.value), 0)
+			order.PutUint16(buf[entry:], t.tag)
+			order.PutUint16(buf[entry+2:], 2)
+			order.PutUint32(buf[entry+4:], uint32(len(value)))
+			if len(value) <= 4 {
+				copy(buf[entry+8:entry+12], value)
+			} else {
+				order.PutUint32(buf[entry+8:], uint32(len(buf)))
+				buf = append(buf, value...)
+			}
+			entry += 12
+		}
+		if exifPointer {
+			order.PutUint16(buf[entry:], 0x8769)
+			order.PutUint16(buf[entry+2:], 4)
+			order.PutUint32(buf[entry+4:], 1)
+			order.PutUint32(buf[entry+8:], uint32(exifOffset))
+		}
+	}
+	writeIFD(8, ifd0, exif != nil)
+	if exif != nil {
+		writeIFD(exifOffset, exif, false)
+	}
+	return buf
+}
+
+// wrapJPEG 把 TIFF 数据放入 JPEG 的 APP1 段，前面加一个 APP0 段
+func wrapJPEG(tiff []byte) []byte {
+	var b bytes.Buffer
+	b.Write([]byte{0xFF, 0xD8})
+	app0 := []byte("JFIF\x00\x01\x02")
+	b.Write([]byte{0xFF, 0xE0})
+	binary.Write(&b, binary.BigEndian, uint16(2+len(app0)))
+	b.Write(app0)
+	b.Write([]byte{0xFF, 0xE1})
+	binary.Write(&b, binary.BigEndian, uint16(2+6+len(tiff)))
+	b.WriteString("Exif\x00\x00")
+	b.Write(tiff)
+	b.Write([]byte{0xFF, 0xD9})
+	return b.Bytes()
+}
+
+func TestParseEXIF(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "小端TIFF，型号已包含厂商",
+			data: buildTIFF(binary.LittleEndian, []exifTag{{0x010F, "Canon"}, {0x0110, "Canon EOS R5"}, {0x0132, "2020:01:02 03:04:05"}}, nil),
+			want: map[string]string{"camera": "Canon EOS R5", "taken_year": "2020", "taken_month": "01", "taken_day": "02"},
+		},
+		{
+			name: "大端TIFF，拍摄时间优先于修改时间",
+			data: buildTIFF(binary.BigEndian,
+				[]exifTag{{0x010F, "NIKON"}, {0x0110, "Z 6"}, {0x0132, "2021:06:07 08:09:10"}},
+				[]exifTag{{0x9003, "2019:12:31 23:59:59"}}),
+			want: map[string]string{"camera": "NIKON Z 6", "taken_year": "2019", "taken_month": "12", "taken_day": "31"},
+		},
+		{
+			name: "JPEG中的EXIF",
+			data: wrapJPEG(buildTIFF(binary.LittleEndian, []exifTag{{0x0110, "X1"}}, []exifTag{{0x9003, "2018:03:04 05:06:07"}})),
+			want: map[string]string{"camera": "X1", "taken_year": "2018", "taken_month": "03", "taken_day": "04"},
+		},
+		{
+			name: "时间格式无效",
+			data: buildTIFF(binary.LittleEndian, []exifTag{{0x0132, "unknown"}}, nil),
+			want: map[string]string{},
+		},
+		{
+			name:    "没有EXIF的JPEG",
+			data:    []byte{0xFF, 0xD8, 0xFF, 0xD9},
+			wantErr: true,
+		},
+		{
+			name:    "不是图片",
+			data:    []byte("plain text"),
+			wantErr: true,
+		},
+		{
+			name:    "TIFF标识错误",
+			data:    []byte("II\x2b\x00\x08\x00\x00\x00"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEXIF(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误 = %v，wantErr = %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseEXIF = %v，应为 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// buildID3 生成 ID3v2 标签，version 为 3 或 4
+func buildID3(version byte, frames ...[]byte) []byte {
+	var body []byte
+	for _, f := range frames {
+		body = append(body, f...)
+	}
+	header := []byte{'I', 'D', '3', version, 0, 0}
+	return append(append(header, syncsafeBytes(len(body))...), body...)
+}
+
+// id3Frame 生成一个文本帧，v2.4 的帧大小是 syncsafe 整数
+func id3Frame(version byte, id string, encoding byte, text []byte) []byte {
+	payload := append([]byte{encoding}, text...)
+	frame := []byte(id)
+	if version >= 4 {
+		frame = append(frame, syncsafeBytes(len(payload))...)
+	} else {
+		frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
+	}
+	return append(append(frame, 0, 0), payload...)
+}
+
+func syncsafeBytes(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}
+}
+
+func utf16Bytes(s string, order binary.AppendByteOrder, bom bool) []byte {
+	var b []byte
+	if bom {
+		b = order.AppendUint16(b, 0xFEFF)
+	}
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = order.AppendUint16(b, u)
+	}
+	return b
+}
+
+func TestParseID3(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "v2.3 ISO-8859-1",
+			data: buildID3(3,
+				id3Frame(3, "TPE1", 0, []byte("Beyonc\xe9")),
+				id3Frame(3, "TALB", 0, []byte("Lemonade\x00")),
+				id3Frame(3, "TYER", 0, []byte("2016"))),
+			want: map[string]string{"artist": "Beyoncé", "album": "Lemonade", "taken_year": "2016"},
+		},
+		{
+			name: "v2.4 UTF-8 和完整日期",
+			data: buildID3(4,
+				id3Frame(4, "TPE1", 3, []byte("周杰伦")),
+				id3Frame(4, "TDRC", 3, []byte("2018-05-01"))),
+			want: map[string]string{"artist": "周杰伦", "taken_year": "2018"},
+		},
+		{
+			name: "UTF-16 小端带BOM",
+			data: buildID3(3, id3Frame(3, "TALB", 1, utf16Bytes("叶惠美", binary.LittleEndian, true))),
+			want: map[string]string{"album": "叶惠美"},
+		},
+		{
+			name: "UTF-16BE",
+			data: buildID3(4, id3Frame(4, "TPE1", 2, utf16Bytes("Björk", binary.BigEndian, false))),
+			want: map[string]string{"artist": "Björk"},
+		},
+		{
+			name: "年份过短时忽略",
+			data: buildID3(3, id3Frame(3, "TYER", 0, []byte("98"))),
+			want: map[string]string{},
+		},
+		{
+			name: "帧大小超出标签时停止",
+			data: func() []byte {
+				data := buildID3(3, id3Frame(3, "TPE1", 0, []byte("A")), id3Frame(3, "TALB", 0, []byte("B")))
+				return data[:len(data)-1]
+			}(),
+			want: map[string]string{"artist": "A"},
+		},
+		{
+			name:    "没有ID3标签",
+			data:    []byte("\xff\xfb\x90\x00 mp3 frame"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseID3(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误 = %v，wantErr = %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseID3 = %v，应为 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadFileMetadata(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.txt"), "II*\x00 不会被解析")
+	mp3 := buildID3(3, id3Frame(3, "TPE1", 0, []byte("Artist")))
+	writeFile(t, filepath.Join(dir, "b.MP3"), string(mp3))
+	writeFile(t, filepath.Join(dir, "c.jpg"), string(wrapJPEG(buildTIFF(binary.BigEndian, []exifTag{{0x0110, "Pixel 8"}}, nil))))
+
+	tests := []struct {
+		name string
+		want map[string]string
+	}{
+		{"a.txt", map[string]string{}},
+		{"b.MP3", map[string]string{"artist": "Artist"}},
+		{"c.jpg", map[string]string{"camera": "Pixel 8"}},
+	}
+	for _, tt := range tests {
+		got, err := readFileMetadata(filepath.Join(dir, tt.name))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %v，应为 %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -2,22 +2,33 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"math"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 )
 
-// LLMProvider 定义大模型接口
+// LLMProvider 定义大模型接口，取消 ctx 时会中止正在进行的API请求
 type LLMProvider interface {
-	ClassifyFiles(files []FileInfo) (map[string][]FileInfo, error)
+	ClassifyFiles(ctx context.Context, files []FileInfo, opts ClassifyOptions) (map[string][]FileInfo, error)
 	GetConfig() (string, string, string) // 返回 modelName, apiURL, apiKey
 }
 
+// ClassifyOptions 一次分类使用的提示词、已有分类和缓存，由调用方在每次运行时传入
+type ClassifyOptions struct {
+	Prompt       *PromptTemplate      // 为nil时使用默认模板
+	Existing     []string             // 磁盘上已有的分类，新文件会优先归入这些分类
+	Cache        *ClassificationCache // 为nil时不使用缓存
+	Unclassified string               // 模型遗漏的文件使用的分类，为空时使用默认语言的"未分类"
+}
+
 // DeepseekProvider Deepseek模型实现
 type DeepseekProvider struct {
 	APIKey    string
@@ -62,8 +73,54 @@ type APIResponse struct {
 		} `json:"message"`
 	} `json:"choices"`
 	Error map[string]interface{} `json:"error,omitempty"`
+	Usage *TokenUsage            `json:"usage,omitempty"`
+}
+
+// TokenUsage 模型调用消耗的 token 数，来自 API 响应中的 usage 字段
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+	Requests         int `json:"requests"` // 成功的 API 请求数
 }
 
+var (
+	tokenUsageMu sync.Mutex
+	tokenUsage   TokenUsage // 进程启动以来累计的用量，运行报告记录前后的差值
+)
+
+// addTokenUsage 累加一次 API 调用的用量，响应中没有 usage 时只计请求数
+func addTokenUsage(usage *TokenUsage) {
+	tokenUsageMu.Lock()
+	defer tokenUsageMu.Unlock()
+	tokenUsage.Requests++
+	if usage != nil {
+		tokenUsage.PromptTokens += usage.PromptTokens
+		tokenUsage.CompletionTokens += usage.CompletionTokens
+		tokenUsage.TotalTokens += usage.TotalTokens
+	}
+}
+
+// currentTokenUsage 返回累计的用量
+func currentTokenUsage() TokenUsage {
+	tokenUsageMu.Lock()
+	defer tokenUsageMu.Unlock()
+	return tokenUsage
+}
+
+// Sub 返回两次累计用量的差值
+func (u TokenUsage) Sub(start TokenUsage) TokenUsage {
+	return TokenUsage{
+		PromptTokens:     u.PromptTokens - start.PromptTokens,
+		CompletionTokens: u.CompletionTokens - start.CompletionTokens,
+		TotalTokens:      u.TotalTokens - start.TotalTokens,
+		Requests:         u.Requests - start.Requests,
+	}
+}
+
+// supportedProviders 支持的大模型提供者
+var supportedProviders = []string{"deepseek", "siliconflow", "aliyun", "github"}
+
 // NewLLMProvider 创建大模型提供者
 func NewLLMProvider(providerType string, config map[string]string) (LLMProvider, error) {
 	switch providerType {
@@ -127,7 +184,7 @@ func extractJSONFromContent(content string) string {
 
 	// 验证提取的JSON是否完整
 	if !isValidJSON(jsonContent) {
-		fmt.Printf("警告：提取的JSON内容可能不完整: %s\n", jsonContent)
+		slog.Warn("提取的JSON内容可能不完整，尝试修复", "content", jsonContent)
 		// 尝试修复不完整的JSON
 		jsonContent = fixIncompleteJSON(jsonContent)
 	}
@@ -289,37 +346,21 @@ func retryWithBackoff(operation func() error, maxRetries int) error {
 
 		// 计算退避时间（指数退避）
 		backoff := time.Duration(math.Pow(2, float64(i))) * time.Second
-		fmt.Printf("操作失败，%d秒后重试 (第%d次重试): %v\n", int(backoff.Seconds()), i+1, err)
+		slog.Warn("操作失败，稍后重试", "retry", i+1, "backoff", backoff, "error", err)
+		emitProgress(ProgressEvent{Kind: EventRetry, Attempt: i + 1, Message: fmt.Sprintf("%v，%d秒后重试", err, int(backoff.Seconds()))})
 		time.Sleep(backoff)
 	}
 	return fmt.Errorf("在%d次重试后仍然失败: %v", maxRetries, err)
 }
 
 // 添加通用的分类处理函数
-func processClassificationChunk(chunk []FileInfo, provider LLMProvider, processedFiles map[string]bool) (map[string][]FileInfo, error) {
-	// 构建文件列表字符串
-	var fileList strings.Builder
-	for _, file := range chunk {
-		fileList.WriteString(fmt.Sprintf("- %s\n", file.Path))
+func processClassificationChunk(ctx context.Context, chunk []FileInfo, provider LLMProvider, opts ClassifyOptions, processedFiles map[string]bool) (map[string][]FileInfo, error) {
+	// 按模板生成提示词
+	content, err := opts.Prompt.Render(chunk, opts.Existing)
+	if err != nil {
+		return nil, err
 	}
 
-	// 构建提示词
-	prompt := fmt.Sprintf(`请根据以下文件列表，将文件按照相似性进行分类。请使用中文命名分类，并返回JSON格式的分类结果。
-文件列表：
-%s
-
-请按照以下JSON格式返回分类结果：
-{
-    "分类名称1": ["文件路径1", "文件路径2", ...],
-    "分类名称2": ["文件路径1", "文件路径2", ...],
-    ...
-}
-
-注意：
-1. 请确保返回的是有效的JSON格式，不要包含任何其他文本
-2. 请确保所有文件都被分类，不要遗漏任何文件
-3. 如果文件内容不明确，可以将其归类到"其他"类别`, fileList.String())
-
 	// 获取提供者配置
 	modelName, apiURL, apiKey := provider.GetConfig()
 
@@ -329,25 +370,25 @@ func processClassificationChunk(chunk []FileInfo, provider LLMProvider, processe
 		Messages: []map[string]string{
 			{
 				"role":    "user",
-				"content": prompt,
+				"content": content,
 			},
 		},
 		MaxTokens: 8192,
 	}
 
 	// 调用API
-	response, err := callAPI(apiURL, apiKey, request)
+	response, err := callAPI(ctx, apiURL, apiKey, request)
 	if err != nil {
 		return nil, fmt.Errorf("API调用失败: %v", err)
 	}
 
 	// 解析分类结果
-	content := extractJSONFromContent(response.Choices[0].Message.Content)
-	fmt.Printf("提取的JSON内容: %s\n", content)
+	content = extractJSONFromContent(response.Choices[0].Message.Content)
+	slog.Debug("提取的JSON内容", "content", content)
 
 	// 尝试修复不完整的JSON
 	content = fixIncompleteJSON(content)
-	fmt.Printf("修复后的JSON内容: %s\n", content)
+	slog.Debug("修复后的JSON内容", "content", content)
 
 	// 检查JSON内容是否完整
 	if !isValidJSON(content) {
@@ -392,7 +433,7 @@ func processClassificationChunk(chunk []FileInfo, provider LLMProvider, processe
 }
 
 // 添加并发处理函数
-func processChunksConcurrently(chunks [][]FileInfo, provider LLMProvider, processedFiles map[string]bool) ([]map[string][]FileInfo, error) {
+func processChunksConcurrently(ctx context.Context, chunks [][]FileInfo, provider LLMProvider, opts ClassifyOptions, processedFiles map[string]bool) ([]map[string][]FileInfo, error) {
 	var (
 		allResults []map[string][]FileInfo
 		mu         sync.Mutex
@@ -404,14 +445,30 @@ func processChunksConcurrently(chunks [][]FileInfo, provider LLMProvider, proces
 		wg.Add(1)
 		go func(i int, chunk []FileInfo) {
 			defer wg.Done()
-			fmt.Printf("正在处理第 %d/%d 批文件...\n", i+1, len(chunks))
-			fmt.Printf("本批次包含 %d 个文件\n", len(chunk))
+			event := ProgressEvent{Kind: EventChunkSent, Chunk: i + 1, Chunks: len(chunks), Files: len(chunk)}
+			emitProgress(event)
 
-			result, err := processClassificationChunk(chunk, provider, processedFiles)
+			result, err := processClassificationChunk(ctx, chunk, provider, opts, processedFiles)
 			if err != nil {
+				// 取消时不逐批报告失败
+				if ctx.Err() != nil {
+					return
+				}
+				event.Kind, event.Message = EventChunkFailed, err.Error()
+				emitProgress(event)
 				errChan <- fmt.Errorf("处理第%d批文件失败: %v", i+1, err)
 				return
 			}
+			event.Kind = EventChunkReceived
+			emitProgress(event)
+
+			// 每批成功后立即写入缓存，中途失败时已完成的批次不会丢失
+			if opts.Cache != nil {
+				modelName, _, _ := provider.GetConfig()
+				if err := opts.Cache.StoreResult(result, modelName, opts.Prompt.ID(), opts.Existing); err != nil {
+					slog.Warn("写入分类缓存失败", "error", err)
+				}
+			}
 
 			mu.Lock()
 			allResults = append(allResults, result)
@@ -422,6 +479,9 @@ func processChunksConcurrently(chunks [][]FileInfo, provider LLMProvider, proces
 	// 等待所有goroutine完成
 	wg.Wait()
 	close(errChan)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// 检查是否有错误发生
 	for err := range errChan {
@@ -432,33 +492,67 @@ func processChunksConcurrently(chunks [][]FileInfo, provider LLMProvider, proces
 }
 
 // 修改各个提供者的ClassifyFiles方法
-func (p *DeepseekProvider) ClassifyFiles(files []FileInfo) (map[string][]FileInfo, error) {
+func (p *DeepseekProvider) ClassifyFiles(ctx context.Context, files []FileInfo, opts ClassifyOptions) (map[string][]FileInfo, error) {
+	return classifyWithProvider(ctx, p, files, opts)
+}
+
+// classifyWithProvider 各提供者共用的分类流程：先查缓存，再分批调用模型
+func classifyWithProvider(ctx context.Context, provider LLMProvider, files []FileInfo, opts ClassifyOptions) (map[string][]FileInfo, error) {
+	modelName, _, _ := provider.GetConfig()
+	if opts.Prompt == nil {
+		opts.Prompt = defaultPrompt()
+	}
+	if opts.Unclassified == "" {
+		opts.Unclassified = NamingConfig{}.UnclassifiedCategory()
+	}
+
+	// 命中缓存的文件不再发送给模型
+	cachedFiles := make(map[string][]FileInfo)
+	pendingFiles := files
+	if opts.Cache != nil {
+		pendingFiles = nil
+		for _, file := range files {
+			if category, ok := opts.Cache.Lookup(file, modelName, opts.Prompt.ID(), opts.Existing); ok {
+				file.Category = category
+				cachedFiles[category] = append(cachedFiles[category], file)
+				continue
+			}
+			pendingFiles = append(pendingFiles, file)
+		}
+		if hits := len(files) - len(pendingFiles); hits > 0 {
+			slog.Info("分类缓存命中", "hits", hits, "pending", len(pendingFiles))
+		}
+	}
+
 	// 将文件列表分成较小的批次
-	chunks := splitFileList(files)
+	chunks := splitFileList(pendingFiles)
 
 	// 创建一个map来跟踪所有文件
 	processedFiles := make(map[string]bool)
-	for _, file := range files {
+	for _, file := range pendingFiles {
 		processedFiles[file.Path] = false
 	}
 
 	// 并发处理所有批次
-	allResults, err := processChunksConcurrently(chunks, p, processedFiles)
+	allResults, err := processChunksConcurrently(ctx, chunks, provider, opts, processedFiles)
 	if err != nil {
 		return nil, err
 	}
 
 	// 处理未分类的文件
-	unclassifiedFiles := handleUnclassifiedFiles(files, processedFiles)
+	unclassifiedFiles := handleUnclassifiedFiles(pendingFiles, processedFiles, opts.Unclassified)
 	if len(unclassifiedFiles) > 0 {
 		allResults = append(allResults, unclassifiedFiles)
 	}
+	if len(cachedFiles) > 0 {
+		allResults = append(allResults, cachedFiles)
+	}
 
 	return mergeClassificationResults(allResults), nil
 }
 
 // 处理未分类文件的函数
-func handleUnclassifiedFiles(files []FileInfo, processedFiles map[string]bool) map[string][]FileInfo {
+func handleUnclassifiedFiles(files []FileInfo, processedFiles map[string]bool, category string) map[string][]FileInfo {
 	var unprocessedFiles []string
 	for path, processed := range processedFiles {
 		if !processed {
@@ -467,18 +561,20 @@ func handleUnclassifiedFiles(files []FileInfo, processedFiles map[string]bool) m
 	}
 
 	if len(unprocessedFiles) > 0 {
-		fmt.Printf("\n警告：发现 %d 个文件未被分类：\n", len(unprocessedFiles))
+		// 路径放在 path 字段中，开启 redact_paths 时会被隐藏
+		sort.Strings(unprocessedFiles)
+		slog.Warn("部分文件未被模型分类", "count", len(unprocessedFiles), "category", category)
 		for _, path := range unprocessedFiles {
-			fmt.Printf("- %s\n", path)
+			slog.Warn("文件未被模型分类", "path", path, "category", category)
 		}
 
 		unclassifiedFiles := make(map[string][]FileInfo)
-		unclassifiedFiles["未分类"] = make([]FileInfo, 0)
+		unclassifiedFiles[category] = make([]FileInfo, 0)
 		for _, path := range unprocessedFiles {
 			for _, file := range files {
 				if file.Path == path {
-					file.Category = "未分类"
-					unclassifiedFiles["未分类"] = append(unclassifiedFiles["未分类"], file)
+					file.Category = category
+					unclassifiedFiles[category] = append(unclassifiedFiles[category], file)
 					break
 				}
 			}
@@ -489,17 +585,20 @@ func handleUnclassifiedFiles(files []FileInfo, processedFiles map[string]bool) m
 	return nil
 }
 
-// 修改callAPI函数，增加重试机制
-func callAPI(url string, apiKey string, payload interface{}) (*APIResponse, error) {
+// 修改callAPI函数，增加重试机制；取消 ctx 时不再重试，返回 ctx.Err()
+func callAPI(ctx context.Context, url string, apiKey string, payload interface{}) (*APIResponse, error) {
 	var response *APIResponse
 	var err error
 
 	// 使用指数退避重试
 	for i := 0; i < 3; i++ {
-		response, err = doAPICall(url, apiKey, payload)
+		response, err = doAPICall(ctx, url, apiKey, payload)
 		if err == nil {
 			return response, nil
 		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 
 		// 如果是JSON解析错误，直接返回
 		if strings.Contains(err.Error(), "JSON") {
@@ -508,27 +607,34 @@ func callAPI(url string, apiKey string, payload interface{}) (*APIResponse, erro
 
 		// 计算退避时间
 		backoff := time.Duration(math.Pow(2, float64(i))) * time.Second
-		fmt.Printf("API调用失败，%d秒后重试 (第%d次重试): %v\n", int(backoff.Seconds()), i+1, err)
-		time.Sleep(backoff)
+		slog.Warn("API调用失败，稍后重试", "url", url, "retry", i+1, "backoff", backoff, "error", err)
+		emitProgress(ProgressEvent{Kind: EventRetry, Attempt: i + 1, Message: fmt.Sprintf("%v，%d秒后重试", err, int(backoff.Seconds()))})
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
 	}
 
 	return nil, fmt.Errorf("在3次重试后仍然失败: %v", err)
 }
 
 // 添加实际的API调用函数
-func doAPICall(url string, apiKey string, payload interface{}) (*APIResponse, error) {
+func doAPICall(ctx context.Context, url string, apiKey string, payload interface{}) (*APIResponse, error) {
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("构建请求失败: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("创建请求失败: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+apiKey)
+	slog.Debug("发送API请求", "url", url, "prompt", string(jsonData))
+	start := time.Now()
 
 	client := &http.Client{
 		Timeout: 180 * time.Second,
@@ -540,19 +646,15 @@ func doAPICall(url string, apiKey string, payload interface{}) (*APIResponse, er
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API请求失败，状态码: %d，响应: %s", resp.StatusCode, string(body))
-	}
-
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("读取响应失败: %v", err)
 	}
+	slog.Debug("收到API响应", "url", url, "status", resp.StatusCode, "duration", time.Since(start), "response", string(body))
 
-	// 打印原始响应以便调试
-	fmt.Printf("API响应状态码: %d\n", resp.StatusCode)
-	fmt.Printf("API响应内容: %s\n", string(body))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API请求失败，状态码: %d，响应: %s", resp.StatusCode, string(body))
+	}
 
 	var apiResponse APIResponse
 	if err := json.Unmarshal(body, &apiResponse); err != nil {
@@ -562,83 +664,21 @@ func doAPICall(url string, apiKey string, payload interface{}) (*APIResponse, er
 	if apiResponse.Error != nil {
 		return nil, fmt.Errorf("API返回错误: %v", apiResponse.Error)
 	}
+	addTokenUsage(apiResponse.Usage)
 
 	return &apiResponse, nil
 }
 
-func (p *SiliconFlowProvider) ClassifyFiles(files []FileInfo) (map[string][]FileInfo, error) {
-	// 将文件列表分成较小的批次
-	chunks := splitFileList(files)
-
-	// 创建一个map来跟踪所有文件
-	processedFiles := make(map[string]bool)
-	for _, file := range files {
-		processedFiles[file.Path] = false
-	}
-
-	// 并发处理所有批次
-	allResults, err := processChunksConcurrently(chunks, p, processedFiles)
-	if err != nil {
-		return nil, err
-	}
-
-	// 处理未分类的文件
-	unclassifiedFiles := handleUnclassifiedFiles(files, processedFiles)
-	if len(unclassifiedFiles) > 0 {
-		allResults = append(allResults, unclassifiedFiles)
-	}
-
-	return mergeClassificationResults(allResults), nil
+func (p *SiliconFlowProvider) ClassifyFiles(ctx context.Context, files []FileInfo, opts ClassifyOptions) (map[string][]FileInfo, error) {
+	return classifyWithProvider(ctx, p, files, opts)
 }
 
-func (p *AliyunProvider) ClassifyFiles(files []FileInfo) (map[string][]FileInfo, error) {
-	// 将文件列表分成较小的批次
-	chunks := splitFileList(files)
-
-	// 创建一个map来跟踪所有文件
-	processedFiles := make(map[string]bool)
-	for _, file := range files {
-		processedFiles[file.Path] = false
-	}
-
-	// 并发处理所有批次
-	allResults, err := processChunksConcurrently(chunks, p, processedFiles)
-	if err != nil {
-		return nil, err
-	}
-
-	// 处理未分类的文件
-	unclassifiedFiles := handleUnclassifiedFiles(files, processedFiles)
-	if len(unclassifiedFiles) > 0 {
-		allResults = append(allResults, unclassifiedFiles)
-	}
-
-	return mergeClassificationResults(allResults), nil
+func (p *AliyunProvider) ClassifyFiles(ctx context.Context, files []FileInfo, opts ClassifyOptions) (map[string][]FileInfo, error) {
+	return classifyWithProvider(ctx, p, files, opts)
 }
 
-func (p *GitHubProvider) ClassifyFiles(files []FileInfo) (map[string][]FileInfo, error) {
-	// 将文件列表分成较小的批次
-	chunks := splitFileList(files)
-
-	// 创建一个map来跟踪所有文件
-	processedFiles := make(map[string]bool)
-	for _, file := range files {
-		processedFiles[file.Path] = false
-	}
-
-	// 并发处理所有批次
-	allResults, err := processChunksConcurrently(chunks, p, processedFiles)
-	if err != nil {
-		return nil, err
-	}
-
-	// 处理未分类的文件
-	unclassifiedFiles := handleUnclassifiedFiles(files, processedFiles)
-	if len(unclassifiedFiles) > 0 {
-		allResults = append(allResults, unclassifiedFiles)
-	}
-
-	return mergeClassificationResults(allResults), nil
+func (p *GitHubProvider) ClassifyFiles(ctx context.Context, files []FileInfo, opts ClassifyOptions) (map[string][]FileInfo, error) {
+	return classifyWithProvider(ctx, p, files, opts)
 }
 
 // 为每个提供者实现GetConfig方法
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// rename 移动时首先尝试的重命名，测试中替换它来模拟跨设备等失败
+var rename = os.Rename
+
+// moveFile 移动单个文件
+// 优先使用 os.Rename；只有跨设备时才复制：先写入目标目录下的临时文件，
+// 保留权限、时间、属主和扩展属性，校验大小和哈希后原子重命名为目标文件，最后删除源文件
+func moveFile(src, dst string) error {
+	if info, err := os.Lstat(src); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return moveSymlink(src, dst)
+	}
+
+	err := rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	if !isCrossDevice(err) {
+		return err
+	}
+
+	if err := copyFileVerified(src, dst); err != nil {
+		return err
+	}
+	if err := os.Remove(src); err != nil {
+		return fmt.Errorf("文件已复制到 %s，但删除源文件失败: %v", dst, err)
+	}
+	return nil
+}
+
+// moveSymlink 移动符号链接本身
+// 相对路径的链接会改写为从新位置出发仍指向原目标的相对路径
+func moveSymlink(src, dst string) error {
+	target, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+	if filepath.IsAbs(target) {
+		if err := rename(src, dst); err == nil || !isCrossDevice(err) {
+			return err
+		}
+	} else {
+		absTarget := filepath.Join(filepath.Dir(src), target)
+		if newTarget, err := filepath.Rel(filepath.Dir(dst), absTarget); err == nil {
+			target = newTarget
+		}
+	}
+
+	if err := os.Symlink(target, dst); err != nil {
+		return err
+	}
+	if err := os.Remove(src); err != nil {
+		return fmt.Errorf("链接已创建于 %s，但删除原链接失败: %v", dst, err)
+	}
+	return nil
+}
+
+// moveDir 移动整个目录，跨设备时先完整复制到临时目录，成功后再替换和删除源目录
+func moveDir(src, dst string) (err error) {
+	// 目标位于源目录内部时（如目录被归入与自己同名的分类），先把源目录改为临时名称
+	if isNestedIn(dst, src) {
+		origSrc, tmpSrc := src, src+".fileclassify-moving"
+		if err := os.Rename(src, tmpSrc); err != nil {
+			return err
+		}
+		// 运行日志中只有原来的路径，移动失败时必须改回原名，否则无法找到和撤销
+		defer func() {
+			if err == nil {
+				return
+			}
+			for dir := filepath.Dir(dst); isNestedIn(dir, origSrc); dir = filepath.Dir(dir) {
+				os.Remove(dir)
+			}
+			os.Remove(origSrc)
+			if rerr := os.Rename(tmpSrc, origSrc); rerr != nil {
+				err = fmt.Errorf("%v；源目录未能改回原名，现在位于 %s: %v", err, tmpSrc, rerr)
+			}
+		}()
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		src = tmpSrc
+	}
+
+	err = rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	if !isCrossDevice(err) {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".fileclassify-*")
+	if err != nil {
+		return fmt.Errorf("创建临时目录失败: %v", err)
+	}
+	if err := copyDir(src, tmpDir); err != nil {
+		os.RemoveAll(tmpDir)
+		return err
+	}
+	if err := os.Rename(tmpDir, dst); err != nil {
+		os.RemoveAll(tmpDir)
+		return err
+	}
+	if err := os.RemoveAll(src); err != nil {
+		return fmt.Errorf("目录已复制到 %s，但删除源目录失败: %v", dst, err)
+	}
+	return nil
+}
+
+// copyDir 递归复制目录内容，dst 必须已存在
+func copyDir(src, dst string) error {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	// 读取源目录
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	// 遍历源目录中的所有文件和子目录
+	for _, entry := range entries {
+		srcPath := filepath.Join(src, entry.Name())
+		dstPath := filepath.Join(dst, entry.Name())
+
+		switch {
+		case entry.IsDir():
+			if err := os.Mkdir(dstPath, 0700); err != nil {
+				return err
+			}
+			if err := copyDir(srcPath, dstPath); err != nil {
+				return err
+			}
+		case entry.Type()&os.ModeSymlink != 0:
+			target, err := os.Readlink(srcPath)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(target, dstPath); err != nil {
+				return err
+			}
+		case entry.Type().IsRegular():
+			if err := copyFileVerified(srcPath, dstPath); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("不支持复制特殊文件: %s", srcPath)
+		}
+	}
+
+	// 子项复制完成后再设置目录的权限和时间，避免被写入操作覆盖
+	return preserveMetadata(src, dst, srcInfo)
+}
+
+// copyFileVerified 将文件复制到目标目录下的临时文件，校验后原子重命名为 dst
+func copyFileVerified(src, dst string) error {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	sourceFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".fileclassify-*.tmp")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	tmpPath := tmpFile.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			tmpFile.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	// 复制的同时计算源文件哈希
+	srcHash := sha256.New()
+	written, err := io.Copy(tmpFile, io.TeeReader(sourceFile, srcHash))
+	if err != nil {
+		return fmt.Errorf("复制文件内容失败: %v", err)
+	}
+	if err := tmpFile.Sync(); err != nil {
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	// 校验大小和内容
+	if written != srcInfo.Size() {
+		return fmt.Errorf("复制后大小不一致: 源文件 %d 字节，目标 %d 字节", srcInfo.Size(), written)
+	}
+	dstHash, err := hashFile(tmpPath)
+	if err != nil {
+		return fmt.Errorf("校验复制结果失败: %v", err)
+	}
+	if dstHash != hex.EncodeToString(srcHash.Sum(nil)) {
+		return fmt.Errorf("复制后内容校验失败: %s", src)
+	}
+
+	if err := preserveMetadata(src, tmpPath, srcInfo); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, dst); err != nil {
+		return err
+	}
+	committed = true
+	syncDir(filepath.Dir(dst))
+	return nil
+}
+
+// preserveMetadata 将源文件的权限、扩展属性、属主和时间复制到目标文件
+// 属主和扩展属性在没有权限时会被忽略
+func preserveMetadata(src, dst string, srcInfo os.FileInfo) error {
+	if err := os.Chmod(dst, srcInfo.Mode().Perm()); err != nil {
+		return fmt.Errorf("设置文件权限失败: %v", err)
+	}
+	if err := copyXattrs(src, dst); err != nil {
+		return fmt.Errorf("复制扩展属性失败: %v", err)
+	}
+	copyOwnership(dst, srcInfo)
+	if err := os.Chtimes(dst, fileAccessTime(srcInfo), srcInfo.ModTime()); err != nil {
+		return fmt.Errorf("设置文件时间失败: %v", err)
+	}
+	return nil
+}
+
+// syncDir 尽量将目录项写入磁盘，失败时忽略
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
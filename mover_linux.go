@@ -0,0 +1,67 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// isCrossDevice 判断重命名失败是否因为源和目标不在同一设备上
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
+
+// fileAccessTime 返回文件的访问时间
+func fileAccessTime(info os.FileInfo) time.Time {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(stat.Atim.Sec, stat.Atim.Nsec)
+	}
+	return info.ModTime()
+}
+
+// copyOwnership 尽量保留属主，普通用户没有权限时忽略
+func copyOwnership(dst string, srcInfo os.FileInfo) {
+	if stat, ok := srcInfo.Sys().(*syscall.Stat_t); ok {
+		os.Lchown(dst, int(stat.Uid), int(stat.Gid))
+	}
+}
+
+// copyXattrs 复制扩展属性，文件系统不支持或没有权限的属性会被跳过
+func copyXattrs(src, dst string) error {
+	size, err := syscall.Listxattr(src, nil)
+	if err != nil || size == 0 {
+		return nil
+	}
+	buf := make([]byte, size)
+	size, err = syscall.Listxattr(src, buf)
+	if err != nil {
+		return nil
+	}
+
+	for _, name := range strings.Split(strings.TrimRight(string(buf[:size]), "\x00"), "\x00") {
+		if name == "" {
+			continue
+		}
+		valueSize, err := syscall.Getxattr(src, name, nil)
+		if err != nil {
+			continue
+		}
+		value := make([]byte, valueSize)
+		if valueSize > 0 {
+			if valueSize, err = syscall.Getxattr(src, name, value); err != nil {
+				continue
+			}
+		}
+		if err := syscall.Setxattr(dst, name, value[:valueSize], 0); err != nil {
+			if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.ENOTSUP) || errors.Is(err, syscall.EACCES) {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+)
+
+func TestMoveDirNestedCopyFailure(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "图片")
+	writeFile(t, filepath.Join(src, "a.jpg"), "a")
+	// 管道无法复制，跨设备复制到一半时失败
+	if err := syscall.Mkfifo(filepath.Join(src, "pipe"), 0600); err != nil {
+		t.Skipf("无法创建管道: %v", err)
+	}
+	failRename(t, syscall.EXDEV)
+
+	if err := moveDir(src, filepath.Join(src, "2024", "图片")); err == nil {
+		t.Fatal("复制特殊文件应失败")
+	}
+	// 源目录改回原名，临时目录和为目标创建的目录都已删除
+	want := map[string]string{"图片/": "", "图片/a.jpg": "a", "图片/pipe": "p---------"}
+	if got := readTree(t, root); !reflect.DeepEqual(got, want) {
+		t.Errorf("失败后的目录 = %v\n应为 %v", got, want)
+	}
+}
@@ -0,0 +1,34 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// isCrossDevice 判断重命名失败是否因为源和目标不在同一设备上
+func isCrossDevice(err error) bool {
+	if errors.Is(err, syscall.EXDEV) {
+		return true
+	}
+	// Windows 下跨卷移动返回 ERROR_NOT_SAME_DEVICE (17)
+	var errno syscall.Errno
+	return runtime.GOOS == "windows" && errors.As(err, &errno) && errno == 17
+}
+
+// fileAccessTime 其他平台无法统一取得访问时间，使用修改时间代替
+func fileAccessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
+
+// copyOwnership 其他平台不保留属主
+func copyOwnership(dst string, srcInfo os.FileInfo) {}
+
+// copyXattrs 其他平台不复制扩展属性
+func copyXattrs(src, dst string) error {
+	return nil
+}
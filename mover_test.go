@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestMoveDir(t *testing.T) {
+	album := map[string]string{
+		"图片/":          "",
+		"图片/a.jpg":     "a",
+		"图片/sub/":      "",
+		"图片/sub/b.jpg": "b",
+	}
+	nested := map[string]string{
+		"图片/":             "",
+		"图片/图片/":          "",
+		"图片/图片/a.jpg":     "a",
+		"图片/图片/sub/":      "",
+		"图片/图片/sub/b.jpg": "b",
+	}
+	tests := []struct {
+		name     string
+		src, dst string
+		fail     error // 首次重命名返回的错误，为nil时不替换
+		wantErr  bool
+		want     map[string]string
+	}{
+		{"归入同名分类", "图片", "图片/图片", nil, false, nested},
+		{"归入同名分类时跨设备复制", "图片", "图片/图片", syscall.EXDEV, false, nested},
+		{"归入同名分类失败时恢复原名", "图片", "图片/图片", syscall.EACCES, true, album},
+		{"归入同名分类的深层目录失败时恢复原名", "图片", "图片/2024/图片", syscall.EACCES, true, album},
+		{"普通移动", "图片", "相册", nil, false, map[string]string{
+			"相册/": "", "相册/a.jpg": "a", "相册/sub/": "", "相册/sub/b.jpg": "b",
+		}},
+		{"普通移动失败时保持原样", "图片", "相册", syscall.EACCES, true, album},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for path, content := range album {
+				if content != "" {
+					writeFile(t, filepath.Join(root, filepath.FromSlash(path)), content)
+				}
+			}
+			if tt.fail != nil {
+				failRename(t, tt.fail)
+			}
+
+			err := moveDir(filepath.Join(root, tt.src), filepath.Join(root, filepath.FromSlash(tt.dst)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("moveDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := readTree(t, root); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("移动后的目录 = %v\n应为 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoveFile(t *testing.T) {
+	modTime := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		fail      error // 首次重命名返回的错误，为nil时不替换
+		noDstDir  bool  // 目标目录不存在
+		wantErr   bool
+		wantMoved bool
+	}{
+		{"同一设备直接重命名", nil, false, false, true},
+		{"跨设备时复制并保留权限和时间", syscall.EXDEV, false, false, true},
+		{"重命名失败时保持原样", syscall.EACCES, false, true, false},
+		{"跨设备复制失败时不留下临时文件", syscall.EXDEV, true, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			src, dst := filepath.Join(root, "a.txt"), filepath.Join(root, "文档", "a.txt")
+			writeFile(t, src, "内容")
+			if err := os.Chmod(src, 0640); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(src, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+			if !tt.noDstDir {
+				mkdirAll(t, filepath.Dir(dst))
+			}
+			if tt.fail != nil {
+				failRename(t, tt.fail)
+			}
+
+			err := moveFile(src, dst)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("moveFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			want := map[string]string{"a.txt": "内容"}
+			if !tt.noDstDir {
+				want["文档/"] = ""
+			}
+			if tt.wantMoved {
+				want = map[string]string{"文档/": "", "文档/a.txt": "内容"}
+			}
+			if got := readTree(t, root); !reflect.DeepEqual(got, want) {
+				t.Fatalf("移动后的目录 = %v\n应为 %v", got, want)
+			}
+
+			// 复制时保留源文件的权限和修改时间
+			path := src
+			if tt.wantMoved {
+				path = dst
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if runtime.GOOS != "windows" && info.Mode().Perm() != 0640 {
+				t.Errorf("权限 = %v，应为 0640", info.Mode().Perm())
+			}
+			if !info.ModTime().Equal(modTime) {
+				t.Errorf("修改时间 = %v，应为 %v", info.ModTime(), modTime)
+			}
+		})
+	}
+}
+
+func TestMoveSymlink(t *testing.T) {
+	for _, fail := range []error{nil, syscall.EXDEV} {
+		t.Run(fmt.Sprint(fail), func(t *testing.T) {
+			root := t.TempDir()
+			writeFile(t, filepath.Join(root, "target.txt"), "x")
+			mkdirAll(t, filepath.Join(root, "a"))
+			mkdirAll(t, filepath.Join(root, "b", "c"))
+			symlink(t, filepath.Join("..", "target.txt"), filepath.Join(root, "a", "rel"))
+			symlink(t, filepath.Join(root, "target.txt"), filepath.Join(root, "a", "abs"))
+			if fail != nil {
+				failRename(t, fail)
+			}
+
+			for _, name := range []string{"rel", "abs"} {
+				if err := moveFile(filepath.Join(root, "a", name), filepath.Join(root, "b", "c", name)); err != nil {
+					t.Fatalf("移动链接 %s 失败: %v", name, err)
+				}
+			}
+			// 相对路径的链接改写为从新位置出发的相对路径，绝对路径的链接保持不变
+			want := map[string]string{
+				"a/": "", "b/": "", "b/c/": "", "target.txt": "x",
+				"b/c/rel": "-> ../../target.txt",
+				"b/c/abs": "-> " + filepath.ToSlash(filepath.Join(root, "target.txt")),
+			}
+			if got := readTree(t, root); !reflect.DeepEqual(got, want) {
+				t.Errorf("移动后的目录 = %v\n应为 %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMoveDirCrossDevice(t *testing.T) {
+	modTime := time.Date(2022, 8, 15, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		dstExists  bool // 目标位置已有非空目录，复制完成后无法替换
+		wantErr    bool
+		wantSubdir string // 移动后目录所在位置
+	}{
+		{"复制整个目录并删除源目录", false, false, "相册/假期"},
+		{"无法替换目标时删除复制的内容", true, true, "假期"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			src, dst := filepath.Join(root, "假期"), filepath.Join(root, "相册", "假期")
+			writeFile(t, filepath.Join(src, "1.jpg"), "1")
+			writeFile(t, filepath.Join(src, "sub", "2.jpg"), "2")
+			symlink(t, "1.jpg", filepath.Join(src, "cover.jpg"))
+			if err := os.Chtimes(src, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+			mkdirAll(t, filepath.Dir(dst))
+			if tt.dstExists {
+				writeFile(t, filepath.Join(dst, "old.jpg"), "old")
+			}
+			failRename(t, syscall.EXDEV)
+
+			err := moveDir(src, dst)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("moveDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			want := map[string]string{"相册/": ""}
+			for rel, content := range map[string]string{"": "", "1.jpg": "1", "sub/": "", "sub/2.jpg": "2", "cover.jpg": "-> 1.jpg"} {
+				want[tt.wantSubdir+"/"+rel] = content
+			}
+			if tt.dstExists {
+				want["相册/假期/"], want["相册/假期/old.jpg"] = "", "old"
+			}
+			if got := readTree(t, root); !reflect.DeepEqual(got, want) {
+				t.Fatalf("移动后的目录 = %v\n应为 %v", got, want)
+			}
+
+			// 子项复制完成后才设置目录时间，不会被写入操作覆盖
+			info, err := os.Stat(filepath.Join(root, filepath.FromSlash(tt.wantSubdir)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !info.ModTime().Equal(modTime) {
+				t.Errorf("目录修改时间 = %v，应为 %v", info.ModTime(), modTime)
+			}
+		})
+	}
+}
+
+// failRename 让移动时首先尝试的重命名返回 errno，测试结束后恢复
+func failRename(t *testing.T, errno error) {
+	t.Helper()
+	old := rename
+	rename = func(src, dst string) error {
+		return &os.LinkError{Op: "rename", Old: src, New: dst, Err: errno}
+	}
+	t.Cleanup(func() { rename = old })
+}
+
+// readTree 返回目录下所有文件的内容，目录以 / 结尾、内容为空，符号链接记录链接目标，特殊文件只记录类型
+func readTree(t *testing.T, root string) map[string]string {
+	t.Helper()
+	tree := map[string]string{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == root {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+		switch {
+		case d.IsDir():
+			tree[rel+"/"] = ""
+		case d.Type()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			tree[rel] = "-> " + filepath.ToSlash(target)
+		case !d.Type().IsRegular():
+			tree[rel] = d.Type().String()
+		default:
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			tree[rel] = string(data)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
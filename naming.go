@@ -0,0 +1,356 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// NamingConfig 定义分类名称的语言和格式
+type NamingConfig struct {
+	Language      string `json:"language,omitempty"`       // 分类名称语言：zh-CN（默认）、zh-TW、en、ja、match（与文件名相同）
+	Style         string `json:"style,omitempty"`          // 命名风格：空为保持模型返回的名称，title 为 Title Case，snake 为 snake_case
+	NumericPrefix bool   `json:"numeric_prefix,omitempty"` // 分类名称前加 01_ 这样的序号，便于排序
+	Transliterate string `json:"transliterate,omitempty"`  // 转写：pinyin 将汉字转为拼音，ascii 只保留 ASCII 字符
+}
+
+// 命名风格
+const (
+	NamingStyleTitle = "title"
+	NamingStyleSnake = "snake"
+)
+
+// 转写方式
+const (
+	TransliteratePinyin = "pinyin"
+	TransliterateASCII  = "ascii"
+)
+
+// categoryLanguage 一种分类语言在提示词中的说明和固定分类的名称
+type categoryLanguage struct {
+	prompt       string // 提示词中的语言说明
+	other        string // 无法判断时使用的分类
+	unclassified string // 模型遗漏的文件
+	duplicates   string // 重复副本
+}
+
+// categoryLanguages 支持的分类语言
+var categoryLanguages = map[string]categoryLanguage{
+	"zh-CN": {prompt: "简体中文", other: "其他", unclassified: "未分类", duplicates: "重复文件"},
+	"zh-TW": {prompt: "繁體中文", other: "其他", unclassified: "未分類", duplicates: "重複檔案"},
+	"en":    {prompt: "English", other: "Other", unclassified: "Unclassified", duplicates: "Duplicates"},
+	"ja":    {prompt: "日本語", other: "その他", unclassified: "未分類", duplicates: "重複ファイル"},
+	"match": {prompt: "与文件名相同的语言（文件名语言不一致时使用中文）", other: "其他", unclassified: "未分类", duplicates: "重复文件"},
+}
+
+// defaultCategoryLanguage 默认的分类语言
+const defaultCategoryLanguage = "zh-CN"
+
+// numberedCategoryPattern 匹配带序号前缀的分类，如 03_照片
+var numberedCategoryPattern = regexp.MustCompile(`^(\d{2,})_(.+)$`)
+
+// Validate 检查命名配置是否有效
+func (n NamingConfig) Validate() error {
+	if _, ok := categoryLanguages[n.Language]; n.Language != "" && !ok {
+		return fmt.Errorf("不支持的分类语言: %s（可选 zh-CN、zh-TW、en、ja、match）", n.Language)
+	}
+	switch n.Style {
+	case "", NamingStyleTitle, NamingStyleSnake:
+	default:
+		return fmt.Errorf("不支持的命名风格: %s（可选 title、snake）", n.Style)
+	}
+	switch n.Transliterate {
+	case "", TransliteratePinyin, TransliterateASCII:
+	default:
+		return fmt.Errorf("不支持的转写方式: %s（可选 pinyin、ascii）", n.Transliterate)
+	}
+	return nil
+}
+
+// language 返回分类语言的说明和固定分类名称
+func (n NamingConfig) language() categoryLanguage {
+	if lang, ok := categoryLanguages[n.Language]; ok {
+		return lang
+	}
+	return categoryLanguages[defaultCategoryLanguage]
+}
+
+// UnclassifiedCategory 模型遗漏的文件使用的分类
+func (n NamingConfig) UnclassifiedCategory() string {
+	return n.language().unclassified
+}
+
+// DuplicatesCategory 重复副本使用的分类，按命名配置格式化，不加序号
+func (n NamingConfig) DuplicatesCategory() string {
+	return n.Format(n.language().duplicates)
+}
+
+// Format 按转写方式和命名风格格式化分类名称，不处理序号
+func (n NamingConfig) Format(name string) string {
+	formatted := strings.TrimSpace(name)
+	if n.Transliterate != "" {
+		formatted = transliterate(formatted, n.Transliterate)
+	}
+	switch n.Style {
+	case NamingStyleTitle:
+		formatted = titleCase(formatted)
+	case NamingStyleSnake:
+		formatted = snakeCase(formatted)
+	}
+	if formatted == "" {
+		return name
+	}
+	return formatted
+}
+
+// Apply 按命名配置重命名分类结果，格式化后同名的分类会被合并
+// 使用序号时沿用整理目录和已有分类 existing 中的序号，新分类依次编号，"其他"和"未分类"排在最后
+func (n NamingConfig) Apply(root string, classified map[string][]FileInfo, existing []string) map[string][]FileInfo {
+	if n.Style == "" && n.Transliterate == "" && !n.NumericPrefix {
+		return classified
+	}
+
+	renamed := make(map[string]string, len(classified))
+	for category := range classified {
+		base := category
+		if m := numberedCategoryPattern.FindStringSubmatch(category); m != nil && n.NumericPrefix {
+			base = m[2]
+		}
+		renamed[category] = n.Format(base)
+	}
+
+	if n.NumericPrefix {
+		numbered, next := existingNumberedCategories(root, existing)
+		lang := n.language()
+		last := map[string]bool{n.Format(lang.other): true, n.Format(lang.unclassified): true}
+
+		names := make([]string, 0, len(renamed))
+		seen := make(map[string]bool)
+		for _, name := range renamed {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if last[names[i]] != last[names[j]] {
+				return !last[names[i]]
+			}
+			return names[i] < names[j]
+		})
+		for _, name := range names {
+			if _, ok := numbered[name]; !ok {
+				numbered[name] = fmt.Sprintf("%02d_%s", next, name)
+				next++
+			}
+		}
+		for category, name := range renamed {
+			renamed[category] = numbered[name]
+		}
+	}
+
+	result := make(map[string][]FileInfo, len(classified))
+	for category, files := range classified {
+		name := renamed[category]
+		for _, file := range files {
+			file.Category = name
+			result[name] = append(result[name], file)
+		}
+	}
+	return result
+}
+
+// existingNumberedCategories 收集整理目录下和已有分类中带序号的分类，返回 名称->带序号名称 和下一个可用序号
+func existingNumberedCategories(root string, existing []string) (map[string]string, int) {
+	candidates := append([]string(nil), existing...)
+	if entries, err := os.ReadDir(root); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() {
+				candidates = append(candidates, entry.Name())
+			}
+		}
+	}
+
+	numbered := make(map[string]string)
+	next := 1
+	for _, candidate := range candidates {
+		m := numberedCategoryPattern.FindStringSubmatch(candidate)
+		if m == nil {
+			continue
+		}
+		if _, ok := numbered[m[2]]; !ok {
+			numbered[m[2]] = candidate
+		}
+		if num, err := strconv.Atoi(m[1]); err == nil && num >= next {
+			next = num + 1
+		}
+	}
+	return numbered, next
+}
+
+// transliterate 将名称转写为拉丁字母
+// pinyin 只转写汉字，每个音节首字母大写；ascii 还会转写假名、去掉变音符号和其他非 ASCII 字符
+func transliterate(name, mode string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.Is(unicode.Han, r):
+			if py, ok := pinyinTable[r]; ok {
+				b.WriteString(strings.ToUpper(py[:1]) + py[1:])
+			} else {
+				fmt.Fprintf(&b, "U%X", r)
+			}
+		case mode != TransliterateASCII || r < 0x80:
+			b.WriteRune(r)
+		case isKana(r):
+			// 连续的假名作为一个词转写
+			j := i
+			for j < len(runes) && isKana(runes[j]) {
+				j++
+			}
+			romaji := kanaToRomaji(runes[i:j])
+			if romaji != "" {
+				b.WriteString(strings.ToUpper(romaji[:1]) + romaji[1:])
+			}
+			i = j - 1
+		case r >= 0xFF01 && r <= 0xFF5E:
+			// 全角字母、数字和符号
+			b.WriteRune(r - 0xFEE0)
+		case r == '　' || r == '、' || r == '，' || r == '・':
+			b.WriteByte(' ')
+		default:
+			if folded, ok := latinFold[r]; ok {
+				b.WriteString(folded)
+			}
+		}
+	}
+
+	result := b.String()
+	if mode == TransliterateASCII {
+		// 旧系统和 Windows 不允许的字符
+		result = strings.Map(func(r rune) rune {
+			if strings.ContainsRune(`<>:"/\|?*`, r) || r < 0x20 {
+				return '_'
+			}
+			return r
+		}, result)
+		result = strings.TrimRight(strings.Join(strings.Fields(result), " "), ". ")
+	}
+	return result
+}
+
+func isKana(r rune) bool {
+	return (r >= 0x3041 && r <= 0x3094) || (r >= 0x30A1 && r <= 0x30F4) || r == 'ー'
+}
+
+// kanaToRomaji 按平文式罗马字转写一段假名，长音符号省略
+func kanaToRomaji(kana []rune) string {
+	var out []string
+	double := false // 促音，重复下一个辅音
+	for _, r := range kana {
+		if r == 'ー' {
+			continue
+		}
+		if r >= 0x30A1 {
+			r -= 0x60
+		}
+		romaji := kanaRomaji[r-0x3041]
+		switch r {
+		case 'っ':
+			double = true
+			continue
+		case 'ゃ', 'ゅ', 'ょ':
+			// 拗音：きゃ -> kya，しゃ -> sha
+			if n := len(out); n > 0 && strings.HasSuffix(out[n-1], "i") {
+				prev := strings.TrimSuffix(out[n-1], "i")
+				if prev == "sh" || prev == "ch" || prev == "j" {
+					romaji = romaji[1:]
+				}
+				out[n-1] = prev + romaji
+				continue
+			}
+		case 'ぁ', 'ぃ', 'ぅ', 'ぇ', 'ぉ':
+			// 外来语：ファ -> fa，ティ -> ti
+			if n := len(out); n > 0 && len(out[n-1]) > 1 {
+				out[n-1] = out[n-1][:len(out[n-1])-1] + romaji
+				continue
+			}
+		}
+		if double && romaji != "" {
+			romaji = romaji[:1] + romaji
+			double = false
+		}
+		out = append(out, romaji)
+	}
+	return strings.Join(out, "")
+}
+
+// splitWords 按空格、下划线、连字符和大小写变化拆分单词，汉字等没有大小写的字符连在一起作为一个词
+func splitWords(name string) []string {
+	var words []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			words = append(words, string(current))
+			current = nil
+		}
+	}
+
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsSpace(r) || r == '_' || r == '-' {
+			flush()
+			continue
+		}
+		if len(current) > 0 {
+			prev := current[len(current)-1]
+			switch {
+			case unicode.IsLower(prev) && unicode.IsUpper(r):
+				// wenDang -> wen Dang
+				flush()
+			case unicode.IsUpper(prev) && unicode.IsUpper(r) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+				// PDFWen -> PDF Wen
+				flush()
+			case isCasedOrDigit(prev) != isCasedOrDigit(r):
+				// PDF文档 -> PDF 文档
+				flush()
+			}
+		}
+		current = append(current, r)
+	}
+	flush()
+	return words
+}
+
+func isCasedOrDigit(r rune) bool {
+	return r < 0x80 || unicode.IsUpper(r) || unicode.IsLower(r)
+}
+
+// titleCase 每个单词首字母大写，全大写的缩写保持不变
+func titleCase(name string) string {
+	words := splitWords(name)
+	for i, word := range words {
+		if strings.ToUpper(word) == word {
+			continue
+		}
+		runes := []rune(strings.ToLower(word))
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
+
+// snakeCase 单词转为小写并用下划线连接
+func snakeCase(name string) string {
+	words := splitWords(name)
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return strings.Join(words, "_")
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		name, mode, want string
+	}{
+		{"照片", TransliteratePinyin, "ZhaoPian"},
+		{"工作文档", TransliteratePinyin, "GongZuoWenDang"},
+		{"PDF文档", TransliteratePinyin, "PDFWenDang"},
+		{"重复文件", TransliteratePinyin, "ChongFuWenJian"}, // 多音字取分类中常见的读音
+		{"音乐", TransliteratePinyin, "YinYue"},
+		{"電腦檔案", TransliteratePinyin, "DianNaoDangAn"},  // 繁体
+		{"龘", TransliteratePinyin, "U9F98"},             // 不在表中的汉字按编码转写
+		{"Café照片", TransliteratePinyin, "CaféZhaoPian"}, // pinyin 只转写汉字
+		{"ファイル", TransliteratePinyin, "ファイル"},
+		{"Café照片", TransliterateASCII, "CafeZhaoPian"},
+		{"Ørsted Straße", TransliterateASCII, "Orsted Strasse"},
+		{"ファイル", TransliterateASCII, "Fairu"},      // 外来语的小写假名
+		{"きょう", TransliterateASCII, "Kyou"},        // 拗音
+		{"しゃしん", TransliterateASCII, "Shashin"},    // sh 开头的拗音
+		{"ちょっと", TransliterateASCII, "Chotto"},     // 促音
+		{"ミュージック", TransliterateASCII, "Myujikku"}, // 长音省略
+		{"写真　フォルダ", TransliterateASCII, "XieZhen Foruda"},
+		{"ＡＢＣ１", TransliterateASCII, "ABC1"},   // 全角字符
+		{"a/b:c", TransliterateASCII, "a_b_c"}, // Windows 不允许的字符
+		{"docs.  ", TransliterateASCII, "docs"},
+		{"  多个   空格  ", TransliterateASCII, "DuoGe KongGe"},
+	}
+	for _, tt := range tests {
+		if got := transliterate(tt.name, tt.mode); got != tt.want {
+			t.Errorf("transliterate(%q, %s) = %q，应为 %q", tt.name, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestPinyinTable(t *testing.T) {
+	// 简体和繁体的读音相同
+	pairs := [][2]rune{{'档', '檔'}, {'视', '視'}, {'乐', '樂'}, {'图', '圖'}}
+	for _, pair := range pairs {
+		simplified, ok1 := pinyinTable[pair[0]]
+		traditional, ok2 := pinyinTable[pair[1]]
+		if !ok1 || !ok2 || simplified != traditional {
+			t.Errorf("%c=%q，%c=%q，应相同", pair[0], simplified, pair[1], traditional)
+		}
+	}
+	for r, py := range pinyinTable {
+		if py == "" {
+			t.Errorf("%c 的拼音为空", r)
+		}
+	}
+}
+
+func TestNamingFormat(t *testing.T) {
+	tests := []struct {
+		config NamingConfig
+		name   string
+		want   string
+	}{
+		{NamingConfig{}, " 照片 ", "照片"},
+		{NamingConfig{Style: NamingStyleTitle}, "my photos", "My Photos"},
+		{NamingConfig{Style: NamingStyleTitle}, "PDF文档", "PDF 文档"},
+		{NamingConfig{Style: NamingStyleTitle}, "HTTPServer", "HTTP Server"},
+		{NamingConfig{Style: NamingStyleSnake}, "Work Documents", "work_documents"},
+		{NamingConfig{Style: NamingStyleSnake}, "wenDang-2024", "wen_dang_2024"},
+		{NamingConfig{Transliterate: TransliteratePinyin, Style: NamingStyleSnake}, "工作文档", "gong_zuo_wen_dang"},
+		{NamingConfig{Transliterate: TransliteratePinyin, Style: NamingStyleTitle}, "工作文档", "Gong Zuo Wen Dang"},
+		{NamingConfig{Transliterate: TransliterateASCII}, "。。。", "。。。"}, // 转写后为空时保留原名
+	}
+	for _, tt := range tests {
+		if got := tt.config.Format(tt.name); got != tt.want {
+			t.Errorf("%+v Format(%q) = %q，应为 %q", tt.config, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNamingApply(t *testing.T) {
+	files := func(paths ...string) []FileInfo {
+		var result []FileInfo
+		for _, path := range paths {
+			result = append(result, FileInfo{Path: path})
+		}
+		return result
+	}
+
+	tests := []struct {
+		name       string
+		config     NamingConfig
+		dirs       []string // 整理目录中已有的目录
+		existing   []string // 增量整理时已有的分类
+		classified map[string][]FileInfo
+		want       map[string][]string
+	}{
+		{
+			name:       "没有设置时保持原样",
+			classified: map[string][]FileInfo{"照片": files("a.jpg")},
+			want:       map[string][]string{"照片": {"a.jpg"}},
+		},
+		{
+			name:       "格式化后同名的分类合并",
+			config:     NamingConfig{Style: NamingStyleSnake},
+			classified: map[string][]FileInfo{"Work Docs": files("a.txt"), "work_docs": files("b.txt")},
+			want:       map[string][]string{"work_docs": {"a.txt", "b.txt"}},
+		},
+		{
+			name:       "序号按名称排列，其他和未分类在最后",
+			config:     NamingConfig{NumericPrefix: true},
+			classified: map[string][]FileInfo{"其他": files("x"), "文档": files("a.txt"), "图片": files("b.jpg"), "未分类": files("y")},
+			want:       map[string][]string{"01_图片": {"b.jpg"}, "02_文档": {"a.txt"}, "03_其他": {"x"}, "04_未分类": {"y"}},
+		},
+		{
+			name:       "沿用目录中已有的序号",
+			config:     NamingConfig{NumericPrefix: true},
+			dirs:       []string{"03_照片", "notes"},
+			classified: map[string][]FileInfo{"照片": files("a.jpg"), "文档": files("b.txt")},
+			want:       map[string][]string{"03_照片": {"a.jpg"}, "04_文档": {"b.txt"}},
+		},
+		{
+			name:       "沿用已有分类的序号，模型返回的序号被忽略",
+			config:     NamingConfig{NumericPrefix: true},
+			existing:   []string{"07_文档"},
+			classified: map[string][]FileInfo{"01_文档": files("a.txt"), "音乐": files("b.mp3")},
+			want:       map[string][]string{"07_文档": {"a.txt"}, "08_音乐": {"b.mp3"}},
+		},
+		{
+			name:       "转写后编号",
+			config:     NamingConfig{NumericPrefix: true, Transliterate: TransliteratePinyin},
+			classified: map[string][]FileInfo{"照片": files("a.jpg")},
+			want:       map[string][]string{"01_ZhaoPian": {"a.jpg"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for _, dir := range tt.dirs {
+				mkdirAll(t, filepath.Join(root, dir))
+			}
+			result := tt.config.Apply(root, tt.classified, tt.existing)
+
+			got := make(map[string][]string)
+			for category, files := range result {
+				for _, file := range files {
+					if file.Category != category && (tt.config != NamingConfig{}) {
+						t.Errorf("%s 的 Category = %q，应为 %q", file.Path, file.Category, category)
+					}
+					got[category] = append(got[category], file.Path)
+				}
+				sort.Strings(got[category])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apply = %v，应为 %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+)
+
+// OrganizerOptions 整理选项，不为空时覆盖配置文件中的设置
+type OrganizerOptions struct {
+	Provider    string // 为空时使用配置中的默认提供者
+	Template    string
+	Prompt      string
+	Duplicates  string
+	Symlinks    string
+	Conflict    string
+	Dirs        bool // 顶层子目录作为整体分类和移动，不递归处理
+	NoCache     bool
+	Incremental bool // 只处理上次整理后新增或修改的文件
+}
+
+// OrganizerHooks 命令行和图形界面在整理过程中接入的回调，均可以为nil
+// 整理流程本身不输出任何内容，需要显示的信息通过这些回调、进度事件或日志交给前端
+type OrganizerHooks struct {
+	// ScanWarnings 显示扫描时跳过的文件和目录，为nil时记录为警告日志
+	ScanWarnings func(warnings []string)
+	// Duplicates 显示找到的重复文件，为nil时只记录日志
+	Duplicates func(groups []DuplicateGroup)
+	// Classified 分类完成后、审查前调用，用于显示分类摘要
+	Classified func(classified map[string][]FileInfo)
+	// Review 在生成移动计划前审查分类结果，返回修改后的结果
+	Review func(map[string][]FileInfo) (map[string][]FileInfo, error)
+	// Confirm 移动前预览移动计划，返回false时取消移动
+	Confirm func([]MoveOp) bool
+	// AskConflict 冲突策略为 ask 时询问用户，为nil时按 rename 处理
+	AskConflict func(src, dst string) (policy string, applyToAll bool)
+}
+
+// errNoProvider 调用 OpenProvider 之前分类时返回
+var errNoProvider = errors.New("尚未创建模型提供者，请先调用 OpenProvider")
+
+// Organizer 整理流程：校验配置、创建模型、扫描、分类并移动文件
+// 命令行、watch 和图形界面使用同一个流程，只通过 Hooks 接入各自的交互
+type Organizer struct {
+	Config   *Config
+	Template *PathTemplate
+	Options  OrganizerOptions
+	Hooks    OrganizerHooks
+
+	// 以下字段只属于这个 Organizer，每次运行重新创建，不会影响之后的运行
+	prompt       *PromptTemplate
+	cache        *ClassificationCache // 为nil时不使用缓存
+	provider     LLMProvider
+	providerName string
+}
+
+// ScanResult 一次扫描的结果
+type ScanResult struct {
+	Files    []FileInfo
+	State    *OrganizeState
+	Found    int      // 扫描到的文件数，增量模式下包括未变化的文件
+	Existing []string // 增量模式下已整理过的分类，分类时新文件优先归入这些分类
+}
+
+// NewOrganizer 将选项应用到配置并在扫描前校验，加载分类使用的提示词
+// 日志由调用方按各自的方式设置
+func NewOrganizer(config *Config, opts OrganizerOptions) (*Organizer, error) {
+	config.Scan.DirsAsUnits = opts.Dirs
+	if opts.Template != "" {
+		config.PathTemplate = opts.Template
+	}
+	tmpl, err := config.GetPathTemplate()
+	if err != nil {
+		return nil, fmt.Errorf("路径模板无效: %v", err)
+	}
+	if opts.Duplicates != "" {
+		config.DuplicatePolicy = opts.Duplicates
+	}
+	if err := validateDuplicatePolicy(config.DuplicatePolicy); err != nil {
+		return nil, err
+	}
+	if opts.Symlinks != "" {
+		config.Scan.SymlinkPolicy = opts.Symlinks
+	}
+	if err := validateSymlinkPolicy(config.Scan.SymlinkPolicy); err != nil {
+		return nil, err
+	}
+	if _, err := NewIgnoreMatcher(config.Scan.IgnorePatterns); err != nil {
+		return nil, fmt.Errorf("全局忽略规则无效: %v", err)
+	}
+	if opts.Conflict != "" {
+		config.Conflict.Policy = opts.Conflict
+	}
+	if err := config.Conflict.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Prompt != "" {
+		config.Prompt.Name = opts.Prompt
+	}
+	if err := config.Naming.Validate(); err != nil {
+		return nil, err
+	}
+	prompt, err := config.promptConfig().LoadPrompt()
+	if err != nil {
+		return nil, err
+	}
+	return &Organizer{Config: config, Template: tmpl, Options: opts, prompt: prompt}, nil
+}
+
+// PromptID 返回分类使用的提示词版本
+func (g *Organizer) PromptID() string {
+	return g.prompt.ID()
+}
+
+// OpenProvider 校验并创建大模型提供者，同时打开分类缓存
+func (g *Organizer) OpenProvider() (LLMProvider, error) {
+	name := g.Options.Provider
+	if name == "" {
+		name = g.Config.DefaultProvider
+	}
+	if errs := configErrors(g.Config.ValidateProvider(name)); len(errs) > 0 {
+		lines := make([]string, len(errs))
+		for i, issue := range errs {
+			lines[i] = "  " + issue.String()
+		}
+		return nil, fmt.Errorf("模型 %s 的配置有误，可运行 doctor 命令检查：\n%s", name, strings.Join(lines, "\n"))
+	}
+
+	provider, err := newProviderFromConfig(g.Config, name)
+	if err != nil {
+		return nil, err
+	}
+	g.cache = nil
+	if !g.Options.NoCache {
+		cache, err := g.Config.OpenCache()
+		if err != nil {
+			slog.Warn("打开分类缓存失败，将不使用缓存", "error", err)
+		} else {
+			g.cache = cache
+		}
+	}
+	g.provider, g.providerName = provider, name
+	return provider, nil
+}
+
+// Scan 扫描整理目录并读取整理状态；增量模式下只返回新增或修改的文件
+func (g *Organizer) Scan(root string) (*ScanResult, error) {
+	// 先读取整理状态，上次整理出的分类目录不会被当作相册或项目整体移动
+	state, err := LoadOrganizeState(root)
+	if err != nil {
+		return nil, err
+	}
+	opts := g.Config.Scan
+	opts.Categories, opts.PlacedDirs = g.categoryNames(state), state.PlacedDirs()
+
+	files, warnings, err := getFileList(root, opts)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件列表失败: %v", err)
+	}
+	switch {
+	case len(warnings) == 0:
+	case g.Hooks.ScanWarnings != nil:
+		g.Hooks.ScanWarnings(warnings)
+	default:
+		for _, warning := range warnings {
+			slog.Warn("扫描警告", "warning", warning)
+		}
+	}
+
+	// 增量模式下只处理新增或修改的文件
+	result := &ScanResult{Files: files, State: state, Found: len(files)}
+	if g.Options.Incremental {
+		result.Files, result.Existing = state.Diff(files)
+	}
+
+	// 只在打开了分类缓存时需要内容哈希
+	if g.cache != nil && g.Config.Cache.HashContent {
+		fillContentHashes(root, result.Files)
+	}
+	return result, nil
+}
+
+// categoryNames 返回状态中已有的分类、配置限定的分类，以及"其他""未分类"和重复文件使用的分类
+func (g *Organizer) categoryNames(state *OrganizeState) map[string]bool {
+	naming := g.Config.Naming
+	lang := naming.language()
+	names := map[string]bool{
+		naming.Format(lang.other):     true,
+		naming.UnclassifiedCategory(): true,
+		naming.DuplicatesCategory():   true,
+	}
+	for _, category := range state.Categories() {
+		names[category] = true
+	}
+	for _, category := range g.Config.Prompt.Taxonomy {
+		names[category] = true
+		names[naming.Format(category)] = true
+	}
+	return names
+}
+
+// Classify 使用模型分类并按命名规则整理分类名称，existing 为优先使用的已有分类；取消 ctx 时返回 ctx.Err()
+func (g *Organizer) Classify(ctx context.Context, root string, files []FileInfo, existing []string) (map[string][]FileInfo, error) {
+	if g.provider == nil {
+		return nil, errNoProvider
+	}
+	emitProgress(ProgressEvent{Kind: EventClassifyStarted, Root: root, Total: len(files)})
+	classifiedFiles, err := g.provider.ClassifyFiles(ctx, files, ClassifyOptions{
+		Prompt:       g.prompt,
+		Existing:     existing,
+		Cache:        g.cache,
+		Unclassified: g.Config.Naming.UnclassifiedCategory(),
+	})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("分类失败: %v", err)
+	}
+	return g.Config.Naming.Apply(root, classifiedFiles, existing), nil
+}
+
+// Plan 查找重复文件、调用模型分类，并按路径模板生成移动计划；需要先调用 OpenProvider
+// existing 为优先使用的已有分类；取消 ctx 时返回 ctx.Err()，Review 返回的错误原样返回
+func (g *Organizer) Plan(ctx context.Context, root string, files []FileInfo, existing []string) (*PlanFile, error) {
+	if g.provider == nil {
+		return nil, errNoProvider
+	}
+	if len(files) == 0 {
+		return g.newPlan(root, nil), nil
+	}
+
+	// 查找重复文件，多余的副本不发送给模型
+	policy := g.Config.DuplicatePolicy
+	var duplicateGroups []DuplicateGroup
+	if policy != "" && policy != DuplicatePolicyOff {
+		groups, err := findDuplicates(root, files)
+		if err != nil {
+			return nil, fmt.Errorf("查找重复文件失败: %v", err)
+		}
+		if g.Hooks.Duplicates != nil && len(groups) > 0 {
+			g.Hooks.Duplicates(groups)
+		} else if len(groups) > 0 {
+			slog.Info("发现重复文件", "groups", len(groups))
+		}
+		files = withoutDuplicateCopies(files, groups)
+		duplicateGroups = groups
+	}
+
+	// 使用大模型对文件进行分类
+	classifiedFiles, err := g.Classify(ctx, root, files, existing)
+	if err != nil {
+		return nil, err
+	}
+	if g.Hooks.Classified != nil {
+		g.Hooks.Classified(classifiedFiles)
+	}
+
+	if g.Hooks.Review != nil {
+		classifiedFiles, err = g.Hooks.Review(classifiedFiles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 按路径模板生成移动计划，重复副本排在最后，保证硬链接时保留的文件已经就位
+	ops, err := buildMovePlan(root, classifiedFiles, g.Template)
+	if err != nil {
+		return nil, fmt.Errorf("生成移动计划失败: %v", err)
+	}
+	duplicateOps, err := duplicateMoveOps(root, duplicateGroups, classifiedFiles, policy, g.Template, g.Config.Naming.DuplicatesCategory())
+	if err != nil {
+		return nil, fmt.Errorf("生成移动计划失败: %v", err)
+	}
+	return g.newPlan(root, append(ops, duplicateOps...)), nil
+}
+
+// newPlan 生成记录了所用模型的移动计划
+func (g *Organizer) newPlan(root string, ops []MoveOp) *PlanFile {
+	modelName, _, _ := g.provider.GetConfig()
+	plan := newPlanFile(root, modelName, g.prompt.ID(), ops)
+	plan.Provider = g.providerName
+	return plan
+}
+
+// Apply 执行移动计划并记录运行日志，移动后删除因此变空的源目录
+// report 为nil时新建；分类前创建报告可以把分类的耗时和用量计入报告
+// 返回的运行日志在没有任何操作时为nil
+func (g *Organizer) Apply(ctx context.Context, plan *PlanFile, state *OrganizeState, report *RunReport) (*RunReport, *RunJournal, error) {
+	ops, err := plan.moveOps()
+	if err != nil {
+		return nil, nil, err
+	}
+	if report == nil {
+		report = NewRunReport(plan.Root)
+	}
+	report.Root, report.Provider, report.Model, report.PromptVersion = plan.Root, plan.Provider, plan.Model, plan.PromptVersion
+
+	resolver := NewConflictResolver(g.Config.Conflict)
+	resolver.Ask = g.Hooks.AskConflict
+
+	journal, err := StartRunJournal(plan.Root, plan.Provider, plan.Model)
+	if err != nil {
+		slog.Warn("本次整理将无法撤销", "error", err)
+	}
+	applyMoves(ctx, plan.Root, ops, organizeOptions{
+		State:     state,
+		Conflicts: resolver,
+		Journal:   journal,
+		Report:    report,
+		Confirm:   g.Hooks.Confirm,
+	})
+	journal.Finish()
+	if journal == nil || len(journal.Entries) == 0 {
+		journal = nil
+	} else {
+		report.RunID = journal.ID
+	}
+
+	// 只删除本次移走文件后留下的空目录，原本就为空的目录保持不变
+	for _, file := range report.Files {
+		if file.Status == FileMoved {
+			removeEmptyParents(filepath.Dir(filepath.Join(plan.Root, filepath.FromSlash(file.Path))), plan.Root)
+		}
+	}
+	return report, journal, nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultPathTemplate 默认目标路径模板，等价于原来的 分类/文件名
+const defaultPathTemplate = "{category}/{name}{ext}"
+
+// pathTemplateFields 路径模板支持的字段及说明
+var pathTemplateFields = map[string]string{
+	"category":    "大模型给出的分类名称",
+	"name":        "不含扩展名的文件名",
+	"ext":         "扩展名（含点，如 .jpg）",
+	"base":        "完整文件名",
+	"dir":         "文件原来所在的相对目录",
+	"year":        "修改时间的年份",
+	"month":       "修改时间的月份",
+	"day":         "修改时间的日期",
+	"taken_year":  "拍摄/录制年份（EXIF或音频标签，缺失时使用修改时间）",
+	"taken_month": "拍摄月份（EXIF，缺失时使用修改时间）",
+	"taken_day":   "拍摄日期（EXIF，缺失时使用修改时间）",
+	"camera":      "相机型号（EXIF）",
+	"artist":      "艺术家（音频标签）",
+	"album":       "专辑（音频标签）",
+}
+
+// metadataFields 需要读取文件内容才能得到的字段
+var metadataFields = map[string]bool{
+	"taken_year":  true,
+	"taken_month": true,
+	"taken_day":   true,
+	"camera":      true,
+	"artist":      true,
+	"album":       true,
+}
+
+// unknownFieldValue 元数据缺失时使用的占位值
+const unknownFieldValue = "未知"
+
+// PathTemplate 已解析的目标路径模板
+type PathTemplate struct {
+	Raw   string
+	parts []templatePart
+}
+
+type templatePart struct {
+	literal string
+	field   string
+}
+
+// ParsePathTemplate 解析并校验路径模板
+func ParsePathTemplate(raw string) (*PathTemplate, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("路径模板不能为空")
+	}
+	if strings.HasPrefix(raw, "/") || strings.HasPrefix(raw, "\\") || filepath.IsAbs(raw) {
+		return nil, fmt.Errorf("路径模板必须是相对路径: %s", raw)
+	}
+
+	tmpl := &PathTemplate{Raw: raw}
+	rest := raw
+	for rest != "" {
+		open := strings.IndexAny(rest, "{}")
+		if open == -1 {
+			tmpl.parts = append(tmpl.parts, templatePart{literal: rest})
+			break
+		}
+		if rest[open] == '}' {
+			return nil, fmt.Errorf("路径模板中存在多余的 '}': %s", raw)
+		}
+		if open > 0 {
+			tmpl.parts = append(tmpl.parts, templatePart{literal: rest[:open]})
+		}
+		end := strings.IndexAny(rest[open+1:], "{}")
+		if end == -1 || rest[open+1+end] != '}' {
+			return nil, fmt.Errorf("路径模板中的 '{' 未闭合: %s", raw)
+		}
+		field := strings.TrimSpace(rest[open+1 : open+1+end])
+		if _, ok := pathTemplateFields[field]; !ok {
+			return nil, fmt.Errorf("路径模板中存在未知字段 {%s}，可用字段: %s", field, strings.Join(templateFieldNames(), ", "))
+		}
+		tmpl.parts = append(tmpl.parts, templatePart{field: field})
+		rest = rest[open+1+end+1:]
+	}
+
+	// 检查路径片段，禁止跳出整理目录
+	segments := strings.FieldsFunc(raw, func(r rune) bool { return r == '/' || r == '\\' })
+	for _, segment := range segments {
+		if segment == ".." {
+			return nil, fmt.Errorf("路径模板不能包含 '..': %s", raw)
+		}
+	}
+
+	// 最后一级必须包含文件名，否则所有文件会落到同一路径
+	last := segments[len(segments)-1]
+	if !strings.Contains(last, "{name}") && !strings.Contains(last, "{base}") {
+		return nil, fmt.Errorf("路径模板的最后一级必须包含 {name} 或 {base}: %s", raw)
+	}
+
+	return tmpl, nil
+}
+
+// templateFieldNames 返回排序后的可用字段名
+func templateFieldNames() []string {
+	names := make([]string, 0, len(pathTemplateFields))
+	for name := range pathTemplateFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// usesMetadata 判断模板是否需要读取文件元数据
+func (t *PathTemplate) usesMetadata() bool {
+	for _, part := range t.parts {
+		if metadataFields[part.field] {
+			return true
+		}
+	}
+	return false
+}
+
+// Render 根据字段值生成相对目标路径
+func (t *PathTemplate) Render(fields map[string]string) (string, error) {
+	var b strings.Builder
+	for _, part := range t.parts {
+		if part.field == "" {
+			b.WriteString(part.literal)
+			continue
+		}
+		value := fields[part.field]
+		if part.field != "dir" {
+			value = sanitizePathSegment(value)
+		}
+		b.WriteString(value)
+	}
+
+	// 开头的字段为空时（如根目录中文件的 {dir}）会留下开头的分隔符，模板本身不允许绝对路径
+	rendered := strings.TrimLeft(strings.ReplaceAll(b.String(), "\\", "/"), "/")
+	rendered = filepath.Clean(filepath.FromSlash(rendered))
+	if rendered == "." || rendered == "" {
+		return "", fmt.Errorf("模板生成的路径为空")
+	}
+	if rendered == ".." || strings.HasPrefix(rendered, ".."+string(filepath.Separator)) || filepath.IsAbs(rendered) {
+		return "", fmt.Errorf("模板生成的路径超出整理目录: %s", rendered)
+	}
+	return rendered, nil
+}
+
+// sanitizePathSegment 去掉字段值中会改变目录层级的字符
+func sanitizePathSegment(value string) string {
+	value = strings.TrimSpace(value)
+	value = strings.NewReplacer("/", "_", "\\", "_", "\x00", "").Replace(value)
+	if value == "." || value == ".." {
+		return "_"
+	}
+	return value
+}
+
+// fileTemplateFields 收集单个文件可用于模板的字段值
+func fileTemplateFields(root string, file FileInfo, withMetadata bool) map[string]string {
+	base := filepath.Base(file.Path)
+	ext := filepath.Ext(base)
+	// 目录名中的点通常不是扩展名，只保留应用程序包等目录的后缀
+	if file.IsDir && !hasAnySuffix(strings.ToLower(base), bundleDirSuffixes) {
+		ext = ""
+	}
+	dir := filepath.Dir(file.Path)
+	if dir == "." {
+		dir = ""
+	}
+
+	fields := map[string]string{
+		"category": file.Category,
+		"name":     strings.TrimSuffix(base, ext),
+		"ext":      ext,
+		"base":     base,
+		"dir":      filepath.ToSlash(dir),
+	}
+
+	modTime := file.ModTime
+	if !modTime.IsZero() {
+		fields["year"] = modTime.Format("2006")
+		fields["month"] = modTime.Format("01")
+		fields["day"] = modTime.Format("02")
+	} else {
+		fields["year"] = unknownFieldValue
+		fields["month"] = unknownFieldValue
+		fields["day"] = unknownFieldValue
+	}
+
+	if !withMetadata {
+		return fields
+	}
+
+	metadata, err := readFileMetadata(filepath.Join(root, file.Path))
+	if err != nil {
+		metadata = map[string]string{}
+	}
+	for _, key := range []string{"taken_year", "taken_month", "taken_day"} {
+		if metadata[key] == "" {
+			metadata[key] = fields[strings.TrimPrefix(key, "taken_")]
+		}
+	}
+	for key := range metadataFields {
+		if metadata[key] == "" {
+			metadata[key] = unknownFieldValue
+		}
+		fields[key] = metadata[key]
+	}
+	return fields
+}
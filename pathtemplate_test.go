@@ -0,0 +1,154 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePathTemplate(t *testing.T) {
+	tests := []struct {
+		raw     string
+		wantErr string // 为空表示应解析成功
+	}{
+		{defaultPathTemplate, ""},
+		{"{year}/{month}/{category}/{base}", ""},
+		{"照片/{taken_year}/{camera}/{name}_{day}{ext}", ""},
+		{`{category}\{name}{ext}`, ""},
+		{"", "不能为空"},
+		{"/{category}/{name}", "相对路径"},
+		{`\{category}\{name}`, "相对路径"},
+		{"{unknown}/{name}", "未知字段"},
+		{"{category/{name}", "未闭合"},
+		{"{category", "未闭合"},
+		{"category}/{name}", "多余的 '}'"},
+		{"../{name}{ext}", "'..'"},
+		{"{category}/../{base}", "'..'"},
+		{"{category}", "最后一级"},
+		{"{name}/{category}", "最后一级"},
+	}
+	for _, tt := range tests {
+		tmpl, err := ParsePathTemplate(tt.raw)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("ParsePathTemplate(%q): %v", tt.raw, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("ParsePathTemplate(%q) 错误 = %v，应包含 %q", tt.raw, err, tt.wantErr)
+		case err == nil && tmpl.Raw != strings.TrimSpace(tt.raw):
+			t.Errorf("ParsePathTemplate(%q).Raw = %q", tt.raw, tmpl.Raw)
+		}
+	}
+}
+
+func TestPathTemplateRender(t *testing.T) {
+	fields := map[string]string{"category": "文档", "name": "a", "ext": ".txt", "base": "a.txt", "dir": "x/y", "year": "2024"}
+	tests := []struct {
+		name     string
+		template string
+		override map[string]string
+		want     string
+		wantErr  bool
+	}{
+		{"默认模板", defaultPathTemplate, nil, "文档/a.txt", false},
+		{"保留原目录", "{dir}/{base}", nil, "x/y/a.txt", false},
+		{"根目录中的文件没有原目录", "{dir}/{category}/{base}", map[string]string{"dir": ""}, "文档/a.txt", false},
+		{"字段中的 / 不会增加目录层级", defaultPathTemplate, map[string]string{"category": "工作/合同"}, "工作_合同/a.txt", false},
+		{"字段中的 \\ 不会增加目录层级", defaultPathTemplate, map[string]string{"category": `a\b`}, "a_b/a.txt", false},
+		{"字段为 .. 时替换", defaultPathTemplate, map[string]string{"category": ".."}, "_/a.txt", false},
+		{"字段首尾空白被去掉", defaultPathTemplate, map[string]string{"category": " 图片 "}, "图片/a.txt", false},
+		{"字面量中的 \\ 作为分隔符", `{year}\{base}`, nil, "2024/a.txt", false},
+		{"生成的路径为空", "{dir}/{base}", map[string]string{"dir": "", "base": ""}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParsePathTemplate(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			values := make(map[string]string)
+			for k, v := range fields {
+				values[k] = v
+			}
+			for k, v := range tt.override {
+				values[k] = v
+			}
+			got, err := tmpl.Render(values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误 = %v，wantErr = %v", err, tt.wantErr)
+			}
+			if got != filepath.FromSlash(tt.want) {
+				t.Errorf("Render = %q，应为 %q", got, filepath.FromSlash(tt.want))
+			}
+		})
+	}
+}
+
+func TestPathTemplateUsesMetadata(t *testing.T) {
+	tests := []struct {
+		template string
+		want     bool
+	}{
+		{defaultPathTemplate, false},
+		{"{year}/{month}/{base}", false},
+		{"{taken_year}/{base}", true},
+		{"{artist}/{album}/{base}", true},
+	}
+	for _, tt := range tests {
+		tmpl, err := ParsePathTemplate(tt.template)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tmpl.usesMetadata(); got != tt.want {
+			t.Errorf("%s: usesMetadata = %v，应为 %v", tt.template, got, tt.want)
+		}
+	}
+}
+
+func TestFileTemplateFields(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "docs", "report.final.pdf"), "pdf")
+	modTime := time.Date(2023, 4, 5, 6, 7, 8, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		file     FileInfo
+		metadata bool
+		want     map[string]string
+	}{
+		{
+			name: "子目录中的文件",
+			file: FileInfo{Path: filepath.Join("docs", "report.final.pdf"), Category: "文档", ModTime: modTime},
+			want: map[string]string{"name": "report.final", "ext": ".pdf", "base": "report.final.pdf", "dir": "docs", "category": "文档", "year": "2023", "month": "04", "day": "05"},
+		},
+		{
+			name: "目录名中的点不是扩展名",
+			file: FileInfo{Path: "Project.v2", IsDir: true},
+			want: map[string]string{"name": "Project.v2", "ext": "", "dir": "", "year": unknownFieldValue},
+		},
+		{
+			name: "应用程序包保留后缀",
+			file: FileInfo{Path: "Tool.app", IsDir: true},
+			want: map[string]string{"name": "Tool", "ext": ".app"},
+		},
+		{
+			name:     "没有元数据时拍摄时间使用修改时间",
+			file:     FileInfo{Path: filepath.Join("docs", "report.final.pdf"), ModTime: modTime},
+			metadata: true,
+			want:     map[string]string{"taken_year": "2023", "taken_month": "04", "taken_day": "05", "camera": unknownFieldValue, "artist": unknownFieldValue},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := fileTemplateFields(root, tt.file, tt.metadata)
+			for key, want := range tt.want {
+				if fields[key] != want {
+					t.Errorf("%s = %q，应为 %q", key, fields[key], want)
+				}
+			}
+			if _, ok := fields["camera"]; ok != tt.metadata {
+				t.Errorf("只有需要元数据时才读取，camera 存在 = %v", ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import "strings"
+
+// pinyinData 分类名称中常用汉字（简体和繁体）的拼音，不带声调
+// 多音字取分类名称中最常见的读音，如 重（重复）、行（行政）、乐（音乐）
+// 每行为 拼音 + 空格 + 汉字，不在表中的汉字按 Unicode 编码转写
+const pinyinData = `
+a 阿
+ai 爱愛
+an 安按案
+ba 八把吧巴
+bai 白百
+ban 办辦版班板半
+bang 帮幫
+bao 包报報保宝寶
+bei 备備被北
+ben 本
+bi 笔筆比必币幣壁
+bian 编編变變便边邊
+biao 表标標
+bie 别別
+bing 并病
+bo 播博
+bu 部不布步簿
+cai 财財采採彩材才菜
+can 参參
+cang 藏
+cao 草
+ce 测測策册冊
+cha 查插
+chan 产產
+chang 场場常长長厂廠
+chao 超
+che 车車
+cheng 程成城
+chi 尺持
+chong 重充宠寵
+chu 出处處
+chuan 传傳
+chuang 创創
+ci 词詞磁
+cun 存
+da 大打答
+dai 代带帶待
+dan 单單
+dang 档檔当當
+dao 导導到
+de 的得
+deng 等灯燈
+di 地第底递遞
+dian 电電点點店典
+diao 调調
+ding 订訂定
+dong 动動东東
+du 读讀度
+duan 短段
+dui 对對
+duo 多
+er 儿兒二
+fa 发發法
+fan 范範翻
+fang 方房放
+fei 费費
+fen 分份
+feng 封风風
+fu 复複服副付府
+gai 改
+gan 感
+gao 告稿高
+ge 个個格歌
+gong 工公共功
+gou 购購
+gu 故古
+guan 管关關馆館
+gui 归歸规規
+guo 国國过過果
+hai 海
+han 函
+hao 号號
+he 合和
+hu 户戶护護
+hua 画畫划劃化话話华華
+huan 幻换換
+hui 会會绘繪汇匯
+huo 活火
+ji 机機记記计計集级級籍纪紀际際基辑輯季
+jia 家价價加
+jian 件简簡建间間检檢健键鍵
+jiang 讲講
+jiao 教交脚腳
+jie 截节節结結介接
+jin 金进進
+jing 镜鏡景经經
+jiu 九旧舊
+ju 据據具剧劇局
+juan 卷
+ka 卡
+kai 开開
+kang 康
+kao 考
+ke 课課科客可
+kong 空控
+ku 库庫
+kuai 快
+la 拉
+lan 览覽
+lao 老
+lei 类類
+li 历歷曆理礼禮例
+lian 链鏈联聯练練
+liao 料疗療聊
+lie 列
+lin 临臨
+ling 零
+liu 六流
+lu 录錄路
+lun 论論
+luo 络絡
+lv 旅律
+ma 码碼
+mai 买買卖賣
+man 漫
+mei 美媒
+men 门門
+mi 密
+mian 面
+miao 描
+min 民
+ming 名明
+mo 模
+mu 目木幕
+nao 脑腦
+nei 内內
+ni 拟擬
+nian 年
+pai 拍排
+pan 盘盤
+pei 配培
+pi 批
+pian 片
+piao 票
+pin 品频頻聘
+ping 屏评評
+pu 普谱譜
+qi 七其器期企汽
+qian 签簽前
+qing 清情
+qiu 求
+qu 曲区區驱驅取
+quan 全券
+ren 人任
+ri 日
+rong 容
+ru 入
+ruan 软軟
+san 三散
+sao 扫掃
+she 设設社摄攝
+shen 身审審
+sheng 声聲生
+shi 十视視时時实實试試式事市识識室食
+shou 手收售
+shu 书書数數术術输輸
+shui 税稅
+shuo 说說
+si 四私司
+sou 搜
+su 素
+suo 缩縮所
+ta 它他
+tai 台
+tao 套
+te 特
+ti 体體题題
+tian 天
+tiao 条條
+tie 贴貼
+ting 庭
+tong 通统統同童
+tou 头頭投
+tu 图圖
+wai 外
+wan 完
+wang 网網
+wei 未微维維
+wen 文问問闻聞
+wu 五务務物无無
+xi 系习習戏戲息
+xia 下
+xian 线線现現险險
+xiang 项項相像箱享
+xiao 销銷小效
+xie 写寫协協
+xin 新信心
+xing 行型形
+xiu 修
+xu 序需虚虛
+xuan 宣选選
+xue 学學
+xun 训訓讯訊
+ya 压壓
+yan 演研验驗言
+yang 样樣
+yao 要药藥
+ye 页頁业業
+yi 一艺藝医醫仪儀议議译譯已
+yin 音银銀印
+ying 应應影营營硬螢
+yong 用
+you 游遊邮郵优優
+yu 语語娱娛域预預
+yuan 源原元
+yue 乐樂楽月约約
+yun 运運云雲
+za 杂雜
+zai 载載
+zhan 展站
+zhang 章账帳
+zhao 照招
+zhe 者
+zhen 真
+zheng 证證政整
+zhi 纸紙志誌知指制
+zhong 种種中
+zhou 周
+zhu 助主注
+zhuan 专專转轉
+zhuang 装裝
+zhuo 桌
+zi 资資字子自
+zong 综綜宗
+zu 组組
+zuo 作
+`
+
+// pinyinTable 汉字到拼音的映射
+var pinyinTable = func() map[rune]string {
+	table := make(map[rune]string)
+	for _, line := range strings.Split(pinyinData, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		for _, r := range fields[1] {
+			table[r] = fields[0]
+		}
+	}
+	return table
+}()
+
+// kanaRomaji 平假名 ぁ(U+3041) 到 ゔ(U+3094) 的罗马字，片假名按偏移量换算
+// 小写假名用于拗音和促音，转写时与前一个假名合并
+var kanaRomaji = strings.Fields(`
+a a i i u u e e o o ka ga ki gi ku gu ke ge ko go
+sa za shi ji su zu se ze so zo ta da chi ji - tsu zu te de to do
+na ni nu ne no ha ba pa hi bi pi fu bu pu he be pe ho bo po
+ma mi mu me mo ya ya yu yu yo yo ra ri ru re ro wa wa i e o n vu
+`)
+
+// latinFold 带变音符号的拉丁字母对应的 ASCII 字母
+var latinFold = func() map[rune]string {
+	pairs := []string{
+		"àáâãäåāă", "a", "ÀÁÂÃÄÅĀĂ", "A", "çćč", "c", "ÇĆČ", "C", "ďđ", "d", "ĎĐ", "D",
+		"èéêëēěę", "e", "ÈÉÊËĒĚĘ", "E", "ìíîïī", "i", "ÌÍÎÏĪ", "I", "ñńň", "n", "ÑŃŇ", "N",
+		"òóôõöøō", "o", "ÒÓÔÕÖØŌ", "O", "ùúûüūů", "u", "ÙÚÛÜŪŮ", "U", "ýÿ", "y", "ÝŸ", "Y",
+		"šś", "s", "ŠŚ", "S", "žźż", "z", "ŽŹŻ", "Z", "łľ", "l", "ŁĽ", "L", "řŕ", "r", "ŘŔ", "R",
+		"ß", "ss", "æ", "ae", "Æ", "AE", "œ", "oe", "Œ", "OE", "ťţ", "t", "ŤŢ", "T", "ğ", "g", "Ğ", "G",
+	}
+	table := make(map[rune]string)
+	for i := 0; i < len(pairs); i += 2 {
+		for _, r := range pairs[i] {
+			table[r] = pairs[i+1]
+		}
+	}
+	return table
+}()
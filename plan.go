@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MoveOp 描述一次计划中的文件移动
+type MoveOp struct {
+	File FileInfo
+	Src  string // 源文件完整路径
+	Dst  string // 按模板生成的目标完整路径
+
+	// LinkTo 不为空时表示这是重复副本，移动时改为创建指向该文件（相对路径）最终位置的硬链接
+	LinkTo string
+}
+
+// buildMovePlan 根据分类结果和路径模板生成移动计划
+func buildMovePlan(root string, classifiedFiles map[string][]FileInfo, tmpl *PathTemplate) ([]MoveOp, error) {
+	withMetadata := tmpl.usesMetadata()
+
+	var ops []MoveOp
+	for _, files := range classifiedFiles {
+		for _, file := range files {
+			relDst, err := tmpl.Render(fileTemplateFields(root, file, withMetadata))
+			if err != nil {
+				return nil, fmt.Errorf("生成 %s 的目标路径失败: %v", file.Path, err)
+			}
+			ops = append(ops, MoveOp{
+				File: file,
+				Src:  filepath.Join(root, file.Path),
+				Dst:  filepath.Join(root, relDst),
+			})
+		}
+	}
+
+	// 目录先移动，避免其他文件先放入与目录同名的分类后被一起带走
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].File.IsDir != ops[j].File.IsDir {
+			return ops[i].File.IsDir
+		}
+		if ops[i].File.Category != ops[j].File.Category {
+			return ops[i].File.Category < ops[j].File.Category
+		}
+		return ops[i].File.Path < ops[j].File.Path
+	})
+	return ops, nil
+}
+
+// printMovePlan 打印移动计划的预览
+func printMovePlan(w io.Writer, root string, ops []MoveOp) {
+	fmt.Fprintf(w, "\n移动预览（共 %d 个文件）：\n", len(ops))
+	for _, op := range ops {
+		relDst, err := filepath.Rel(root, op.Dst)
+		if err != nil {
+			relDst = op.Dst
+		}
+		if op.File.IsDir {
+			fmt.Fprintf(w, "  %s/ -> %s/（整个目录）\n", op.File.Path, relDst)
+			continue
+		}
+		fmt.Fprintf(w, "  %s -> %s\n", op.File.Path, relDst)
+	}
+}
+
+// PlanFile 保存到文件的移动计划，可以在检查后用 apply -plan 执行
+type PlanFile struct {
+	Root          string        `json:"root"`
+	Model         string        `json:"model,omitempty"`
+	Provider      string        `json:"provider,omitempty"`
+	PromptVersion string        `json:"prompt_version,omitempty"` // 分类使用的提示词版本
+	CreatedAt     time.Time     `json:"created_at"`
+	Moves         []PlannedMove `json:"moves"`
+}
+
+// PlannedMove 计划中的一次移动，路径相对于整理目录
+type PlannedMove struct {
+	File   FileInfo `json:"file"`
+	Dst    string   `json:"dst"`
+	LinkTo string   `json:"link_to,omitempty"`
+}
+
+// newPlanFile 将移动计划转换为可保存的格式，promptVersion 为分类使用的提示词版本
+func newPlanFile(root, model, promptVersion string, ops []MoveOp) *PlanFile {
+	plan := &PlanFile{Root: root, Model: model, PromptVersion: promptVersion, CreatedAt: time.Now(), Moves: []PlannedMove{}}
+	for _, op := range ops {
+		relDst, err := filepath.Rel(root, op.Dst)
+		if err != nil {
+			relDst = op.Dst
+		}
+		plan.Moves = append(plan.Moves, PlannedMove{
+			File:   op.File,
+			Dst:    filepath.ToSlash(relDst),
+			LinkTo: op.LinkTo,
+		})
+	}
+	return plan
+}
+
+// loadPlanFile 读取保存的移动计划
+func loadPlanFile(path string) (*PlanFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取计划文件失败: %v", err)
+	}
+	var plan PlanFile
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("解析计划文件失败: %v", err)
+	}
+	if plan.Root == "" {
+		return nil, fmt.Errorf("计划文件中缺少整理目录")
+	}
+	return &plan, nil
+}
+
+// save 保存移动计划
+func (p *PlanFile) save(path string) error {
+	data, err := json.MarshalIndent(p, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// moveOps 将保存的计划还原为移动操作，拒绝指向整理目录之外的路径
+func (p *PlanFile) moveOps() ([]MoveOp, error) {
+	ops := make([]MoveOp, 0, len(p.Moves))
+	for _, move := range p.Moves {
+		for _, rel := range []string{move.File.Path, move.Dst} {
+			clean := filepath.Clean(filepath.FromSlash(rel))
+			if rel == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+				return nil, fmt.Errorf("计划中的路径无效: %s", rel)
+			}
+		}
+		ops = append(ops, MoveOp{
+			File:   move.File,
+			Src:    filepath.Join(p.Root, filepath.FromSlash(move.File.Path)),
+			Dst:    filepath.Join(p.Root, filepath.FromSlash(move.Dst)),
+			LinkTo: move.LinkTo,
+		})
+	}
+	return ops, nil
+}
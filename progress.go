@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// progressBarWidth 进度条的字符数
+const progressBarWidth = 30
+
+// progressRedrawInterval 进度条的最短刷新间隔
+const progressRedrawInterval = 100 * time.Millisecond
+
+// cliProgress 在终端中显示进度：终端中为单行刷新的进度条，重定向到文件时逐行输出
+type cliProgress struct {
+	w   io.Writer
+	tty bool
+
+	line     string // 当前显示的进度条
+	width    int    // 当前进度条的显示宽度，清除时用空格覆盖
+	drawnAt  time.Time
+	received int // 已完成的批次数，批次并发处理，序号不一定按顺序完成
+}
+
+// newCLIProgress 创建输出到 f 的进度显示
+func newCLIProgress(f *os.File) *cliProgress {
+	return &cliProgress{w: f, tty: isTerminal(f)}
+}
+
+// handle 处理一个进度事件
+func (p *cliProgress) handle(event ProgressEvent) {
+	switch event.Kind {
+	case EventScanStarted:
+		p.draw("正在扫描 "+event.Root, true)
+	case EventScanProgress:
+		p.draw(fmt.Sprintf("正在扫描，已找到 %d 个文件", event.Done), false)
+	case EventScanFinished:
+		p.clear()
+
+	case EventClassifyStarted:
+		p.println("正在使用模型进行分类...")
+	case EventChunkSent:
+		if !p.tty {
+			p.println(fmt.Sprintf("正在处理第 %d/%d 批文件（%d 个文件）", event.Chunk, event.Chunks, event.Files))
+			return
+		}
+		p.drawBar("分类", p.received, event.Chunks, "批", true)
+	case EventChunkFailed:
+		p.println(fmt.Sprintf("第 %d/%d 批文件分类失败: %s", event.Chunk, event.Chunks, event.Message))
+		fallthrough
+	case EventChunkReceived:
+		p.received++
+		if p.tty {
+			p.drawBar("分类", p.received, event.Chunks, "批", true)
+		}
+		if p.received == event.Chunks {
+			p.received = 0
+			p.clear()
+		}
+	case EventRetry:
+		p.println(fmt.Sprintf("API调用失败，第 %d 次重试: %s", event.Attempt, event.Message))
+
+	case EventMoveStarted:
+		if !p.tty {
+			p.println("开始移动文件...")
+			return
+		}
+		p.drawBar("移动", 0, event.Total, "", true)
+	case EventFileMoved:
+		if !p.tty {
+			message := event.Message
+			if message == "" {
+				message = "成功移动文件"
+			}
+			p.println(fmt.Sprintf("%s: %s -> %s", message, event.Path, event.Dst))
+			return
+		}
+		p.drawBar("移动", event.Done, event.Total, event.Path, event.Done == event.Total)
+	case EventFileSkipped:
+		p.println(fmt.Sprintf("跳过文件 %s: %s", event.Path, event.Message))
+		p.drawBar("移动", event.Done, event.Total, event.Path, true)
+	case EventFileFailed:
+		p.println(event.Message)
+		p.drawBar("移动", event.Done, event.Total, event.Path, true)
+	case EventRunFinished:
+		p.clear()
+		if event.Message != "" {
+			fmt.Fprintln(p.w, event.Message)
+		}
+	}
+}
+
+// drawBar 显示进度条，如 移动 [=======>      ] 12/40 a.txt，detail 为数量后显示的说明
+func (p *cliProgress) drawBar(label string, done, total int, detail string, force bool) {
+	if !p.tty || total <= 0 {
+		return
+	}
+	filled := done * progressBarWidth / total
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+	line := fmt.Sprintf("%s [%s] %d/%d", label, bar, done, total)
+	if detail != "" {
+		line += " " + detail
+	}
+	p.draw(line, force)
+}
+
+// draw 在当前行显示进度，非终端时不显示；未到刷新间隔时跳过，force 为 true 时总是刷新
+func (p *cliProgress) draw(line string, force bool) {
+	if !p.tty {
+		return
+	}
+	if !force && time.Since(p.drawnAt) < progressRedrawInterval {
+		return
+	}
+	line = truncateDisplay(line, 78)
+	width := displayWidth(line)
+	padding := ""
+	if width < p.width {
+		padding = strings.Repeat(" ", p.width-width)
+	}
+	fmt.Fprintf(p.w, "\r%s%s", line, padding)
+	p.line, p.width, p.drawnAt = line, width, time.Now()
+}
+
+// clear 清除进度条
+func (p *cliProgress) clear() {
+	if !p.tty || p.width == 0 {
+		return
+	}
+	fmt.Fprintf(p.w, "\r%s\r", strings.Repeat(" ", p.width))
+	p.line, p.width = "", 0
+}
+
+// println 在进度条上方输出一行，之后重新显示进度条
+func (p *cliProgress) println(text string) {
+	line := p.line
+	p.clear()
+	fmt.Fprintln(p.w, text)
+	if line != "" {
+		p.draw(line, true)
+	}
+}
+
+// truncateDisplay 将文本截断到指定的显示宽度以内，避免折行后无法刷新
+func truncateDisplay(s string, max int) string {
+	if displayWidth(s) <= max {
+		return s
+	}
+	width := 0
+	for i, r := range s {
+		w := displayWidth(string(r))
+		if width+w > max-3 {
+			return s[:i] + "..."
+		}
+		width += w
+	}
+	return s
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// PromptConfig 定义分类提示词的配置
+type PromptConfig struct {
+	Name     string   `json:"name,omitempty"`     // 模板名称，默认 default
+	Dir      string   `json:"dir,omitempty"`      // 自定义模板目录，默认在用户配置目录下的 fileclassify/prompts
+	Language string   `json:"language,omitempty"` // 提示词中的分类语言说明，默认按 naming.language 生成
+	Taxonomy []string `json:"taxonomy,omitempty"` // 限定使用的分类
+	Hints    []string `json:"hints,omitempty"`    // 额外的分类说明
+
+	other string // 无法判断时使用的分类，按 naming.language 生成
+}
+
+// defaultPromptName 默认使用的提示词模板
+const defaultPromptName = "default"
+
+// builtinPrompts 内置的提示词模板，第一行的 version 注释是模板版本，修改模板后需要递增
+var builtinPrompts = map[string]string{
+	"default": `{{/* version: 2 */ -}}
+请根据以下文件列表，将文件按照相似性进行分类。请使用{{.Language}}命名分类，并返回JSON格式的分类结果。
+文件列表：
+{{range .Files}}- {{.Path}}
+{{end}}
+请按照以下JSON格式返回分类结果：
+{
+    "分类名称1": ["文件路径1", "文件路径2", ...],
+    "分类名称2": ["文件路径1", "文件路径2", ...],
+    ...
+}
+
+注意：
+1. 请确保返回的是有效的JSON格式，不要包含任何其他文本
+2. 请确保所有文件都被分类，不要遗漏任何文件
+3. 如果文件内容不明确，可以将其归类到"{{.Other}}"类别
+{{- if .Taxonomy}}
+- 只能使用以下分类：{{join .Taxonomy "、"}}，都不合适时归入"{{.Other}}"
+{{- end}}
+{{- if .Existing}}
+- 目录中已有以下分类，请优先将文件归入这些分类，只有确实不合适时才新建分类：{{join .Existing "、"}}
+{{- end}}
+{{- range .Hints}}
+- {{.}}
+{{- end}}
+{{- if .Dirs}}
+
+以下条目是整个目录（代码项目、相册、应用程序等），请根据目录名称判断整个目录的用途进行分类，返回时保持原路径：{{join .Dirs "、"}}
+{{- end}}`,
+
+	"concise": `{{/* version: 2 */ -}}
+Group these files by similarity. Name categories in {{.Language}}. Reply with JSON only: {"category": ["path", ...]}. Every path must appear exactly once, unchanged; use "{{.Other}}" when unsure.
+{{- if .Taxonomy}}
+Allowed categories: {{join .Taxonomy ", "}}.
+{{- end}}
+{{- if .Existing}}
+Prefer existing categories: {{join .Existing ", "}}.
+{{- end}}
+{{- range .Hints}}
+{{.}}
+{{- end}}
+{{- if .Dirs}}
+These entries are whole directories, classify them by purpose: {{join .Dirs ", "}}
+{{- end}}
+Files:
+{{range .Files}}{{.Path}}
+{{end}}`,
+}
+
+// PromptData 渲染提示词模板时可用的变量
+type PromptData struct {
+	Files    []FileInfo // 本批次要分类的文件
+	Taxonomy []string   // 限定使用的分类
+	Language string     // 分类名称使用的语言
+	Other    string     // 无法判断时使用的分类
+	Hints    []string   // 额外的分类说明
+	Existing []string   // 磁盘上已有的分类，增量整理时使用
+	Dirs     []string   // 本批次中作为整体分类的目录
+}
+
+// PromptTemplate 解析后的提示词模板
+type PromptTemplate struct {
+	Name     string
+	Version  string // 模板中声明的版本，没有声明时为内容哈希
+	Source   string // 模板来源，内置模板为 builtin
+	Language string
+	Other    string
+	Taxonomy []string
+	Hints    []string
+	text     string
+	tmpl     *template.Template
+}
+
+// promptVersionPattern 匹配模板开头的 {{/* version: N */}} 注释
+var promptVersionPattern = regexp.MustCompile(`^\s*\{\{-?\s*/\*\s*version:\s*([^\s*]+)\s*\*/`)
+
+// promptDir 返回自定义模板目录
+func (p PromptConfig) promptDir() (string, error) {
+	if p.Dir != "" {
+		return expandHome(expandEnvRefs(p.Dir)), nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "fileclassify", "prompts"), nil
+}
+
+// LoadPrompt 按名称加载提示词模板：优先使用模板目录下的 <名称>.tmpl，其次是内置模板
+func (p PromptConfig) LoadPrompt() (*PromptTemplate, error) {
+	name := p.Name
+	if name == "" {
+		name = defaultPromptName
+	}
+	if strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("提示词模板名称无效: %s", name)
+	}
+
+	source, text := "builtin", ""
+	if dir, err := p.promptDir(); err == nil {
+		path := filepath.Join(dir, name+".tmpl")
+		if data, err := os.ReadFile(path); err == nil {
+			source, text = path, string(data)
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("读取提示词模板失败: %v", err)
+		}
+	}
+	if source == "builtin" {
+		builtin, ok := builtinPrompts[name]
+		if !ok {
+			names, _ := p.PromptNames()
+			return nil, fmt.Errorf("找不到提示词模板 %s，可选 %s", name, strings.Join(names, "、"))
+		}
+		text = builtin
+	}
+	return parsePrompt(name, source, text, p)
+}
+
+// parsePrompt 解析模板文本并读取其中声明的版本
+func parsePrompt(name, source, text string, p PromptConfig) (*PromptTemplate, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("解析提示词模板 %s 失败: %v", name, err)
+	}
+
+	version := ""
+	if m := promptVersionPattern.FindStringSubmatch(text); m != nil {
+		version = "v" + strings.TrimPrefix(m[1], "v")
+	} else {
+		sum := sha256.Sum256([]byte(text))
+		version = hex.EncodeToString(sum[:4])
+	}
+
+	lang := categoryLanguages[defaultCategoryLanguage]
+	language, other := p.Language, p.other
+	if language == "" {
+		language = lang.prompt
+	}
+	if other == "" {
+		other = lang.other
+	}
+	prompt := &PromptTemplate{
+		Name:     name,
+		Version:  version,
+		Source:   source,
+		Language: language,
+		Other:    other,
+		Taxonomy: p.Taxonomy,
+		Hints:    p.Hints,
+		text:     text,
+		tmpl:     tmpl,
+	}
+	// 用示例数据渲染一次，尽早发现模板中引用了不存在的变量
+	if _, err := prompt.Render([]FileInfo{{Path: "example.txt"}}, []string{"示例分类"}); err != nil {
+		return nil, err
+	}
+	return prompt, nil
+}
+
+// PromptNames 返回可用的模板名称，包括内置模板和模板目录中的 .tmpl 文件
+func (p PromptConfig) PromptNames() ([]string, error) {
+	seen := make(map[string]bool)
+	for name := range builtinPrompts {
+		seen[name] = true
+	}
+	dir, err := p.promptDir()
+	if err == nil {
+		paths, _ := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+		for _, path := range paths {
+			seen[strings.TrimSuffix(filepath.Base(path), ".tmpl")] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, err
+}
+
+// ID 返回记录在计划和缓存中的提示词版本，如 default@v1
+// 设置了语言、分类或说明时附加它们的哈希，设置不同的结果不会混用
+func (t *PromptTemplate) ID() string {
+	id := t.Name + "@" + t.Version
+	if t.Language == categoryLanguages[defaultCategoryLanguage].prompt && len(t.Taxonomy) == 0 && len(t.Hints) == 0 {
+		return id
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s", t.Language, strings.Join(t.Taxonomy, "\x01"), strings.Join(t.Hints, "\x01"))
+	return id + "+" + hex.EncodeToString(h.Sum(nil)[:4])
+}
+
+// Render 为一批文件生成提示词，existing 为磁盘上已有的分类，新文件会优先归入这些分类
+func (t *PromptTemplate) Render(files []FileInfo, existing []string) (string, error) {
+	data := PromptData{
+		Files:    files,
+		Taxonomy: t.Taxonomy,
+		Language: t.Language,
+		Other:    t.Other,
+		Hints:    t.Hints,
+		Existing: existing,
+	}
+	for _, file := range files {
+		if file.IsDir {
+			data.Dirs = append(data.Dirs, file.Path)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("渲染提示词模板 %s 失败: %v", t.Name, err)
+	}
+	return buf.String(), nil
+}
+
+// defaultPrompt 返回默认的提示词模板，未指定模板时使用
+func defaultPrompt() *PromptTemplate {
+	prompt, err := PromptConfig{}.LoadPrompt()
+	if err != nil {
+		// 用户目录中的 default.tmpl 有问题时退回内置模板
+		slog.Warn("加载提示词模板失败，使用内置模板", "error", err)
+		prompt, _ = parsePrompt(defaultPromptName, "builtin", builtinPrompts[defaultPromptName], PromptConfig{})
+	}
+	return prompt
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+)
+
+// RunReport 一次整理运行的记录
+type RunReport struct {
+	Root          string             `json:"root"`
+	RunID         string             `json:"run_id,omitempty"`
+	Provider      string             `json:"provider,omitempty"`
+	Model         string             `json:"model,omitempty"`
+	PromptVersion string             `json:"prompt_version,omitempty"`
+	StartedAt     time.Time          `json:"started_at"`
+	FinishedAt    time.Time          `json:"finished_at,omitempty"`
+	Moved         int                `json:"moved"`
+	Cancelled     bool               `json:"cancelled,omitempty"`
+	Files         []ReportFile       `json:"files,omitempty"`
+	Conflicts     []ConflictDecision `json:"conflicts,omitempty"`
+	Failures      []string           `json:"failures,omitempty"`
+	Tokens        TokenUsage         `json:"tokens"`
+
+	tokensAtStart TokenUsage
+}
+
+// 报告中文件的处理结果
+const (
+	FileMoved   = "moved"
+	FileSkipped = "skipped"
+	FileFailed  = "failed"
+)
+
+// ReportFile 一个文件的处理结果，路径相对于整理目录
+type ReportFile struct {
+	Path     string `json:"path"`
+	Dst      string `json:"dst,omitempty"`
+	Category string `json:"category"`
+	Status   string `json:"status"`
+	Reason   string `json:"reason,omitempty"`
+	IsDir    bool   `json:"is_dir,omitempty"`
+}
+
+// NewRunReport 创建运行记录，从创建时开始统计耗时和 token 用量
+func NewRunReport(root string) *RunReport {
+	return &RunReport{Root: root, StartedAt: time.Now(), tokensAtStart: currentTokenUsage()}
+}
+
+// AddFile 记录一个文件的处理结果
+func (r *RunReport) AddFile(file FileInfo, status, dst, reason string) {
+	r.Files = append(r.Files, ReportFile{
+		Path:     file.Path,
+		Dst:      dst,
+		Category: file.Category,
+		Status:   status,
+		Reason:   reason,
+		IsDir:    file.IsDir,
+	})
+}
+
+// Finish 记录结束时间和本次运行消耗的 token
+func (r *RunReport) Finish() {
+	r.FinishedAt = time.Now()
+	r.Tokens = currentTokenUsage().Sub(r.tokensAtStart)
+}
+
+// Duration 返回运行耗时
+func (r *RunReport) Duration() time.Duration {
+	if r.FinishedAt.IsZero() {
+		return time.Since(r.StartedAt)
+	}
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// AddConflict 记录一次冲突处理
+func (r *RunReport) AddConflict(decision ConflictDecision) {
+	r.Conflicts = append(r.Conflicts, decision)
+}
+
+// Failf 记录一个未能完成的操作，由调用方通过进度事件显示
+func (r *RunReport) Failf(format string, args ...interface{}) {
+	r.Failures = append(r.Failures, fmt.Sprintf(format, args...))
+}
+
+// PrintSummary 打印冲突处理和失败情况
+func (r *RunReport) PrintSummary(w io.Writer) {
+	if len(r.Failures) > 0 {
+		fmt.Fprintf(w, "\n有 %d 个操作失败，详见上方输出\n", len(r.Failures))
+	}
+	if len(r.Conflicts) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n目标冲突处理（共 %d 个）：\n", len(r.Conflicts))
+	for _, decision := range r.Conflicts {
+		line := fmt.Sprintf("- %s -> %s: %s", r.rel(decision.Src), r.rel(decision.Dst), conflictActionName(decision.Action))
+		if decision.FinalDst != "" && decision.FinalDst != decision.Dst {
+			line += " " + r.rel(decision.FinalDst)
+		}
+		if decision.Reason != "" {
+			line += "（" + decision.Reason + "）"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+func (r *RunReport) rel(path string) string {
+	if rel, err := filepath.Rel(r.Root, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// conflictActionName 冲突处理动作的中文名称
+func conflictActionName(action string) string {
+	switch action {
+	case ConflictSkip:
+		return "跳过"
+	case ConflictOverwrite:
+		return "覆盖"
+	case ConflictRename:
+		return "重命名为"
+	case ConflictDedupe:
+		return "删除源文件"
+	}
+	return action
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 运行报告的格式
+const (
+	reportMarkdown = "md"
+	reportHTML     = "html"
+	reportCSV      = "csv"
+	reportJSON     = "json"
+)
+
+// reportFormatByExt 按文件扩展名判断报告格式
+var reportFormatByExt = map[string]string{
+	".md":       reportMarkdown,
+	".markdown": reportMarkdown,
+	".html":     reportHTML,
+	".htm":      reportHTML,
+	".csv":      reportCSV,
+	".json":     reportJSON,
+}
+
+// reportDocumentVersion JSON 报告的格式版本，字段含义变化时递增
+const reportDocumentVersion = 1
+
+// reportDocument 导出的报告内容，JSON 报告直接使用该结构，字段名保持稳定供脚本读取
+type reportDocument struct {
+	Version         int              `json:"version"`
+	Root            string           `json:"root"`
+	RunID           string           `json:"run_id,omitempty"`
+	Provider        string           `json:"provider,omitempty"`
+	Model           string           `json:"model,omitempty"`
+	PromptVersion   string           `json:"prompt_version,omitempty"`
+	StartedAt       time.Time        `json:"started_at"`
+	FinishedAt      time.Time        `json:"finished_at"`
+	DurationSeconds float64          `json:"duration_seconds"`
+	Cancelled       bool             `json:"cancelled"`
+	Tokens          TokenUsage       `json:"tokens"`
+	Summary         reportSummary    `json:"summary"`
+	Categories      []reportCategory `json:"categories"`
+	Conflicts       []reportConflict `json:"conflicts"`
+	Skipped         []ReportFile     `json:"skipped"`
+	Failed          []ReportFile     `json:"failed"`
+	Errors          []string         `json:"errors"` // 没有对应文件的失败，如创建目录失败
+}
+
+// reportSummary 各种结果的文件数
+type reportSummary struct {
+	Files   int `json:"files"`
+	Moved   int `json:"moved"`
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+}
+
+// reportCategory 一个分类及其中的文件
+type reportCategory struct {
+	Name  string       `json:"name"`
+	Files []ReportFile `json:"files"`
+}
+
+// reportConflict 一次目标冲突的处理，路径相对于整理目录
+type reportConflict struct {
+	Src      string `json:"src"`
+	Dst      string `json:"dst"`
+	Action   string `json:"action"`
+	FinalDst string `json:"final_dst,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// document 整理报告内容：文件按分类分组，分类按名称排序，分类中的文件保持移动顺序
+func (r *RunReport) document() reportDocument {
+	doc := reportDocument{
+		Version:         reportDocumentVersion,
+		Root:            r.Root,
+		RunID:           r.RunID,
+		Provider:        r.Provider,
+		Model:           r.Model,
+		PromptVersion:   r.PromptVersion,
+		StartedAt:       r.StartedAt,
+		FinishedAt:      r.FinishedAt,
+		DurationSeconds: r.Duration().Round(time.Millisecond).Seconds(),
+		Cancelled:       r.Cancelled,
+		Tokens:          r.Tokens,
+		Categories:      []reportCategory{},
+		Conflicts:       []reportConflict{},
+		Skipped:         []ReportFile{},
+		Failed:          []ReportFile{},
+		Errors:          []string{},
+	}
+
+	byCategory := make(map[string]int)
+	failures := make(map[string]bool)
+	for _, file := range r.Files {
+		doc.Summary.Files++
+		switch file.Status {
+		case FileMoved:
+			doc.Summary.Moved++
+		case FileSkipped:
+			doc.Summary.Skipped++
+			doc.Skipped = append(doc.Skipped, file)
+		case FileFailed:
+			doc.Summary.Failed++
+			doc.Failed = append(doc.Failed, file)
+			failures[file.Reason] = true
+		}
+		i, ok := byCategory[file.Category]
+		if !ok {
+			i = len(doc.Categories)
+			byCategory[file.Category] = i
+			doc.Categories = append(doc.Categories, reportCategory{Name: file.Category})
+		}
+		doc.Categories[i].Files = append(doc.Categories[i].Files, file)
+	}
+	sort.SliceStable(doc.Categories, func(i, j int) bool { return doc.Categories[i].Name < doc.Categories[j].Name })
+
+	for _, decision := range r.Conflicts {
+		conflict := reportConflict{
+			Src:    r.rel(decision.Src),
+			Dst:    r.rel(decision.Dst),
+			Action: decision.Action,
+			Reason: decision.Reason,
+		}
+		if decision.FinalDst != "" && decision.FinalDst != decision.Dst {
+			conflict.FinalDst = r.rel(decision.FinalDst)
+		}
+		doc.Conflicts = append(doc.Conflicts, conflict)
+	}
+	for _, failure := range r.Failures {
+		if !failures[failure] {
+			doc.Errors = append(doc.Errors, failure)
+		}
+	}
+	return doc
+}
+
+// validateReportPath 检查能否根据扩展名判断报告格式
+func validateReportPath(path string) error {
+	if _, ok := reportFormatByExt[strings.ToLower(filepath.Ext(path))]; !ok {
+		return fmt.Errorf("无法根据扩展名判断报告格式: %s（可选 .md、.html、.csv、.json）", path)
+	}
+	return nil
+}
+
+// WriteReport 按文件扩展名将报告保存为 Markdown、HTML、CSV 或 JSON
+func (r *RunReport) WriteReport(path string) error {
+	if err := validateReportPath(path); err != nil {
+		return err
+	}
+	format := reportFormatByExt[strings.ToLower(filepath.Ext(path))]
+
+	var buf bytes.Buffer
+	var err error
+	doc := r.document()
+	switch format {
+	case reportMarkdown:
+		writeReportMarkdown(&buf, doc)
+	case reportHTML:
+		err = reportHTMLTemplate.Execute(&buf, doc)
+	case reportCSV:
+		err = writeReportCSV(&buf, doc)
+	case reportJSON:
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(doc)
+	}
+	if err != nil {
+		return fmt.Errorf("生成报告失败: %v", err)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("创建报告目录失败: %v", err)
+		}
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("保存报告失败: %v", err)
+	}
+	return nil
+}
+
+// reportStatusName 文件处理结果的中文名称
+func reportStatusName(status string) string {
+	switch status {
+	case FileMoved:
+		return "已移动"
+	case FileSkipped:
+		return "已跳过"
+	case FileFailed:
+		return "失败"
+	}
+	return status
+}
+
+// writeReportMarkdown 生成 Markdown 报告，文件名中的 | 会破坏表格，需要转义
+func writeReportMarkdown(buf *bytes.Buffer, doc reportDocument) {
+	cell := func(s string) string {
+		return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+	}
+
+	fmt.Fprintf(buf, "# 整理报告\n\n")
+	fmt.Fprintf(buf, "| 项目 | 内容 |\n| --- | --- |\n")
+	fmt.Fprintf(buf, "| 目录 | %s |\n", cell(doc.Root))
+	if doc.RunID != "" {
+		fmt.Fprintf(buf, "| 运行编号 | %s |\n", doc.RunID)
+	}
+	fmt.Fprintf(buf, "| 模型 | %s |\n", cell(reportModelName(doc)))
+	if doc.PromptVersion != "" {
+		fmt.Fprintf(buf, "| 提示词版本 | %s |\n", cell(doc.PromptVersion))
+	}
+	fmt.Fprintf(buf, "| 开始时间 | %s |\n", doc.StartedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(buf, "| 耗时 | %s |\n", formatReportDuration(doc.DurationSeconds))
+	fmt.Fprintf(buf, "| Token | %d（输入 %d，输出 %d，%d 次请求） |\n", doc.Tokens.TotalTokens, doc.Tokens.PromptTokens, doc.Tokens.CompletionTokens, doc.Tokens.Requests)
+	fmt.Fprintf(buf, "| 文件 | 共 %d 个，移动 %d，跳过 %d，失败 %d |\n", doc.Summary.Files, doc.Summary.Moved, doc.Summary.Skipped, doc.Summary.Failed)
+	if doc.Cancelled {
+		fmt.Fprintf(buf, "| 状态 | 已取消 |\n")
+	}
+
+	for _, category := range doc.Categories {
+		fmt.Fprintf(buf, "\n## %s（%d）\n\n", category.Name, len(category.Files))
+		fmt.Fprintf(buf, "| 原路径 | 新路径 | 结果 |\n| --- | --- | --- |\n")
+		for _, file := range category.Files {
+			status := reportStatusName(file.Status)
+			if file.Reason != "" {
+				status += "：" + file.Reason
+			}
+			fmt.Fprintf(buf, "| %s | %s | %s |\n", cell(file.Path), cell(file.Dst), cell(status))
+		}
+	}
+
+	if len(doc.Conflicts) > 0 {
+		fmt.Fprintf(buf, "\n## 目标冲突\n\n| 来源 | 目标 | 处理 | 说明 |\n| --- | --- | --- | --- |\n")
+		for _, c := range doc.Conflicts {
+			action := conflictActionName(c.Action)
+			if c.FinalDst != "" {
+				action += " " + c.FinalDst
+			}
+			fmt.Fprintf(buf, "| %s | %s | %s | %s |\n", cell(c.Src), cell(c.Dst), cell(action), cell(c.Reason))
+		}
+	}
+	if len(doc.Errors) > 0 {
+		fmt.Fprintf(buf, "\n## 其他错误\n\n")
+		for _, e := range doc.Errors {
+			fmt.Fprintf(buf, "- %s\n", e)
+		}
+	}
+}
+
+// writeReportCSV 每个文件一行，便于在表格软件中筛选
+func writeReportCSV(buf *bytes.Buffer, doc reportDocument) error {
+	w := csv.NewWriter(buf)
+	w.Write([]string{"category", "status", "path", "dst", "reason"})
+	for _, category := range doc.Categories {
+		for _, file := range category.Files {
+			w.Write([]string{category.Name, file.Status, file.Path, file.Dst, file.Reason})
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// reportModelName 显示用的模型名称，如 deepseek / deepseek-chat
+func reportModelName(doc reportDocument) string {
+	switch {
+	case doc.Provider != "" && doc.Model != "":
+		return doc.Provider + " / " + doc.Model
+	case doc.Model != "":
+		return doc.Model
+	}
+	return doc.Provider
+}
+
+// formatReportDuration 将秒数显示为 1m23s 这样的格式
+func formatReportDuration(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(100 * time.Millisecond).String()
+}
+
+// reportHTMLTemplate HTML 报告模板，样式内联，可以直接作为附件打开
+var reportHTMLTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"status":   reportStatusName,
+	"action":   conflictActionName,
+	"model":    reportModelName,
+	"duration": formatReportDuration,
+	"time":     func(t time.Time) string { return t.Format("2006-01-02 15:04:05") },
+}).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>整理报告 - {{.Root}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "Microsoft YaHei", sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 0.5em 0 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: left; font-size: 14px; }
+th { background: #f3f3f3; }
+tr.skipped td { color: #8a6d00; }
+tr.failed td { color: #b00020; }
+h2 { margin-top: 1.5em; }
+</style>
+</head>
+<body>
+<h1>整理报告</h1>
+<table>
+<tr><th>目录</th><td>{{.Root}}</td></tr>
+{{- if .RunID}}
+<tr><th>运行编号</th><td>{{.RunID}}</td></tr>
+{{- end}}
+<tr><th>模型</th><td>{{model .}}</td></tr>
+{{- if .PromptVersion}}
+<tr><th>提示词版本</th><td>{{.PromptVersion}}</td></tr>
+{{- end}}
+<tr><th>开始时间</th><td>{{time .StartedAt}}</td></tr>
+<tr><th>耗时</th><td>{{duration .DurationSeconds}}</td></tr>
+<tr><th>Token</th><td>{{.Tokens.TotalTokens}}（输入 {{.Tokens.PromptTokens}}，输出 {{.Tokens.CompletionTokens}}，{{.Tokens.Requests}} 次请求）</td></tr>
+<tr><th>文件</th><td>共 {{.Summary.Files}} 个，移动 {{.Summary.Moved}}，跳过 {{.Summary.Skipped}}，失败 {{.Summary.Failed}}</td></tr>
+{{- if .Cancelled}}
+<tr><th>状态</th><td>已取消</td></tr>
+{{- end}}
+</table>
+{{range .Categories}}
+<h2>{{.Name}}（{{len .Files}}）</h2>
+<table>
+<tr><th>原路径</th><th>新路径</th><th>结果</th></tr>
+{{- range .Files}}
+<tr class="{{.Status}}"><td>{{.Path}}</td><td>{{.Dst}}</td><td>{{status .Status}}{{if .Reason}}：{{.Reason}}{{end}}</td></tr>
+{{- end}}
+</table>
+{{end}}
+{{- if .Conflicts}}
+<h2>目标冲突</h2>
+<table>
+<tr><th>来源</th><th>目标</th><th>处理</th><th>说明</th></tr>
+{{- range .Conflicts}}
+<tr><td>{{.Src}}</td><td>{{.Dst}}</td><td>{{action .Action}}{{if .FinalDst}} {{.FinalDst}}{{end}}</td><td>{{.Reason}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Errors}}
+<h2>其他错误</h2>
+<ul>
+{{- range .Errors}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+`))
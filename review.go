@@ -0,0 +1,602 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// errReviewCancelled 在审查界面中取消
+var errReviewCancelled = errors.New("已取消审查")
+
+// reviewCategory 审查中的一个分类
+type reviewCategory struct {
+	name     string
+	files    []FileInfo
+	expanded bool
+}
+
+// reviewRow 列表中的一行，file 为 -1 时是分类行
+type reviewRow struct {
+	category int
+	file     int
+}
+
+// classificationReview 审查分类结果时的状态，与终端界面分开，便于在其他界面中复用
+type classificationReview struct {
+	categories []*reviewCategory
+	excluded   map[string]bool // 被排除的文件路径，不会被移动
+	cursor     int
+	offset     int
+	message    string
+}
+
+// newClassificationReview 按分类名称排序，分类中的文件按路径排序
+func newClassificationReview(classified map[string][]FileInfo) *classificationReview {
+	r := &classificationReview{excluded: make(map[string]bool)}
+	for name, files := range classified {
+		files = append([]FileInfo(nil), files...)
+		sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+		r.categories = append(r.categories, &reviewCategory{name: name, files: files})
+	}
+	r.sortCategories()
+	return r
+}
+
+func (r *classificationReview) sortCategories() {
+	sort.SliceStable(r.categories, func(i, j int) bool { return r.categories[i].name < r.categories[j].name })
+}
+
+// rows 返回当前显示的行，展开的分类下列出其中的文件
+func (r *classificationReview) rows() []reviewRow {
+	var rows []reviewRow
+	for ci, category := range r.categories {
+		rows = append(rows, reviewRow{category: ci, file: -1})
+		if category.expanded {
+			for fi := range category.files {
+				rows = append(rows, reviewRow{category: ci, file: fi})
+			}
+		}
+	}
+	return rows
+}
+
+// current 返回光标所在的行
+func (r *classificationReview) current() (reviewRow, bool) {
+	rows := r.rows()
+	if len(rows) == 0 {
+		return reviewRow{}, false
+	}
+	if r.cursor >= len(rows) {
+		r.cursor = len(rows) - 1
+	}
+	return rows[r.cursor], true
+}
+
+// moveCursor 上下移动光标
+func (r *classificationReview) moveCursor(delta int) {
+	r.cursor += delta
+	if n := len(r.rows()); r.cursor >= n {
+		r.cursor = n - 1
+	}
+	if r.cursor < 0 {
+		r.cursor = 0
+	}
+}
+
+// focusCategory 将光标移到指定名称的分类
+func (r *classificationReview) focusCategory(name string) {
+	for i, row := range r.rows() {
+		if row.file == -1 && r.categories[row.category].name == name {
+			r.cursor = i
+			return
+		}
+	}
+}
+
+// findCategory 按名称查找分类，不存在时返回 -1
+func (r *classificationReview) findCategory(name string) int {
+	for i, category := range r.categories {
+		if category.name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// rename 重命名分类，新名称已存在时合并到该分类
+func (r *classificationReview) rename(ci int, name string) {
+	name = strings.TrimSpace(name)
+	old := r.categories[ci].name
+	if name == "" || name == old {
+		return
+	}
+	if target := r.findCategory(name); target >= 0 {
+		r.merge(ci, target)
+		return
+	}
+	r.categories[ci].name = name
+	r.sortCategories()
+	r.focusCategory(name)
+	r.message = fmt.Sprintf("已将 %s 重命名为 %s", old, name)
+}
+
+// merge 将一个分类中的文件并入另一个分类
+func (r *classificationReview) merge(from, to int) {
+	if from == to {
+		return
+	}
+	source, target := r.categories[from], r.categories[to]
+	target.files = append(target.files, source.files...)
+	sort.Slice(target.files, func(i, j int) bool { return target.files[i].Path < target.files[j].Path })
+	r.categories = append(r.categories[:from], r.categories[from+1:]...)
+	r.focusCategory(target.name)
+	r.message = fmt.Sprintf("已将 %s 的 %d 个文件合并到 %s", source.name, len(source.files), target.name)
+}
+
+// moveFile 将文件移到指定名称的分类，分类不存在时新建，移空的分类会被删除
+func (r *classificationReview) moveFile(ci, fi int, name string) {
+	name = strings.TrimSpace(name)
+	source := r.categories[ci]
+	if name == "" || name == source.name {
+		return
+	}
+	file := source.files[fi]
+	source.files = append(source.files[:fi], source.files[fi+1:]...)
+
+	target := r.findCategory(name)
+	if target < 0 {
+		r.categories = append(r.categories, &reviewCategory{name: name})
+		target = len(r.categories) - 1
+	}
+	category := r.categories[target]
+	category.files = append(category.files, file)
+	sort.Slice(category.files, func(i, j int) bool { return category.files[i].Path < category.files[j].Path })
+	category.expanded = true
+
+	if len(source.files) == 0 {
+		r.categories = append(r.categories[:ci], r.categories[ci+1:]...)
+	}
+	r.sortCategories()
+	r.moveCursorToFile(file.Path)
+	r.message = fmt.Sprintf("已将 %s 移到 %s", file.Path, name)
+}
+
+// moveCursorToFile 将光标移到指定文件
+func (r *classificationReview) moveCursorToFile(path string) {
+	for i, row := range r.rows() {
+		if row.file >= 0 && r.categories[row.category].files[row.file].Path == path {
+			r.cursor = i
+			return
+		}
+	}
+}
+
+// toggleExclude 排除或恢复一个文件；在分类行上时对分类中的所有文件操作
+func (r *classificationReview) toggleExclude(row reviewRow) {
+	category := r.categories[row.category]
+	if row.file >= 0 {
+		path := category.files[row.file].Path
+		r.excluded[path] = !r.excluded[path]
+		if r.excluded[path] {
+			r.message = "已排除 " + path
+		} else {
+			r.message = "已恢复 " + path
+		}
+		return
+	}
+
+	// 分类中有未排除的文件时全部排除，否则全部恢复
+	exclude := r.excludedCount(category) < len(category.files)
+	for _, file := range category.files {
+		r.excluded[file.Path] = exclude
+	}
+	if exclude {
+		r.message = fmt.Sprintf("已排除 %s 中的 %d 个文件", category.name, len(category.files))
+	} else {
+		r.message = fmt.Sprintf("已恢复 %s 中的 %d 个文件", category.name, len(category.files))
+	}
+}
+
+// removeCategory 删除分类，其中的文件不再移动
+func (r *classificationReview) removeCategory(ci int) {
+	category := r.categories[ci]
+	r.categories = append(r.categories[:ci], r.categories[ci+1:]...)
+	r.moveCursor(0)
+	r.message = fmt.Sprintf("已删除分类 %s，其中的 %d 个文件保留在原位置", category.name, len(category.files))
+}
+
+// excludedCount 返回分类中被排除的文件数
+func (r *classificationReview) excludedCount(category *reviewCategory) int {
+	count := 0
+	for _, file := range category.files {
+		if r.excluded[file.Path] {
+			count++
+		}
+	}
+	return count
+}
+
+// totals 返回文件总数和被排除的文件数
+func (r *classificationReview) totals() (int, int) {
+	files, excluded := 0, 0
+	for _, category := range r.categories {
+		files += len(category.files)
+		excluded += r.excludedCount(category)
+	}
+	return files, excluded
+}
+
+// result 返回审查后的分类结果，不包含被排除的文件和空分类
+func (r *classificationReview) result() map[string][]FileInfo {
+	result := make(map[string][]FileInfo)
+	for _, category := range r.categories {
+		for _, file := range category.files {
+			if r.excluded[file.Path] {
+				continue
+			}
+			file.Category = category.name
+			result[category.name] = append(result[category.name], file)
+		}
+	}
+	return result
+}
+
+// reviewTerminal 审查界面使用的终端；不支持原始模式时每次输入后需要按回车
+type reviewTerminal struct {
+	in      *os.File
+	screen  *os.File // 显示界面的终端，用于获取窗口大小
+	out     *bufio.Writer
+	raw     bool
+	restore func()
+	reader  *bufio.Reader
+	pending []byte // 原始模式下已读取、尚未处理的输入
+}
+
+// ANSI 控制序列
+const (
+	ansiClear      = "\033[H\033[2J"
+	ansiAltScreen  = "\033[?1049h"
+	ansiMainScreen = "\033[?1049l"
+	ansiHideCursor = "\033[?25l"
+	ansiShowCursor = "\033[?25h"
+	ansiReverse    = "\033[7m"
+	ansiDim        = "\033[2m"
+	ansiReset      = "\033[0m"
+)
+
+// openReviewTerminal 尽量切换到原始模式；reader 为读取标准输入的缓冲，与其他询问共用，避免输入被提前读走
+// 界面显示在 screen 上，JSON 输出时为标准错误
+func openReviewTerminal(reader *bufio.Reader, screen *os.File) *reviewTerminal {
+	t := &reviewTerminal{in: os.Stdin, screen: screen, out: bufio.NewWriter(screen), reader: reader}
+	if restore, err := makeRaw(os.Stdin); err == nil {
+		t.raw, t.restore = true, restore
+		t.out.WriteString(ansiAltScreen + ansiHideCursor)
+		t.out.Flush()
+	}
+	return t
+}
+
+// close 恢复终端设置
+func (t *reviewTerminal) close() {
+	if t.raw {
+		t.out.WriteString(ansiShowCursor + ansiMainScreen)
+		t.out.Flush()
+		t.restore()
+	}
+}
+
+// readKey 读取一个按键，返回 up、down、pgup、pgdn、home、end、enter、esc、backspace、ctrl-c 或输入的字符
+func (t *reviewTerminal) readKey() (string, error) {
+	if !t.raw {
+		line, err := t.reader.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			return "enter", nil
+		}
+		key, _ := nextReviewKey([]byte(line))
+		return key, nil
+	}
+
+	// 粘贴或输入较快时一次可能读到多个按键，逐个取出
+	if len(t.pending) == 0 {
+		buf := make([]byte, 64)
+		n, err := t.in.Read(buf)
+		if err != nil {
+			return "", err
+		}
+		t.pending = buf[:n]
+	}
+	key, n := nextReviewKey(t.pending)
+	t.pending = t.pending[n:]
+	return key, nil
+}
+
+// nextReviewKey 解析 b 开头的一个按键，返回按键名称和占用的字节数；方向键等以 ESC [ 开头
+func nextReviewKey(b []byte) (string, int) {
+	switch {
+	case len(b) == 0:
+		return "", 0
+	case b[0] == 3:
+		return "ctrl-c", 1
+	case b[0] == '\r' || b[0] == '\n':
+		return "enter", 1
+	case b[0] == 127 || b[0] == 8:
+		return "backspace", 1
+	case b[0] == 27:
+		if len(b) < 3 || (b[1] != '[' && b[1] != 'O') {
+			return "esc", 1
+		}
+		// 控制序列以 0x40-0x7E 之间的字节结束
+		end := 2
+		for end < len(b) && (b[end] < 0x40 || b[end] > 0x7e) {
+			end++
+		}
+		if end == len(b) {
+			return "", len(b)
+		}
+		switch string(b[2 : end+1]) {
+		case "A":
+			return "up", end + 1
+		case "B":
+			return "down", end + 1
+		case "5~":
+			return "pgup", end + 1
+		case "6~":
+			return "pgdn", end + 1
+		case "H", "1~":
+			return "home", end + 1
+		case "F", "4~":
+			return "end", end + 1
+		}
+		return "", end + 1
+	}
+	r, size := utf8.DecodeRune(b)
+	return string(r), size
+}
+
+// readLine 在底部读取一行文本，留空或按 Esc 时返回 false
+func (t *reviewTerminal) readLine(prompt string) (string, bool) {
+	if !t.raw {
+		fmt.Fprint(t.out, prompt)
+		t.out.Flush()
+		line, err := t.reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		return line, err == nil && line != ""
+	}
+
+	t.out.WriteString(ansiShowCursor)
+	defer t.out.WriteString(ansiHideCursor)
+	var input []rune
+	for {
+		fmt.Fprintf(t.out, "\r\033[K%s%s", prompt, string(input))
+		t.out.Flush()
+
+		key, err := t.readKey()
+		if err != nil {
+			return "", false
+		}
+		switch key {
+		case "enter":
+			line := strings.TrimSpace(string(input))
+			return line, line != ""
+		case "esc", "ctrl-c":
+			return "", false
+		case "backspace":
+			if len(input) > 0 {
+				input = input[:len(input)-1]
+			}
+		default:
+			// 方向键等名称不止一个字符，只接受可打印的单个字符
+			if r := []rune(key); len(r) == 1 && r[0] >= 0x20 {
+				input = append(input, r[0])
+			}
+		}
+	}
+}
+
+// render 绘制审查界面
+func (t *reviewTerminal) render(r *classificationReview) {
+	cols, lines := terminalSize(t.screen)
+	height := lines - 5
+	if height < 3 {
+		height = 3
+	}
+	rows := r.rows()
+	if r.cursor < r.offset {
+		r.offset = r.cursor
+	}
+	if r.cursor >= r.offset+height {
+		r.offset = r.cursor - height + 1
+	}
+
+	if t.raw {
+		t.out.WriteString(ansiClear)
+	} else {
+		t.out.WriteString("\n")
+	}
+	files, excluded := r.totals()
+	t.line(cols, fmt.Sprintf("审查分类结果：%d 个分类，%d 个文件，已排除 %d 个", len(r.categories), files, excluded))
+	t.line(cols, strings.Repeat("-", 40))
+
+	for i := r.offset; i < len(rows) && i < r.offset+height; i++ {
+		row := rows[i]
+		category := r.categories[row.category]
+		var text string
+		if row.file < 0 {
+			marker := "+"
+			if category.expanded {
+				marker = "-"
+			}
+			text = fmt.Sprintf("[%s] %s（%d）", marker, category.name, len(category.files))
+			if n := r.excludedCount(category); n > 0 {
+				text += fmt.Sprintf("，排除 %d", n)
+			}
+		} else {
+			file := category.files[row.file]
+			text = "      " + file.Path
+			if file.IsDir {
+				text += "/"
+			}
+		}
+		code := ""
+		if row.file >= 0 && r.excluded[category.files[row.file].Path] {
+			text += "（已排除）"
+			code = ansiDim
+		}
+		prefix := "  "
+		if i == r.cursor {
+			prefix = "> "
+			code = ansiReverse
+		}
+		t.styledLine(cols, code, prefix+text)
+	}
+	if !t.raw && len(rows) > height {
+		t.line(cols, fmt.Sprintf("  ...（第 %d-%d 行，共 %d 行）", r.offset+1, min(r.offset+height, len(rows)), len(rows)))
+	}
+
+	t.line(cols, strings.Repeat("-", 40))
+	t.line(cols, r.message)
+	help := "↑↓/jk 选择  回车 展开  r 重命名  m 合并  v 移动文件  x 排除/恢复  y 确认  q 取消"
+	if !t.raw {
+		help = "输入命令后按回车: j/k 上下  回车 展开  r 重命名  m 合并  v 移动文件  x 排除/恢复  y 确认  q 取消"
+	}
+	t.line(cols, help)
+	t.out.Flush()
+	r.message = ""
+}
+
+// line 输出一行，超出终端宽度的部分截断，避免折行打乱界面
+func (t *reviewTerminal) line(cols int, text string) {
+	t.styledLine(cols, "", text)
+}
+
+// styledLine 输出带样式的一行，样式只在原始模式下使用
+func (t *reviewTerminal) styledLine(cols int, code, text string) {
+	if t.raw {
+		text = truncateDisplay(text, cols-1)
+		if code != "" {
+			text = code + text + ansiReset
+		}
+	}
+	t.out.WriteString(text + "\n")
+}
+
+// pickCategory 列出分类供选择，输入编号或新名称
+func (t *reviewTerminal) pickCategory(r *classificationReview, title string, exclude int) (string, bool) {
+	cols, lines := terminalSize(t.screen)
+	if t.raw {
+		t.out.WriteString(ansiClear)
+	} else {
+		t.out.WriteString("\n")
+	}
+	t.line(cols, title)
+	shown := 0
+	for i, category := range r.categories {
+		if i == exclude {
+			continue
+		}
+		if t.raw && shown >= lines-4 {
+			t.line(cols, "  ...")
+			break
+		}
+		t.line(cols, fmt.Sprintf("  %d) %s（%d）", i+1, category.name, len(category.files)))
+		shown++
+	}
+	answer, ok := t.readLine("编号或新分类名称（留空取消）: ")
+	if !ok {
+		return "", false
+	}
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(r.categories) && n-1 != exclude {
+		return r.categories[n-1].name, true
+	}
+	return answer, true
+}
+
+// reviewClassification 在终端中审查分类结果：可以重命名、合并分类，在分类间移动文件或排除文件
+// 确认后返回修改后的结果，取消时返回 errReviewCancelled
+func reviewClassification(reader *bufio.Reader, screen *os.File, classified map[string][]FileInfo) (map[string][]FileInfo, error) {
+	r := newClassificationReview(classified)
+	t := openReviewTerminal(reader, screen)
+	defer t.close()
+
+	for {
+		t.render(r)
+		key, err := t.readKey()
+		if err != nil {
+			return nil, errReviewCancelled
+		}
+		row, ok := r.current()
+
+		switch key {
+		case "up", "k":
+			r.moveCursor(-1)
+		case "down", "j":
+			r.moveCursor(1)
+		case "pgup":
+			r.moveCursor(-10)
+		case "pgdn":
+			r.moveCursor(10)
+		case "home", "g":
+			r.cursor = 0
+		case "end", "G":
+			r.cursor = len(r.rows()) - 1
+		case "enter", " ", "l", "h":
+			if ok {
+				category := r.categories[row.category]
+				category.expanded = !category.expanded
+				r.focusCategory(category.name)
+			}
+		case "r":
+			if !ok {
+				continue
+			}
+			category := r.categories[row.category]
+			if name, ok := t.readLine(fmt.Sprintf("将 %s 重命名为（留空取消）: ", category.name)); ok {
+				r.rename(row.category, name)
+			}
+		case "m":
+			if !ok {
+				continue
+			}
+			name := r.categories[row.category].name
+			if target, ok := t.pickCategory(r, "将 "+name+" 合并到：", row.category); ok {
+				if to := r.findCategory(target); to >= 0 {
+					r.merge(row.category, to)
+				} else {
+					r.rename(row.category, target)
+				}
+			}
+		case "v":
+			if !ok || row.file < 0 {
+				r.message = "请先展开分类并选择要移动的文件"
+				continue
+			}
+			path := r.categories[row.category].files[row.file].Path
+			if target, ok := t.pickCategory(r, "将 "+path+" 移到：", row.category); ok {
+				r.moveFile(row.category, row.file, target)
+			}
+		case "x":
+			if ok {
+				r.toggleExclude(row)
+			}
+		case "y":
+			result := r.result()
+			if len(result) == 0 {
+				r.message = "所有文件都已排除，没有需要移动的文件；按 q 取消"
+				continue
+			}
+			return result, nil
+		case "q", "esc", "ctrl-c":
+			return nil, errReviewCancelled
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// 符号链接处理策略
+const (
+	SymlinkSkip   = "skip"   // 跳过符号链接（默认）
+	SymlinkMove   = "move"   // 把链接本身当作文件移动，不读取链接目标
+	SymlinkFollow = "follow" // 跟随链接扫描目标，检测目录循环
+)
+
+// scanProgressInterval 扫描时每找到多少个文件发出一次进度事件
+const scanProgressInterval = 200
+
+// ScanOptions 定义扫描选项
+type ScanOptions struct {
+	SymlinkPolicy  string   `json:"symlink_policy,omitempty"`
+	IgnorePatterns []string `json:"ignore_patterns"` // 全局忽略规则，gitignore 格式
+
+	// DirsAsUnits 为 true 时整理目录下的每个子目录都作为一个整体分类和移动，不再递归扫描
+	DirsAsUnits bool `json:"-"`
+
+	// 程序整理出的分类目录总是拆开扫描，不当作项目或相册整体移动，否则再次整理时会把分类目录移进它自己
+	Categories map[string]bool `json:"-"` // 已有和配置的分类名称，同名的目录视为分类目录
+	PlacedDirs map[string]bool `json:"-"` // 状态文件中记录有已整理文件的目录，相对于整理目录，使用 / 分隔
+}
+
+// validateSymlinkPolicy 检查符号链接策略是否有效
+func validateSymlinkPolicy(policy string) error {
+	switch policy {
+	case "", SymlinkSkip, SymlinkMove, SymlinkFollow:
+		return nil
+	}
+	return fmt.Errorf("不支持的符号链接策略: %s（可选 skip、move、follow）", policy)
+}
+
+// fileScanner 保存一次扫描的状态
+type fileScanner struct {
+	root     string
+	opts     ScanOptions
+	files    []FileInfo
+	warnings []string
+	visited  []os.FileInfo // 已扫描的目录，跟随链接时用于检测循环和重复
+}
+
+// getFileList 获取指定目录下的所有文件列表
+// 无法读取的子目录和特殊文件（套接字、管道、设备）会被跳过并记录到警告列表中，不会中断扫描
+// 全局忽略规则和各级目录中的 .fileclassifyignore 文件按 gitignore 语义生效
+func getFileList(root string, opts ScanOptions) ([]FileInfo, []string, error) {
+	rootInfo, err := os.Stat(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !rootInfo.IsDir() {
+		return nil, nil, fmt.Errorf("%s 不是目录", root)
+	}
+	if _, err := os.ReadDir(root); err != nil {
+		return nil, nil, err
+	}
+
+	ignore, err := NewIgnoreMatcher(opts.IgnorePatterns)
+	if err != nil {
+		return nil, nil, fmt.Errorf("全局忽略规则无效: %v", err)
+	}
+
+	emitProgress(ProgressEvent{Kind: EventScanStarted, Root: root})
+	s := &fileScanner{root: root, opts: opts}
+	s.visited = append(s.visited, rootInfo)
+	s.walkDir(root, "", ignore)
+	emitProgress(ProgressEvent{Kind: EventScanFinished, Root: root, Done: len(s.files), Total: len(s.files)})
+	return s.files, s.warnings, nil
+}
+
+func (s *fileScanner) warn(format string, args ...interface{}) {
+	s.warnings = append(s.warnings, fmt.Sprintf(format, args...))
+}
+
+// walkDir 扫描目录，relDir 为该目录相对于扫描根目录的路径
+// ignore 为上级目录累积的忽略规则，进入目录后追加该目录的 .fileclassifyignore
+func (s *fileScanner) walkDir(dir, relDir string, ignore *IgnoreMatcher) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		s.warn("无法读取目录 %s: %v", displayRelPath(relDir), err)
+		return
+	}
+
+	ignore, err = ignore.withDir(dir, relDir)
+	if err != nil {
+		s.warn("无法读取忽略规则 %v", err)
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		relPath := filepath.Join(relDir, entry.Name())
+
+		// 跳过程序自身的状态文件和忽略规则文件
+		if relPath == stateFileName || entry.Name() == ignoreFileName {
+			continue
+		}
+
+		mode := entry.Type()
+		// 被忽略的目录整体跳过，其中的文件无法再通过否定规则恢复
+		if ignore.Match(relPath, entry.IsDir()) {
+			continue
+		}
+		switch {
+		case mode&os.ModeSymlink != 0:
+			s.handleSymlink(path, relPath, ignore)
+
+		case entry.IsDir():
+			info, err := entry.Info()
+			if err != nil {
+				s.warn("无法读取目录信息 %s: %v", relPath, err)
+				continue
+			}
+			// 跟随链接时，同一目录可能已经通过链接扫描过
+			if s.opts.SymlinkPolicy == SymlinkFollow && s.seen(info) {
+				s.warn("跳过目录 %s：已通过符号链接扫描过", relPath)
+				continue
+			}
+			s.visited = append(s.visited, info)
+			// 顶层子目录按整体处理，或者是必须整体移动的项目、相册、应用
+			if (s.opts.DirsAsUnits && relDir == "") || s.isAtomicDir(path, relPath) {
+				s.addDir(path, relPath, info)
+				continue
+			}
+			s.walkDir(path, relPath, ignore)
+
+		case mode.IsRegular():
+			info, err := entry.Info()
+			if err != nil {
+				s.warn("无法读取文件信息 %s: %v", relPath, err)
+				continue
+			}
+			s.addFile(relPath, info, false)
+
+		default:
+			s.warn("跳过特殊文件 %s (%s)", relPath, mode.Type())
+		}
+	}
+}
+
+// handleSymlink 按策略处理符号链接
+func (s *fileScanner) handleSymlink(path, relPath string, ignore *IgnoreMatcher) {
+	switch s.opts.SymlinkPolicy {
+	case SymlinkMove:
+		info, err := os.Lstat(path)
+		if err != nil {
+			s.warn("无法读取符号链接 %s: %v", relPath, err)
+			return
+		}
+		s.addFile(relPath, info, true)
+
+	case SymlinkFollow:
+		target, err := os.Stat(path)
+		if err != nil {
+			s.warn("跳过失效的符号链接 %s: %v", relPath, err)
+			return
+		}
+		switch {
+		case target.IsDir():
+			// 只对目录生效的规则需要在解析链接目标后再判断
+			if ignore.Match(relPath, true) {
+				return
+			}
+			if s.seen(target) {
+				s.warn("跳过符号链接 %s：目标目录已扫描过或形成循环", relPath)
+				return
+			}
+			// 需要整体移动的目录只移动链接本身，不拆分链接目标
+			if (s.opts.DirsAsUnits && filepath.Dir(relPath) == ".") || s.isAtomicDir(path, relPath) {
+				if info, err := os.Lstat(path); err == nil {
+					s.addFile(relPath, info, true)
+				}
+				return
+			}
+			s.visited = append(s.visited, target)
+			s.walkDir(path, relPath, ignore)
+		case target.Mode().IsRegular():
+			s.addFile(relPath, target, false)
+		default:
+			s.warn("跳过指向特殊文件的符号链接 %s", relPath)
+		}
+
+	default:
+		// 默认跳过，不记录警告
+	}
+}
+
+// isAtomicDir 判断目录是否需要整体移动，程序整理出的分类目录不算
+func (s *fileScanner) isAtomicDir(path, relPath string) bool {
+	if s.opts.PlacedDirs[filepath.ToSlash(relPath)] {
+		return false
+	}
+	name := filepath.Base(relPath)
+	if s.opts.Categories[name] {
+		return false
+	}
+	if m := numberedCategoryPattern.FindStringSubmatch(name); m != nil && s.opts.Categories[m[2]] {
+		return false
+	}
+	return atomicDirKind(path) != ""
+}
+
+// seen 判断目录是否已经扫描过
+func (s *fileScanner) seen(info os.FileInfo) bool {
+	for _, visited := range s.visited {
+		if os.SameFile(visited, info) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *fileScanner) addFile(relPath string, info os.FileInfo, isSymlink bool) {
+	s.files = append(s.files, FileInfo{
+		Path:      relPath,
+		Size:      info.Size(),
+		ModTime:   info.ModTime(),
+		IsSymlink: isSymlink,
+	})
+	s.reportProgress()
+}
+
+// addDir 将整个目录作为一个条目，大小为目录中所有文件的总大小
+func (s *fileScanner) addDir(path, relPath string, info os.FileInfo) {
+	s.files = append(s.files, FileInfo{
+		Path:    relPath,
+		Size:    dirSize(path),
+		ModTime: info.ModTime(),
+		IsDir:   true,
+	})
+	s.reportProgress()
+}
+
+// reportProgress 每找到一定数量的文件发出一次扫描进度
+func (s *fileScanner) reportProgress() {
+	if len(s.files)%scanProgressInterval == 0 {
+		emitProgress(ProgressEvent{Kind: EventScanProgress, Root: s.root, Done: len(s.files)})
+	}
+}
+
+func displayRelPath(relPath string) string {
+	if relPath == "" {
+		return "."
+	}
+	return relPath
+}
+
+// printScanWarnings 打印扫描警告
+func printScanWarnings(w io.Writer, warnings []string) {
+	if len(warnings) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "扫描时有 %d 个警告：\n", len(warnings))
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "- %s\n", warning)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestGetFileListCategoryDirs(t *testing.T) {
+	// 上次整理后的目录：图片、代码项目是程序创建的分类，旅行是用户自己的相册
+	files := []string{
+		"图片/a.jpg", "图片/b.jpg", "图片/c.jpg",
+		"代码项目/Makefile", "代码项目/main.c",
+		"03_视频/x.mp4", "03_视频/y.mp4", "03_视频/z.mp4",
+		"旅行/1.jpg", "旅行/2.jpg", "旅行/3.jpg",
+	}
+	split := []string{
+		"03_视频/x.mp4", "03_视频/y.mp4", "03_视频/z.mp4",
+		"代码项目/Makefile", "代码项目/main.c",
+		"图片/a.jpg", "图片/b.jpg", "图片/c.jpg",
+		"旅行/",
+	}
+	tests := []struct {
+		name string
+		opts ScanOptions
+		want []string
+	}{
+		{"没有整理记录时按相册和项目整体处理", ScanOptions{}, []string{"03_视频/", "代码项目/", "图片/", "旅行/"}},
+		{"与分类同名的目录拆开扫描", ScanOptions{Categories: map[string]bool{"图片": true, "代码项目": true, "视频": true}}, split},
+		{"带序号的分类目录", ScanOptions{Categories: map[string]bool{"03_视频": true}}, []string{
+			"03_视频/x.mp4", "03_视频/y.mp4", "03_视频/z.mp4", "代码项目/", "图片/", "旅行/",
+		}},
+		{"状态文件中记录过的目录拆开扫描", ScanOptions{PlacedDirs: map[string]bool{"图片": true, "代码项目": true, "03_视频": true}}, split},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for _, file := range files {
+				writeFile(t, filepath.Join(root, filepath.FromSlash(file)), file)
+			}
+			got, warnings, err := getFileList(root, tt.opts)
+			if err != nil || len(warnings) > 0 {
+				t.Fatalf("getFileList() = %v, %v", warnings, err)
+			}
+			if paths := scannedPaths(got); !reflect.DeepEqual(paths, tt.want) {
+				t.Errorf("扫描结果 = %q\n应为 %q", paths, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrganizerScanRerun(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, ".config"))
+	org, err := NewOrganizer(defaultConfig(), OrganizerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一次整理：图片被拆开扫描，用户的相册整体移动
+	for _, file := range []string{"a.jpg", "b.jpg", "c.jpg", "假期/1.jpg", "假期/2.jpg", "假期/3.jpg"} {
+		writeFile(t, filepath.Join(root, filepath.FromSlash(file)), file)
+	}
+	scan, err := org.Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paths := scannedPaths(scan.Files); !reflect.DeepEqual(paths, []string{"a.jpg", "b.jpg", "c.jpg", "假期/"}) {
+		t.Fatalf("第一次扫描 = %q", paths)
+	}
+	ops := []MoveOp{
+		{File: scan.Files[0], Src: filepath.Join(root, "a.jpg"), Dst: filepath.Join(root, "图片", "a.jpg")},
+		{File: scan.Files[1], Src: filepath.Join(root, "b.jpg"), Dst: filepath.Join(root, "图片", "b.jpg")},
+		{File: scan.Files[2], Src: filepath.Join(root, "c.jpg"), Dst: filepath.Join(root, "图片", "c.jpg")},
+		{File: scan.Files[3], Src: filepath.Join(root, "假期"), Dst: filepath.Join(root, "相册", "假期")},
+	}
+	for i := range ops {
+		ops[i].File.Category = filepath.Base(filepath.Dir(ops[i].Dst))
+	}
+	report := applyMoves(context.Background(), root, ops, organizeOptions{State: scan.State})
+	if len(report.Failures) > 0 {
+		t.Fatalf("移动失败: %v", report.Failures)
+	}
+
+	// 再次整理：图片目录中只有三张照片，但它是上次整理出的分类，不能作为相册移进它自己
+	scan, err = org.Scan(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"图片/a.jpg", "图片/b.jpg", "图片/c.jpg", "相册/假期/"}
+	if paths := scannedPaths(scan.Files); !reflect.DeepEqual(paths, want) {
+		t.Errorf("再次扫描 = %q\n应为 %q", paths, want)
+	}
+}
+
+// scannedPaths 返回排序后的扫描结果路径，目录以 / 结尾
+func scannedPaths(files []FileInfo) []string {
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		path := filepath.ToSlash(file.Path)
+		if file.IsDir {
+			path += "/"
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+// scryptKey 按 RFC 7914 从口令派生密钥，只依赖标准库
+// N 必须是大于 1 的 2 的幂，内存占用约为 128*N*r 字节
+func scryptKey(password, salt []byte, N, r, p, keyLen int) ([]byte, error) {
+	if N <= 1 || N&(N-1) != 0 {
+		return nil, errors.New("scrypt: N 必须是大于 1 的 2 的幂")
+	}
+	if r <= 0 || p <= 0 || uint64(r)*uint64(p) >= 1<<30 || N > (1<<31-1)/128/r {
+		return nil, errors.New("scrypt: 参数过大")
+	}
+
+	R := 32 * r
+	xy := make([]uint32, 2*R)
+	v := make([]uint32, R*N)
+	b := pbkdf2SHA256(password, salt, p*128*r)
+	for i := 0; i < p; i++ {
+		smix(b[i*128*r:], r, N, v, xy)
+	}
+	return pbkdf2SHA256(password, b, keyLen), nil
+}
+
+// pbkdf2SHA256 迭代次数为 1 的 PBKDF2-HMAC-SHA256，scrypt 只需要这种形式
+func pbkdf2SHA256(password, salt []byte, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var counter [4]byte
+	dk := make([]byte, 0, keyLen+prf.Size())
+	for block := uint32(1); len(dk) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], block)
+		prf.Write(counter[:])
+		dk = prf.Sum(dk)
+	}
+	return dk[:keyLen]
+}
+
+// smix 对 128*r 字节的块 b 执行 ROMix
+func smix(b []byte, r, N int, v, xy []uint32) {
+	R := 32 * r
+	x, y := xy[:R], xy[R:]
+	for i := range x {
+		x[i] = binary.LittleEndian.Uint32(b[4*i:])
+	}
+
+	var tmp [16]uint32
+	for i := 0; i < N; i++ {
+		copy(v[i*R:], x)
+		blockMix(&tmp, x, y, r)
+		x, y = y, x
+	}
+	for i := 0; i < N; i++ {
+		j := int(x[(2*r-1)*16] & uint32(N-1))
+		for k, w := range v[j*R : (j+1)*R] {
+			x[k] ^= w
+		}
+		blockMix(&tmp, x, y, r)
+		x, y = y, x
+	}
+
+	for i, w := range x {
+		binary.LittleEndian.PutUint32(b[4*i:], w)
+	}
+}
+
+// blockMix 对 2r 个 64 字节的子块执行 Salsa20/8，偶数结果放前半部分，奇数结果放后半部分
+func blockMix(tmp *[16]uint32, in, out []uint32, r int) {
+	copy(tmp[:], in[(2*r-1)*16:])
+	for i := 0; i < 2*r; i += 2 {
+		salsaXOR(tmp, in[i*16:], out[i*8:])
+		salsaXOR(tmp, in[i*16+16:], out[i*8+r*16:])
+	}
+}
+
+// salsaXOR 计算 Salsa20/8(tmp ^ in)，结果写入 out 和 tmp
+func salsaXOR(tmp *[16]uint32, in, out []uint32) {
+	var x [16]uint32
+	for i := range x {
+		x[i] = tmp[i] ^ in[i]
+	}
+
+	w := x
+	quarter := func(a, b, c, d int) {
+		w[b] ^= bits.RotateLeft32(w[a]+w[d], 7)
+		w[c] ^= bits.RotateLeft32(w[b]+w[a], 9)
+		w[d] ^= bits.RotateLeft32(w[c]+w[b], 13)
+		w[a] ^= bits.RotateLeft32(w[d]+w[c], 18)
+	}
+	for i := 0; i < 8; i += 2 {
+		quarter(0, 4, 8, 12)
+		quarter(5, 9, 13, 1)
+		quarter(10, 14, 2, 6)
+		quarter(15, 3, 7, 11)
+		quarter(0, 1, 2, 3)
+		quarter(5, 6, 7, 4)
+		quarter(10, 11, 8, 9)
+		quarter(15, 12, 13, 14)
+	}
+
+	for i := range x {
+		x[i] += w[i]
+		out[i] = x[i]
+		tmp[i] = x[i]
+	}
+}
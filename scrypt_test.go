@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// RFC 7914 第 12 节的测试向量，N=1048576 的一组需要 1GB 内存，不在这里运行
+func TestScryptKey(t *testing.T) {
+	tests := []struct {
+		password, salt string
+		N, r, p        int
+		want           string
+	}{
+		{"", "", 16, 1, 1, "77d6576238657b203b19ca42c18a0497f16b4844e3074ae8dfdffa3fede21442fcd0069ded0948f8326a753a0fc81f17e8d3e0fb2e0d3628cf35e20c38d18906"},
+		{"password", "NaCl", 1024, 8, 16, "fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b3731622eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640"},
+		{"pleaseletmein", "SodiumChloride", 16384, 8, 1, "7023bdcb3afd7348461c06cd81fd38ebfda8fbba904f8e3ea9b543f6545da1f2d5432955613f0fcf62d49705242a9af9e61e85dc0d651e40dfcf017b45575887"},
+	}
+	for _, tt := range tests {
+		key, err := scryptKey([]byte(tt.password), []byte(tt.salt), tt.N, tt.r, tt.p, 64)
+		if err != nil {
+			t.Fatalf("scryptKey(%q, %q, %d, %d, %d): %v", tt.password, tt.salt, tt.N, tt.r, tt.p, err)
+		}
+		if got := hex.EncodeToString(key); got != tt.want {
+			t.Errorf("scryptKey(%q, %q, %d, %d, %d) = %s，应为 %s", tt.password, tt.salt, tt.N, tt.r, tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestScryptKeyInvalidParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		N, r, p int
+	}{
+		{"N 不是 2 的幂", 1000, 8, 1},
+		{"N 为 1", 1, 8, 1},
+		{"r 为 0", 16, 0, 1},
+		{"p 为 0", 16, 1, 0},
+		{"r*p 过大", 16, 1 << 15, 1 << 15},
+	}
+	for _, tt := range tests {
+		if _, err := scryptKey([]byte("pw"), []byte("salt"), tt.N, tt.r, tt.p, 32); err == nil {
+			t.Errorf("%s: 应返回错误", tt.name)
+		}
+	}
+}
+
+func TestSecretStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+
+	store, err := OpenSecretStore(path, "正确的口令")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("deepseek", "api_key", "sk-test-key"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("aliyun", "api_secret", "secret-value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("sk-test-key")) || bytes.Contains(data, []byte("secret-value")) {
+		t.Fatal("密钥文件中不应有明文密钥")
+	}
+
+	reopened, err := OpenSecretStore(path, "正确的口令")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.Providers["deepseek"].APIKey; got != "sk-test-key" {
+		t.Errorf("deepseek api_key = %q", got)
+	}
+	if got := reopened.Providers["aliyun"].APISecret; got != "secret-value" {
+		t.Errorf("aliyun api_secret = %q", got)
+	}
+
+	if _, err := OpenSecretStore(path, "错误的口令"); err == nil {
+		t.Error("口令错误时应返回错误")
+	}
+}
+
+func TestSecretStoreTampered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	store, err := OpenSecretStore(path, "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Set("deepseek", "api_key", "sk-test-key")
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(sf map[string]interface{})
+		want   string // 错误信息中应包含的内容
+	}{
+		{"N 过大", func(sf map[string]interface{}) { sf["n"] = 1 << 30 }, "n="},
+		{"N 不是 2 的幂", func(sf map[string]interface{}) { sf["n"] = 1000 }, "n="},
+		{"r 过大", func(sf map[string]interface{}) { sf["r"] = 1 << 20 }, "r="},
+		{"p 过大", func(sf map[string]interface{}) { sf["p"] = 1 << 20 }, "p="},
+		{"内存超出上限", func(sf map[string]interface{}) { sf["n"], sf["r"] = 1<<20, 32 }, "内存"},
+		{"随机数长度错误", func(sf map[string]interface{}) { sf["nonce"] = "AAAA" }, "随机数"},
+		{"提供者名称被修改", func(sf map[string]interface{}) { sf["providers"] = []string{"github"} }, "口令错误"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sf map[string]interface{}
+			if err := json.Unmarshal(data, &sf); err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(sf)
+			modified, err := json.Marshal(sf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tampered := filepath.Join(t.TempDir(), "secrets.enc")
+			if err := os.WriteFile(tampered, modified, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = OpenSecretStore(tampered, "pw")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("错误 = %v，应包含 %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// passphraseEnvVar 提供密钥文件口令的环境变量，没有设置时在终端中询问
+const passphraseEnvVar = "FILECLASSIFY_PASSPHRASE"
+
+// 新建密钥文件使用的 scrypt 参数，派生一次约需 32MB 内存
+const (
+	secretsScryptN = 1 << 15
+	secretsScryptR = 8
+	secretsScryptP = 1
+)
+
+// 读取密钥文件时允许的 scrypt 参数上限，避免被改过的文件让派生密钥占用过多内存和时间
+const (
+	secretsMaxScryptN   = 1 << 20
+	secretsMaxScryptR   = 32
+	secretsMaxScryptP   = 16
+	secretsMaxScryptMem = 256 << 20 // 128*N*r 字节
+)
+
+// secretsFile 加密密钥文件的内容
+// 提供者名称以明文保存，只有需要对应密钥时才询问口令；它同时作为附加数据参与认证，不能被篡改
+type secretsFile struct {
+	Version   int      `json:"version"`
+	KDF       string   `json:"kdf"`
+	N         int      `json:"n"`
+	R         int      `json:"r"`
+	P         int      `json:"p"`
+	Salt      []byte   `json:"salt"`
+	Nonce     []byte   `json:"nonce"`
+	Providers []string `json:"providers"`
+	Data      []byte   `json:"data"`
+}
+
+// SecretStore 解密后的密钥，只包含各提供者的 api_key 和 api_secret
+type SecretStore struct {
+	path       string
+	passphrase string
+	Providers  map[string]ProviderConfig
+}
+
+// defaultSecretsPath 默认的密钥文件位置，Linux 下为 ~/.config/fileclassify/secrets.enc
+func defaultSecretsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "fileclassify", "secrets.enc"), nil
+}
+
+// readSecretsHeader 读取密钥文件但不解密，文件不存在时返回nil
+func readSecretsHeader(path string) (*secretsFile, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPrivateFile(path, info); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sf secretsFile
+	if err := json.Unmarshal(data, &sf); err != nil {
+		return nil, fmt.Errorf("解析密钥文件 %s 失败: %v", path, err)
+	}
+	if sf.Version != 1 || sf.KDF != "scrypt" {
+		return nil, fmt.Errorf("不支持的密钥文件格式: %s", path)
+	}
+	if err := sf.validate(); err != nil {
+		return nil, fmt.Errorf("密钥文件 %s 无效: %v", path, err)
+	}
+	return &sf, nil
+}
+
+// validate 检查文件头中的 scrypt 参数和随机数，派生密钥前拒绝超出上限的参数
+func (sf *secretsFile) validate() error {
+	if sf.N <= 1 || sf.N > secretsMaxScryptN || sf.N&(sf.N-1) != 0 {
+		return fmt.Errorf("scrypt 参数 n=%d 无效，应为不超过 %d 的 2 的幂", sf.N, secretsMaxScryptN)
+	}
+	if sf.R <= 0 || sf.R > secretsMaxScryptR {
+		return fmt.Errorf("scrypt 参数 r=%d 超出范围 1-%d", sf.R, secretsMaxScryptR)
+	}
+	if sf.P <= 0 || sf.P > secretsMaxScryptP {
+		return fmt.Errorf("scrypt 参数 p=%d 超出范围 1-%d", sf.P, secretsMaxScryptP)
+	}
+	if 128*int64(sf.N)*int64(sf.R) > secretsMaxScryptMem {
+		return fmt.Errorf("scrypt 参数 n=%d、r=%d 需要的内存超过 %dMB", sf.N, sf.R, secretsMaxScryptMem>>20)
+	}
+	// AES-GCM 的随机数为 12 字节，长度不对时解密会直接崩溃
+	if len(sf.Salt) == 0 || len(sf.Nonce) != 12 {
+		return errors.New("盐或随机数长度不正确")
+	}
+	return nil
+}
+
+// OpenSecretStore 用口令解密密钥文件；文件不存在时返回空的密钥库，保存时使用该口令加密
+func OpenSecretStore(path, passphrase string) (*SecretStore, error) {
+	store := &SecretStore{path: path, passphrase: passphrase, Providers: map[string]ProviderConfig{}}
+	sf, err := readSecretsHeader(path)
+	if err != nil || sf == nil {
+		return store, err
+	}
+
+	gcm, err := secretsCipher(passphrase, sf.Salt, sf.N, sf.R, sf.P)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, sf.Nonce, sf.Data, secretsAAD(sf.Providers))
+	if err != nil {
+		return nil, errors.New("口令错误或密钥文件已损坏")
+	}
+	if err := json.Unmarshal(plain, &store.Providers); err != nil {
+		return nil, fmt.Errorf("解析密钥失败: %v", err)
+	}
+	return store, nil
+}
+
+// Save 使用新的盐和随机数重新加密，先写临时文件再替换，权限为 0600
+func (s *SecretStore) Save() error {
+	providers := make([]string, 0, len(s.Providers))
+	for name := range s.Providers {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+
+	sf := secretsFile{Version: 1, KDF: "scrypt", N: secretsScryptN, R: secretsScryptR, P: secretsScryptP, Providers: providers}
+	sf.Salt = make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, sf.Salt); err != nil {
+		return err
+	}
+	gcm, err := secretsCipher(s.passphrase, sf.Salt, sf.N, sf.R, sf.P)
+	if err != nil {
+		return err
+	}
+	sf.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, sf.Nonce); err != nil {
+		return err
+	}
+	plain, err := json.Marshal(s.Providers)
+	if err != nil {
+		return err
+	}
+	sf.Data = gcm.Seal(nil, sf.Nonce, plain, secretsAAD(providers))
+
+	data, err := json.MarshalIndent(&sf, "", "    ")
+	if err != nil {
+		return err
+	}
+	return writePrivateFile(s.path, data)
+}
+
+// Set 设置提供者的密钥，field 为 api_key 或 api_secret
+func (s *SecretStore) Set(provider, field, value string) error {
+	pc := s.Providers[provider]
+	switch field {
+	case "api_key":
+		pc.APIKey = value
+	case "api_secret":
+		pc.APISecret = value
+	default:
+		return fmt.Errorf("只能保存 api_key 或 api_secret，不支持 %s", field)
+	}
+	s.Providers[provider] = pc
+	return nil
+}
+
+func secretsCipher(passphrase string, salt []byte, N, r, p int) (cipher.AEAD, error) {
+	key, err := scryptKey([]byte(passphrase), salt, N, r, p, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func secretsAAD(providers []string) []byte {
+	return []byte("fileclassify-secrets-v1:" + strings.Join(providers, ","))
+}
+
+// writePrivateFile 写入只有当前用户可读写的文件，已存在的文件权限也会改为 0600
+func writePrivateFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0600); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// checkPrivateFile 拒绝其他用户可读的文件；Windows 的权限位没有意义，不检查
+func checkPrivateFile(path string, info os.FileInfo) error {
+	if runtime.GOOS == "windows" || info.Mode().Perm()&0004 == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s 包含密钥且所有用户可读，请运行 chmod 600 %s", path, path)
+}
+
+// readPassphrase 读取密钥文件口令：优先使用环境变量，其次在终端中不回显地询问
+// confirm 为 true 时要求输入两次，用于创建新的密钥文件
+func readPassphrase(prompt string, confirm bool) (string, error) {
+	if passphrase := os.Getenv(passphraseEnvVar); passphrase != "" {
+		return passphrase, nil
+	}
+	if !stdinIsTerminal() {
+		return "", fmt.Errorf("需要密钥文件口令，请在终端中运行或设置环境变量 %s", passphraseEnvVar)
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := readPassword(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("口令不能为空")
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "请再次输入口令: ")
+		again, err := readPassword(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", errors.New("两次输入的口令不一致")
+		}
+	}
+	return passphrase, nil
+}
+
+// readRawLine 不经过缓冲逐字节读取一行，避免吞掉后续输入
+func readRawLine(f *os.File) (string, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+			line = append(line, buf[0])
+		}
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				break
+			}
+			return "", err
+		}
+	}
+	return strings.TrimRight(string(line), "\r"), nil
+}
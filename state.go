@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// stateFileName 每个整理目录下记录已放置文件的状态文件
+const stateFileName = ".fileclassify_state.json"
+
+// PlacedFile 状态文件中记录的已整理文件
+type PlacedFile struct {
+	Category string    `json:"category"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mod_time"`
+	PlacedAt time.Time `json:"placed_at"`
+}
+
+// OrganizeState 整理目录的状态，键为相对于整理目录的路径
+type OrganizeState struct {
+	path  string
+	Files map[string]PlacedFile `json:"files"`
+}
+
+// LoadOrganizeState 读取整理目录下的状态文件，不存在时返回空状态
+func LoadOrganizeState(root string) (*OrganizeState, error) {
+	state := &OrganizeState{
+		path:  filepath.Join(root, stateFileName),
+		Files: make(map[string]PlacedFile),
+	}
+
+	data, err := os.ReadFile(state.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("读取状态文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("解析状态文件失败: %v", err)
+	}
+	if state.Files == nil {
+		state.Files = make(map[string]PlacedFile)
+	}
+	return state, nil
+}
+
+// Diff 将扫描结果与状态对比，返回新增或修改过的文件，以及磁盘上仍在使用的已有分类
+// 状态中已不存在于磁盘的记录会被清除
+func (s *OrganizeState) Diff(files []FileInfo) ([]FileInfo, []string) {
+	seen := make(map[string]bool)
+	categorySet := make(map[string]bool)
+	var changed []FileInfo
+
+	for _, file := range files {
+		key := filepath.ToSlash(file.Path)
+		seen[key] = true
+		placed, ok := s.Files[key]
+		if ok && placed.Size == file.Size && placed.ModTime.Equal(file.ModTime) {
+			categorySet[placed.Category] = true
+			continue
+		}
+		// 按目录整理时，已整理过的分类目录本身也会被扫描到，跳过其中已记录的内容
+		if file.IsDir && s.containsPlaced(key, seen, categorySet) {
+			continue
+		}
+		changed = append(changed, file)
+	}
+
+	for key := range s.Files {
+		if !seen[key] {
+			delete(s.Files, key)
+		}
+	}
+
+	categories := make([]string, 0, len(categorySet))
+	for category := range categorySet {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return changed, categories
+}
+
+// containsPlaced 判断目录中是否有已记录的文件，有则将这些记录标记为仍然存在
+func (s *OrganizeState) containsPlaced(dir string, seen, categorySet map[string]bool) bool {
+	found := false
+	for key, placed := range s.Files {
+		if strings.HasPrefix(key, dir+"/") {
+			seen[key] = true
+			categorySet[placed.Category] = true
+			found = true
+		}
+	}
+	return found
+}
+
+// Categories 返回状态中记录的全部分类
+func (s *OrganizeState) Categories() []string {
+	categorySet := make(map[string]bool)
+	for _, placed := range s.Files {
+		categorySet[placed.Category] = true
+	}
+	categories := make([]string, 0, len(categorySet))
+	for category := range categorySet {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// PlacedDirs 返回包含已整理文件的目录，相对于整理目录，使用 / 分隔
+func (s *OrganizeState) PlacedDirs() map[string]bool {
+	dirs := make(map[string]bool)
+	for key := range s.Files {
+		for dir := path.Dir(key); dir != "." && !dirs[dir]; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+	return dirs
+}
+
+// Record 记录一个已放置到目标位置的文件
+func (s *OrganizeState) Record(root, dstPath, category string) error {
+	relPath, err := filepath.Rel(root, dstPath)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(dstPath)
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	if info.IsDir() {
+		size = dirSize(dstPath)
+	}
+	s.Files[filepath.ToSlash(relPath)] = PlacedFile{
+		Category: category,
+		Size:     size,
+		ModTime:  info.ModTime(),
+		PlacedAt: time.Now(),
+	}
+	return nil
+}
+
+// Save 保存状态文件
+func (s *OrganizeState) Save() error {
+	data, err := json.MarshalIndent(s, "", "    ")
+	if err != nil {
+		return err
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
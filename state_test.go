@@ -0,0 +1,144 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestOrganizeStateDiff(t *testing.T) {
+	t1 := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	placed := map[string]PlacedFile{
+		"文档/a.txt":  {Category: "文档", Size: 10, ModTime: t1},
+		"图片/b.jpg":  {Category: "图片", Size: 20, ModTime: t1},
+		"图片/旧.jpg":  {Category: "图片", Size: 5, ModTime: t1},
+		"代码项目/tool": {Category: "代码项目", Size: 30, ModTime: t1},
+	}
+	tests := []struct {
+		name         string
+		files        []FileInfo
+		wantChanged  []string
+		wantExisting []string
+		wantKept     []string // 对比后状态中保留的记录
+	}{
+		{
+			name: "未变化的文件跳过，已删除的记录被清除",
+			files: []FileInfo{
+				{Path: "文档/a.txt", Size: 10, ModTime: t1},
+				{Path: "图片/b.jpg", Size: 20, ModTime: t1},
+			},
+			wantExisting: []string{"图片", "文档"},
+			wantKept:     []string{"图片/b.jpg", "文档/a.txt"},
+		},
+		{
+			name: "大小或修改时间变化的文件需要重新整理",
+			files: []FileInfo{
+				{Path: "文档/a.txt", Size: 11, ModTime: t1},
+				{Path: "图片/b.jpg", Size: 20, ModTime: t2},
+				{Path: "代码项目/tool", Size: 30, ModTime: t1, IsDir: true},
+			},
+			wantChanged:  []string{"文档/a.txt", "图片/b.jpg"},
+			wantExisting: []string{"代码项目"},
+			wantKept:     []string{"代码项目/tool", "图片/b.jpg", "文档/a.txt"},
+		},
+		{
+			name:         "新文件",
+			files:        []FileInfo{{Path: "new.pdf", Size: 1, ModTime: t2}},
+			wantChanged:  []string{"new.pdf"},
+			wantExisting: []string{},
+		},
+		{
+			// 按目录整理时扫描到的是分类目录本身，其中的记录仍然有效
+			name:         "包含已整理文件的分类目录",
+			files:        []FileInfo{{Path: "图片", Size: 25, ModTime: t2, IsDir: true}, {Path: "相册", Size: 3, ModTime: t2, IsDir: true}},
+			wantChanged:  []string{"相册"},
+			wantExisting: []string{"图片"},
+			wantKept:     []string{"图片/b.jpg", "图片/旧.jpg"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &OrganizeState{Files: map[string]PlacedFile{}}
+			for key, file := range placed {
+				state.Files[key] = file
+			}
+
+			changed, existing := state.Diff(tt.files)
+			var changedPaths []string
+			for _, file := range changed {
+				changedPaths = append(changedPaths, file.Path)
+			}
+			if !reflect.DeepEqual(changedPaths, tt.wantChanged) {
+				t.Errorf("changed = %q，应为 %q", changedPaths, tt.wantChanged)
+			}
+			if !reflect.DeepEqual(existing, tt.wantExisting) {
+				t.Errorf("existing = %q，应为 %q", existing, tt.wantExisting)
+			}
+			var kept []string
+			for key := range state.Files {
+				kept = append(kept, key)
+			}
+			sort.Strings(kept)
+			if !reflect.DeepEqual(kept, tt.wantKept) {
+				t.Errorf("保留的记录 = %q，应为 %q", kept, tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestOrganizeStateRecordSaveLoad(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "文档", "a.txt"), "hello")
+	writeFile(t, filepath.Join(root, "代码项目", "tool", "main.go"), "package main")
+	writeFile(t, filepath.Join(root, "代码项目", "tool", "go.mod"), "module tool")
+
+	state, err := LoadOrganizeState(root)
+	if err != nil || len(state.Files) != 0 {
+		t.Fatalf("状态文件不存在时应返回空状态: %v, %v", state, err)
+	}
+	if err := state.Record(root, filepath.Join(root, "文档", "a.txt"), "文档"); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.Record(root, filepath.Join(root, "代码项目", "tool"), "代码项目"); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.Record(root, filepath.Join(root, "missing.txt"), "文档"); err == nil {
+		t.Error("记录不存在的文件应返回错误")
+	}
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadOrganizeState(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Files["文档/a.txt"]; got.Category != "文档" || got.Size != 5 || got.PlacedAt.IsZero() {
+		t.Errorf("文档/a.txt 的记录 = %+v", got)
+	}
+	// 目录的大小为其中所有文件的总大小
+	if got := loaded.Files["代码项目/tool"]; got.Category != "代码项目" || got.Size != int64(len("package main")+len("module tool")) {
+		t.Errorf("代码项目/tool 的记录 = %+v", got)
+	}
+	if got := loaded.Categories(); !reflect.DeepEqual(got, []string{"代码项目", "文档"}) {
+		t.Errorf("Categories() = %q", got)
+	}
+	if got := loaded.PlacedDirs(); !reflect.DeepEqual(got, map[string]bool{"代码项目": true, "文档": true}) {
+		t.Errorf("PlacedDirs() = %v", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, stateFileName+".tmp")); !os.IsNotExist(err) {
+		t.Error("保存后不应留下临时文件")
+	}
+
+	if err := os.WriteFile(filepath.Join(root, stateFileName), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOrganizeState(root); err == nil {
+		t.Error("状态文件损坏时应返回错误")
+	}
+}
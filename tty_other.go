@@ -0,0 +1,29 @@
+//go:build !linux
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// isTerminal 其他平台按字符设备判断是否是终端
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// readPassword 其他平台无法关闭回显，直接读取一行
+func readPassword(f *os.File) (string, error) {
+	return readRawLine(f)
+}
+
+// makeRaw 其他平台不支持原始模式，调用方改为按行读取
+func makeRaw(f *os.File) (func(), error) {
+	return nil, errors.New("当前平台不支持终端原始模式")
+}
+
+// terminalSize 其他平台使用默认的 80x24
+func terminalSize(f *os.File) (int, int) {
+	return 80, 24
+}
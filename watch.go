@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WatchConfig 定义 watch 命令的配置
+type WatchConfig struct {
+	Inboxes         []string `json:"inboxes,omitempty"`          // 需要持续整理的收件目录
+	DebounceSeconds int      `json:"debounce_seconds,omitempty"` // 最后一次事件后等待多久再处理，默认5秒
+	StableSeconds   int      `json:"stable_seconds,omitempty"`   // 文件大小保持不变多久才认为写入完成，默认3秒
+}
+
+// inboxEvent 收件目录中出现的新文件
+type inboxEvent struct {
+	Root string
+	Name string
+}
+
+// 下载中的临时文件后缀，这些文件会在下载完成后被重命名
+var partialDownloadSuffixes = []string{".crdownload", ".part", ".partial", ".download", ".tmp", ".opdownload"}
+
+// pendingFile 等待写入完成的文件
+type pendingFile struct {
+	size      int64
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// runWatchCommand 监控收件目录，将新文件分批交给分类和移动流程，org 需要已经打开模型
+// inboxes 为空时使用配置中的目录，debounce 和 stable 为0时使用配置中的时间
+// ctx 取消时中止正在处理的批次（当前文件移动完成后停止）并结束监控
+// 提示信息和每批的摘要写到 o.info；JSON 格式时每批处理完成后向标准输出写一个结果对象
+func runWatchCommand(ctx context.Context, o *cliOptions, org *Organizer, inboxes []string, debounce, stable time.Duration) error {
+	config := org.Config
+	if debounce <= 0 {
+		debounce = secondsOrDefault(config.Watch.DebounceSeconds, 5)
+	}
+	if stable <= 0 {
+		stable = secondsOrDefault(config.Watch.StableSeconds, 3)
+	}
+
+	if len(inboxes) == 0 {
+		inboxes = config.Watch.Inboxes
+	}
+	if len(inboxes) == 0 {
+		return fmt.Errorf("没有需要监控的目录，请在配置的 watch.inboxes 中设置或在命令行中指定")
+	}
+	for i, inbox := range inboxes {
+		abs, err := filepath.Abs(expandHome(expandEnvRefs(inbox)))
+		if err != nil {
+			return fmt.Errorf("解析目录失败 %s: %v", inbox, err)
+		}
+		if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+			return fmt.Errorf("监控目录不存在: %s", abs)
+		}
+		inboxes[i] = abs
+	}
+
+	events := make(chan inboxEvent, 256)
+	if err := watchInboxes(inboxes, events); err != nil {
+		return err
+	}
+
+	pending := make(map[inboxEvent]*pendingFile)
+
+	// 启动时先处理收件目录中已有的文件
+	for _, inbox := range inboxes {
+		entries, err := os.ReadDir(inbox)
+		if err != nil {
+			return fmt.Errorf("读取目录失败 %s: %v", inbox, err)
+		}
+		for _, entry := range entries {
+			if entry.Type().IsRegular() {
+				pending[inboxEvent{Root: inbox, Name: entry.Name()}] = &pendingFile{}
+			}
+		}
+	}
+
+	fmt.Fprintf(o.info, "正在监控 %d 个目录: %s\n", len(inboxes), strings.Join(inboxes, ", "))
+
+	timer := time.NewTimer(debounce)
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(o.info, "\n停止监控")
+			return nil
+
+		case event, ok := <-events:
+			if !ok {
+				return fmt.Errorf("文件监控意外停止")
+			}
+			if _, exists := pending[event]; !exists {
+				pending[event] = &pendingFile{}
+			}
+			// 持续有事件时推迟处理，合并同一批下载
+			resetTimer(timer, debounce)
+
+		case <-timer.C:
+			ready := collectStableFiles(pending, stable)
+			for root, files := range ready {
+				// 每批都重新读取忽略规则，修改 .fileclassifyignore 后无需重启
+				ignore, err := loadIgnoreMatcher(root, config.Scan.IgnorePatterns)
+				if err != nil {
+					slog.Warn("读取忽略规则失败", "root", root, "error", err)
+				}
+				files = withoutIgnoredFiles(files, ignore)
+				if len(files) > 0 && ctx.Err() == nil {
+					if err := processInboxBatch(ctx, o, org, root, files); err != nil {
+						fmt.Fprintln(o.info, err)
+					}
+				}
+			}
+			if len(pending) > 0 {
+				resetTimer(timer, stable)
+			}
+		}
+	}
+}
+
+// collectStableFiles 检查等待中的文件，返回已经写入完成的文件（按收件目录分组）
+func collectStableFiles(pending map[inboxEvent]*pendingFile, stable time.Duration) map[string][]FileInfo {
+	now := time.Now()
+	ready := make(map[string][]FileInfo)
+	for event, p := range pending {
+		if shouldSkipInboxFile(event.Name) {
+			delete(pending, event)
+			continue
+		}
+
+		info, err := os.Lstat(filepath.Join(event.Root, event.Name))
+		if err != nil || !info.Mode().IsRegular() {
+			delete(pending, event)
+			continue
+		}
+
+		// 文件仍在增长，记录当前大小后继续等待
+		if p.checkedAt.IsZero() || info.Size() != p.size || !info.ModTime().Equal(p.modTime) {
+			p.size, p.modTime, p.checkedAt = info.Size(), info.ModTime(), now
+			continue
+		}
+		if now.Sub(p.checkedAt) < stable {
+			continue
+		}
+
+		delete(pending, event)
+		ready[event.Root] = append(ready[event.Root], FileInfo{
+			Path:    event.Name,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+	return ready
+}
+
+// shouldSkipInboxFile 跳过隐藏文件、状态文件和下载中的临时文件
+func shouldSkipInboxFile(name string) bool {
+	if name == stateFileName || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~$") {
+		return true
+	}
+	lower := strings.ToLower(name)
+	for _, suffix := range partialDownloadSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutIgnoredFiles 去掉匹配忽略规则的文件
+func withoutIgnoredFiles(files []FileInfo, ignore *IgnoreMatcher) []FileInfo {
+	var kept []FileInfo
+	for _, file := range files {
+		if file.Path != ignoreFileName && !ignore.Match(file.Path, false) {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
+// processInboxBatch 将一批新文件交给分类和移动流程，每批单独记录运行日志，可以用 undo 撤销
+// 返回的错误只影响这一批文件，监控会继续进行
+func processInboxBatch(ctx context.Context, o *cliOptions, org *Organizer, root string, files []FileInfo) error {
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	fmt.Fprintf(o.info, "\n[%s] %s 中有 %d 个新文件\n", time.Now().Format("15:04:05"), root, len(files))
+
+	state, err := LoadOrganizeState(root)
+	if err != nil {
+		return err
+	}
+
+	// 新文件优先归入收件目录中已有的分类
+	plan, err := org.Plan(ctx, root, files, state.Categories())
+	if ctx.Err() != nil {
+		fmt.Fprintln(o.info, "已取消，本批文件未移动")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	report, journal, err := org.Apply(ctx, plan, state, nil)
+	if err != nil {
+		return err
+	}
+	report.PrintSummary(o.info)
+
+	if o.Format == formatJSON {
+		output := map[string]interface{}{"root": root, "report": report}
+		if journal != nil && report.Moved > 0 {
+			output["run_id"] = journal.ID
+		}
+		return o.writeJSON(output)
+	}
+	return nil
+}
+
+func secondsOrDefault(seconds, fallback int) time.Duration {
+	if seconds <= 0 {
+		seconds = fallback
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
+
+// expandHome 展开路径开头的 ~
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
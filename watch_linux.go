@@ -0,0 +1,88 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// watchInboxes 使用 inotify 监控收件目录（只监控第一层，不含分类子目录）
+func watchInboxes(inboxes []string, events chan<- inboxEvent) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return fmt.Errorf("初始化inotify失败: %v", err)
+	}
+
+	roots := make(map[int]string)
+	const mask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_CREATE
+	for _, inbox := range inboxes {
+		wd, err := syscall.InotifyAddWatch(fd, inbox, mask)
+		if err != nil {
+			syscall.Close(fd)
+			return fmt.Errorf("监控目录失败 %s: %v", inbox, err)
+		}
+		roots[wd] = inbox
+	}
+
+	go func() {
+		defer syscall.Close(fd)
+		defer close(events)
+
+		buf := make([]byte, 64*1024)
+		for {
+			n, err := syscall.Read(fd, buf)
+			if err != nil {
+				if err == syscall.EINTR {
+					continue
+				}
+				slog.Error("读取inotify事件失败", "error", err)
+				return
+			}
+
+			for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+				raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				nameStart := offset + syscall.SizeofInotifyEvent
+				nameEnd := nameStart + int(raw.Len)
+				if nameEnd > n {
+					break
+				}
+				name := strings.TrimRight(string(buf[nameStart:nameEnd]), "\x00")
+				offset = nameEnd
+
+				// 事件队列溢出时重新列出目录，避免漏掉文件
+				if raw.Mask&syscall.IN_Q_OVERFLOW != 0 {
+					for _, root := range roots {
+						rescanInbox(root, events)
+					}
+					continue
+				}
+				if raw.Mask&syscall.IN_ISDIR != 0 || name == "" {
+					continue
+				}
+				if root, ok := roots[int(raw.Wd)]; ok {
+					events <- inboxEvent{Root: root, Name: name}
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// rescanInbox 将收件目录中现有的文件全部作为事件发送
+func rescanInbox(root string, events chan<- inboxEvent) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			events <- inboxEvent{Root: root, Name: entry.Name()}
+		}
+	}
+}
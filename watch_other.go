@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "fmt"
+
+// watchInboxes 目前只在 Linux 上通过 inotify 实现
+func watchInboxes(inboxes []string, events chan<- inboxEvent) error {
+	return fmt.Errorf("watch 命令目前只支持 Linux")
+}